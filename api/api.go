@@ -3,14 +3,15 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"path/filepath"
 	"strings"
 
 	"github.com/yaoapp/kun/log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/observe"
 	"github.com/yaoapp/kun/exception"
-	"github.com/yaoapp/xun"
 )
 
 // APIs 已加载API列表
@@ -42,6 +43,9 @@ func LoadSource(file string, data []byte, id string, guard ...string) (*API, err
 		http.Group = strings.ReplaceAll(strings.ToLower(id), ".", "/")
 	}
 
+	// REST resources
+	http.Paths = append(http.Paths, http.resourcePaths()...)
+
 	// Validate API
 	uniquePathCheck := map[string]bool{}
 	for _, path := range http.Paths {
@@ -77,44 +81,48 @@ func Select(id string) *API {
 	return api
 }
 
+// Observability the access logs, metrics and trace context option of the api routes, nil means disabled
+var Observability *observe.Option
+
 // SetRoutes set the api routes
 func SetRoutes(router *gin.Engine, path string, allows ...string) {
 
+	// Observability
+	if Observability != nil {
+		router.Use(observe.Middleware(*Observability))
+		if Observability.Metrics != "" {
+			router.GET(Observability.Metrics, observe.MetricsHandler())
+		}
+	}
+
 	// Error handler
 	router.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
 
 		var code = http.StatusInternalServerError
 
 		if err, ok := recovered.(string); ok {
-			c.JSON(code, xun.R{
-				"code":    code,
-				"message": fmt.Sprintf("%s", err),
-			})
+			abort(c, code, fmt.Sprintf("%s", err), nil)
 		} else if err, ok := recovered.(exception.Exception); ok {
-			code = err.Code
-			c.JSON(code, xun.R{
-				"code":    code,
-				"message": err.Message,
-			})
+			abort(c, err.Code, err.Message, nil)
 		} else if err, ok := recovered.(*exception.Exception); ok {
-			code = err.Code
-			c.JSON(code, xun.R{
-				"code":    code,
-				"message": err.Message,
-			})
+			abort(c, err.Code, err.Message, nil)
 		} else {
-			c.JSON(code, xun.R{
-				"code":    code,
-				"message": fmt.Sprintf("%v", recovered),
-			})
+			abort(c, code, fmt.Sprintf("%v", recovered), nil)
 		}
-
-		c.AbortWithStatus(code)
 	}))
 
 	// Load apis
 	for _, api := range APIs {
-		api.HTTP.Routes(router, path, allows...)
+		api.resolve().Routes(router, path, allows...)
+	}
+
+	// Accept-Version dispatchers
+	setVersionRoutes(router, path)
+
+	// OpenAPI document
+	if OpenAPIRoute != "" {
+		handlers := append(Guards(OpenAPIGuard), openAPIHandler(path))
+		router.GET(filepath.Join(path, "/", OpenAPIRoute), handlers...)
 	}
 }
 
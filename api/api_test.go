@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -196,6 +197,41 @@ func testRouter(t *testing.T, middlewares ...gin.HandlerFunc) *gin.Engine {
 	return router
 }
 
+// testRequest serve the request via the router and record the response, the body is a string, []byte or io.Reader.
+// the body is sent as JSON if the Content-Type header is not given
+func testRequest(router http.Handler, method string, url string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	switch value := body.(type) {
+	case string:
+		reader = strings.NewReader(value)
+	case []byte:
+		reader = bytes.NewReader(value)
+	case io.Reader:
+		reader = value
+	}
+
+	req, _ := http.NewRequest(method, url, reader)
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(closeNotifyRecorder{response}, req)
+	return response
+}
+
+// closeNotifyRecorder the recorder of the streamed responses, the gin stream requires the http.CloseNotifier
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (closeNotifyRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
 func responseMap(resp *httptest.ResponseRecorder) maps.MapStrAny {
 	body := resp.Body.Bytes()
 	res := map[string]interface{}{}
@@ -0,0 +1,307 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/log"
+)
+
+const cachePrefix = "__api_cache"
+
+// the stores used by the path caches
+var cacheStores = map[string]bool{}
+var cacheStoresMutex sync.Mutex
+
+// the response headers are not cached, the hop-by-hop headers, the cookies and the headers set by the cache
+var cacheSkipHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+	"Set-Cookie":          true,
+	"Content-Length":      true,
+	"Etag":                true,
+	"Last-Modified":       true,
+}
+
+// cacheEntry the cached response
+type cacheEntry struct {
+	Status   int         `json:"status"`
+	Body     string      `json:"body"`
+	ETag     string      `json:"etag"`
+	Modified string      `json:"modified"`
+	Headers  http.Header `json:"headers,omitempty"`
+}
+
+// cacheWriter buffer the response until the handlers completed
+type cacheWriter struct {
+	gin.ResponseWriter
+	status int
+	body   *bytes.Buffer
+}
+
+// InvalidateCache remove the cached responses of the given tags, remove all if the tags are empty
+func InvalidateCache(tags ...string) int {
+	cacheStoresMutex.Lock()
+	names := []string{}
+	for name := range cacheStores {
+		names = append(names, name)
+	}
+	cacheStoresMutex.Unlock()
+
+	prefixes := []string{fmt.Sprintf("%s:", cachePrefix)}
+	if len(tags) > 0 {
+		prefixes = []string{}
+		for _, tag := range tags {
+			prefixes = append(prefixes, fmt.Sprintf("%s:%s:", cachePrefix, tag))
+		}
+	}
+
+	removed := 0
+	for _, name := range names {
+		stor, has := store.Pools[name]
+		if !has {
+			continue
+		}
+
+		for _, prefix := range prefixes {
+			keys := stor.Keys(prefix + "*")
+			stor.DelMulti(keys)
+			removed += len(keys)
+		}
+	}
+	return removed
+}
+
+// cacheHandler the response cache middleware of the path, the responses of the guarded path vary by the user if the vary is not given
+func (path Path) cacheHandler(tag string, guarded bool) gin.HandlerFunc {
+
+	cacheStoresMutex.Lock()
+	cacheStores[path.Cache.Store] = true
+	cacheStoresMutex.Unlock()
+
+	if path.Cache.Tag != "" {
+		tag = path.Cache.Tag
+	}
+
+	ttl := time.Duration(path.Cache.TTL) * time.Second
+	return func(c *gin.Context) {
+
+		if c.Request.Method != "GET" && c.Request.Method != "HEAD" {
+			return
+		}
+
+		stor, has := store.Pools[path.Cache.Store]
+		if !has {
+			log.Error("[Path] %s cache store %s does not load", path.Path, path.Cache.Store)
+			return
+		}
+
+		key := path.Cache.key(c, tag, guarded)
+		if value, ok := stor.Get(key); ok {
+			if entry, ok := toCacheEntry(value); ok {
+				entry.write(c)
+				c.Abort()
+				return
+			}
+		}
+
+		// the headers set before the handlers are set again on each request
+		before := c.Writer.Header().Clone()
+		writer := &cacheWriter{ResponseWriter: c.Writer, status: 200, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if writer.status != 200 || len(body) == 0 {
+			c.Writer.WriteHeader(writer.status)
+			c.Writer.Write(body)
+			return
+		}
+
+		sum := sha1.Sum(body)
+		entry := &cacheEntry{
+			Status:   writer.status,
+			Body:     string(body),
+			ETag:     fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:])),
+			Modified: time.Now().UTC().Format(http.TimeFormat),
+			Headers:  http.Header{},
+		}
+
+		for name, values := range c.Writer.Header() {
+			if cacheSkipHeaders[http.CanonicalHeaderKey(name)] || reflect.DeepEqual(before[name], values) {
+				continue
+			}
+			entry.Headers[name] = values
+		}
+
+		data, err := jsoniter.Marshal(entry)
+		if err == nil {
+			err = stor.Set(key, string(data), ttl)
+		}
+		if err != nil {
+			log.Error("[Path] %s cache set %s", path.Path, err.Error())
+		}
+		entry.write(c)
+	}
+}
+
+// key the cache key of the request
+func (cache *Cache) key(c *gin.Context, tag string, guarded bool) string {
+	vary := cache.Vary
+	if len(vary) == 0 {
+		vary = []string{"params", "query"}
+		if guarded {
+			vary = append(vary, "sid", "global", "header.Authorization")
+		}
+	}
+
+	hash := sha1.New()
+	hash.Write([]byte(c.Request.Method))
+	hash.Write([]byte(c.Request.URL.Path))
+	for _, name := range vary {
+		switch {
+		case name == "params":
+			params := []string{}
+			for _, param := range c.Params {
+				params = append(params, fmt.Sprintf("%s=%s", param.Key, param.Value))
+			}
+			sort.Strings(params)
+			hash.Write([]byte(strings.Join(params, "&")))
+
+		case name == "query":
+			hash.Write([]byte(c.Request.URL.Query().Encode()))
+
+		case name == "sid":
+			hash.Write([]byte(c.GetString("__sid")))
+
+		case name == "global":
+			if global, has := c.Get("__global"); has {
+				data, _ := jsoniter.Marshal(global)
+				hash.Write(data)
+			}
+
+		case name == "headers":
+			hash.Write([]byte(fmt.Sprintf("%v", c.Request.Header)))
+
+		case strings.HasPrefix(name, "headers."), strings.HasPrefix(name, "header."):
+			hash.Write([]byte(c.GetHeader(name[strings.Index(name, ".")+1:])))
+		}
+		hash.Write([]byte{0})
+	}
+
+	return fmt.Sprintf("%s:%s:%s", cachePrefix, tag, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// write the cached response, response 304 if the client cache is fresh
+func (entry *cacheEntry) write(c *gin.Context) {
+	header := c.Writer.Header()
+	for name, values := range entry.Headers {
+		header[name] = values
+	}
+	header.Set("ETag", entry.ETag)
+	header.Set("Last-Modified", entry.Modified)
+
+	if entry.notModified(c.Request) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Writer.WriteHeader(entry.Status)
+	if c.Request.Method != "HEAD" {
+		c.Writer.Write([]byte(entry.Body))
+	}
+}
+
+// notModified check the If-None-Match and the If-Modified-Since headers
+func (entry *cacheEntry) notModified(req *http.Request) bool {
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == entry.ETag || etag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := req.Header.Get("If-Modified-Since"); since != "" {
+		sinceAt, err := http.ParseTime(since)
+		if err != nil {
+			return false
+		}
+		modifiedAt, err := http.ParseTime(entry.Modified)
+		if err != nil {
+			return false
+		}
+		return !modifiedAt.After(sinceAt)
+	}
+
+	return false
+}
+
+// toCacheEntry convert the cached value to the cache entry
+func toCacheEntry(value interface{}) (*cacheEntry, bool) {
+	data, ok := value.(string)
+	if !ok {
+		return nil, false
+	}
+
+	entry := &cacheEntry{}
+	err := jsoniter.Unmarshal([]byte(data), entry)
+	if err != nil {
+		return nil, false
+	}
+	return entry, entry.ETag != ""
+}
+
+// WriteHeader hold the status code
+func (w *cacheWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+// WriteHeaderNow do nothing, the header will be written after the handlers completed
+func (w *cacheWriter) WriteHeaderNow() {}
+
+// Write buffer the data
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// WriteString buffer the string
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+// Status returns the status code
+func (w *cacheWriter) Status() int {
+	return w.status
+}
+
+// Size returns the size of the buffered body
+func (w *cacheWriter) Size() int {
+	return w.body.Len()
+}
+
+// Written check if the body was written
+func (w *cacheWriter) Written() bool {
+	return w.body.Len() > 0
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/store"
+)
+
+func TestCache(t *testing.T) {
+	router, counter := cacheRouter(t)
+
+	response := testRequest(router, "GET", "/cache/counter?foo=bar", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, `{"count":1}`, response.Body.String())
+	etag := response.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, response.Header().Get("Last-Modified"))
+
+	// cached
+	response = testRequest(router, "GET", "/cache/counter?foo=bar", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, `{"count":1}`, response.Body.String())
+	assert.Equal(t, etag, response.Header().Get("ETag"))
+	assert.Equal(t, "counter", response.Header().Get("X-Unit-Cache"))
+	assert.Contains(t, response.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, 1, *counter)
+
+	// vary by query
+	response = testRequest(router, "GET", "/cache/counter?foo=baz", nil, nil)
+	assert.Equal(t, `{"count":2}`, response.Body.String())
+
+	// not modified
+	response = testRequest(router, "GET", "/cache/counter?foo=bar", nil, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, 304, response.Code)
+	assert.Empty(t, response.Body.String())
+
+	// invalidate
+	removed := process.New("api.invalidate", "unit-cache").Run()
+	assert.Equal(t, 2, removed)
+	response = testRequest(router, "GET", "/cache/counter?foo=bar", nil, nil)
+	assert.Equal(t, `{"count":3}`, response.Body.String())
+}
+
+func TestCacheKeyGuarded(t *testing.T) {
+	cache := &Cache{Store: "unit-api-cache"}
+	keyOf := func(sid string, guarded bool) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/cache/counter?foo=bar", nil)
+		c.Set("__sid", sid)
+		return cache.key(c, "unit-cache", guarded)
+	}
+
+	assert.Equal(t, keyOf("sid-1", false), keyOf("sid-2", false))
+	assert.NotEqual(t, keyOf("sid-1", true), keyOf("sid-2", true))
+
+	cache.Vary = []string{"query"}
+	assert.Equal(t, keyOf("sid-1", true), keyOf("sid-2", true))
+}
+
+func cacheRouter(t *testing.T) (*gin.Engine, *int) {
+	stor, err := store.New(nil, store.Option{"size": 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Pools["unit-api-cache"] = stor
+
+	counter := 0
+	process.Register("unit.api.counter", func(process *process.Process) interface{} {
+		counter++
+		return map[string]interface{}{"count": counter}
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	http := HTTP{
+		Name:  "cache",
+		Group: "cache",
+		Guard: "-",
+		Paths: []Path{{
+			Path:    "/counter",
+			Method:  "GET",
+			Process: "unit.api.counter",
+			Out:     Out{Status: 200, Type: "application/json", Headers: map[string]string{"X-Unit-Cache": "counter"}},
+			Cache:   &Cache{Store: "unit-api-cache", TTL: 60, Tag: "unit-cache"},
+		}},
+	}
+	http.Routes(router, "/")
+	return router, &counter
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/model"
+)
+
+// APIKeyOption the API key guard option
+type APIKeyOption struct {
+	Header string   `json:"header,omitempty"` // the key header, default is X-API-Key
+	Query  string   `json:"query,omitempty"`  // read the key from the query string if the header is empty
+	Keys   []string `json:"keys,omitempty"`   // the static keys, supports $ENV.NAME
+	Model  string   `json:"model,omitempty"`  // the model of the keys
+	Column string   `json:"column,omitempty"` // the key column of the model, default is key
+	SID    string   `json:"sid,omitempty"`    // the column of the session id (model-backed only)
+	Global []string `json:"global,omitempty"` // the columns set to the __global (model-backed only)
+}
+
+// APIKeyGuard create an API key guard
+func APIKeyGuard(option APIKeyOption) (gin.HandlerFunc, error) {
+
+	if len(option.Keys) == 0 && option.Model == "" {
+		return nil, fmt.Errorf("keys or model is required")
+	}
+
+	header := option.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+
+	column := option.Column
+	if column == "" {
+		column = "key"
+	}
+
+	keys := [][]byte{}
+	for _, key := range option.Keys {
+		keys = append(keys, []byte(helper.EnvString(key)))
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(header)
+		if key == "" && option.Query != "" {
+			key = c.Query(option.Query)
+		}
+
+		if key == "" {
+			unauthorized(c, "the api key is required")
+			return
+		}
+
+		for _, k := range keys {
+			if subtle.ConstantTimeCompare(k, []byte(key)) == 1 {
+				return
+			}
+		}
+
+		if option.Model == "" {
+			unauthorized(c, "the api key is invalid")
+			return
+		}
+
+		mod, has := model.Models[option.Model]
+		if !has {
+			unauthorized(c, fmt.Sprintf("the model %s does not load", option.Model))
+			return
+		}
+
+		rows, err := mod.Get(model.QueryParam{
+			Wheres: []model.QueryWhere{{Column: column, Value: key}},
+			Limit:  1,
+		})
+
+		if err != nil || len(rows) == 0 {
+			unauthorized(c, "the api key is invalid")
+			return
+		}
+
+		row := rows[0]
+		if option.SID != "" {
+			if sid, ok := row[option.SID].(string); ok && sid != "" {
+				c.Set("__sid", sid)
+			}
+		}
+
+		global := map[string]interface{}{}
+		for _, name := range option.Global {
+			global[name] = row[name]
+		}
+		setGlobal(c, global)
+	}, nil
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/session"
+)
+
+// CSRFOption the CSRF token guard option
+type CSRFOption struct {
+	Header  string   `json:"header,omitempty"`  // the token header, default is X-CSRF-Token
+	Form    string   `json:"form,omitempty"`    // the form field of the token if the header is empty, default is _csrf
+	Methods []string `json:"methods,omitempty"` // the unsafe methods, default is POST, PUT, PATCH, DELETE
+}
+
+// CSRFGuard create a guard validates the CSRF token of the session on the unsafe methods.
+// The guard must be used after the guards set the __sid (e.g. session), the token of the session is
+// responded in the header on the safe methods, and it could be read by the session.CSRFToken process.
+func CSRFGuard(option CSRFOption) (gin.HandlerFunc, error) {
+	option.Header = defaultString(option.Header, "X-CSRF-Token")
+	option.Form = defaultString(option.Form, "_csrf")
+	if len(option.Methods) == 0 {
+		option.Methods = []string{"POST", "PUT", "PATCH", "DELETE"}
+	}
+
+	unsafe := map[string]bool{}
+	for _, method := range option.Methods {
+		unsafe[strings.ToUpper(method)] = true
+	}
+
+	return func(c *gin.Context) {
+		sid := c.GetString("__sid")
+		if sid == "" {
+			forbidden(c, "the session is required")
+			return
+		}
+
+		if !unsafe[c.Request.Method] {
+			token, err := csrfToken(c, sid)
+			if err != nil {
+				forbidden(c, err.Error())
+				return
+			}
+			c.Header(option.Header, token)
+			return
+		}
+
+		token := c.GetHeader(option.Header)
+		if token == "" {
+			contentType := strings.ToLower(c.ContentType())
+			if contentType == "application/x-www-form-urlencoded" || contentType == "multipart/form-data" {
+				token = c.PostForm(option.Form)
+			}
+		}
+
+		if !csrfVerify(c, sid, token) {
+			forbidden(c, "the CSRF token is invalid")
+			return
+		}
+	}, nil
+}
+
+// csrfToken the CSRF token of the session, the token is kept in the cookie in the stateless mode
+func csrfToken(c *gin.Context, sid string) (string, error) {
+	if data, ok := statelessSession(c); ok {
+		if token, ok := data[session.CSRFKey].(string); ok && token != "" {
+			return token, nil
+		}
+		token := session.ID()
+		data[session.CSRFKey] = token
+		return token, nil
+	}
+	return session.Global().ID(sid).CSRFToken()
+}
+
+func csrfVerify(c *gin.Context, sid string, token string) bool {
+	if data, ok := statelessSession(c); ok {
+		expected, _ := data[session.CSRFKey].(string)
+		return token != "" && expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+	}
+	return session.Global().ID(sid).VerifyCSRF(token)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+)
+
+// GuardDSL the built-in guard DSL
+type GuardDSL struct {
+	Type   string                 `json:"type"` // jwt, api-key, hmac, session
+	Option map[string]interface{} `json:"option,omitempty"`
+}
+
+// LoadGuard load a built-in guard from the guard DSL, and register it as the name
+func LoadGuard(file string, name string) (gin.HandlerFunc, error) {
+	data, err := application.App.Read(file)
+	if err != nil {
+		return nil, err
+	}
+	return LoadGuardSource(file, data, name)
+}
+
+// LoadGuardSource load a built-in guard from the source
+func LoadGuardSource(file string, data []byte, name string) (gin.HandlerFunc, error) {
+	dsl := GuardDSL{}
+	err := application.Parse(file, data, &dsl)
+	if err != nil {
+		return nil, err
+	}
+
+	handler, err := NewGuard(dsl.Type, dsl.Option)
+	if err != nil {
+		return nil, fmt.Errorf("[Guard] %s %s", name, err.Error())
+	}
+
+	AddGuard(name, handler)
+	return handler, nil
+}
+
+// NewGuard create a built-in guard by the type
+func NewGuard(typ string, option map[string]interface{}) (gin.HandlerFunc, error) {
+
+	bytes, err := jsoniter.Marshal(option)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(typ) {
+	case "jwt":
+		opt := JWTOption{}
+		if err := jsoniter.Unmarshal(bytes, &opt); err != nil {
+			return nil, err
+		}
+		return JWTGuard(opt)
+
+	case "api-key", "apikey":
+		opt := APIKeyOption{}
+		if err := jsoniter.Unmarshal(bytes, &opt); err != nil {
+			return nil, err
+		}
+		return APIKeyGuard(opt)
+
+	case "hmac":
+		opt := HMACOption{}
+		if err := jsoniter.Unmarshal(bytes, &opt); err != nil {
+			return nil, err
+		}
+		return HMACGuard(opt)
+
+	case "session":
+		opt := SessionOption{}
+		if err := jsoniter.Unmarshal(bytes, &opt); err != nil {
+			return nil, err
+		}
+		return SessionGuard(opt)
+
+	case "csrf":
+		opt := CSRFOption{}
+		if err := jsoniter.Unmarshal(bytes, &opt); err != nil {
+			return nil, err
+		}
+		return CSRFGuard(opt)
+	}
+
+	return nil, fmt.Errorf("the guard type %s does not support", typ)
+}
+
+// unauthorized abort the request with 401
+func unauthorized(c *gin.Context, message string) {
+	abort(c, 401, message, nil)
+}
+
+// forbidden abort the request with 403
+func forbidden(c *gin.Context, message string) {
+	abort(c, 403, message, nil)
+}
+
+// setGlobal merge the values into the __global of the context
+func setGlobal(c *gin.Context, values map[string]interface{}) {
+	global := map[string]interface{}{}
+	if v, has := c.Get("__global"); has {
+		if v, ok := v.(map[string]interface{}); ok {
+			global = v
+		}
+	}
+
+	for name, value := range values {
+		global[name] = value
+	}
+	c.Set("__global", global)
+}
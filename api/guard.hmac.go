@@ -0,0 +1,191 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/store"
+)
+
+// HMACOption the HMAC-signed request guard option
+type HMACOption struct {
+	Secrets   map[string]string `json:"secrets"`             // key id -> secret, supports $ENV.NAME
+	Algorithm string            `json:"algorithm,omitempty"` // SHA1, SHA256, SHA512, default is SHA256
+	Header    string            `json:"header,omitempty"`    // the signature header, default is X-Signature
+	KeyHeader string            `json:"key,omitempty"`       // the key id header, default is X-Key-Id
+	Timestamp string            `json:"timestamp,omitempty"` // the timestamp header (unix seconds), default is X-Timestamp
+	Nonce     string            `json:"nonce,omitempty"`     // the nonce header, default is X-Nonce
+	Tolerance int               `json:"tolerance,omitempty"` // the allowed clock skew (seconds), default is 300
+	Store     string            `json:"store,omitempty"`     // the store of the used nonces, an in-memory LRU store is used if not given
+	Nonces    int               `json:"nonces,omitempty"`    // the capacity of the in-memory nonce store, default is 102400
+	MaxBody   int64             `json:"maxBody,omitempty"`   // the max size of the signed body (bytes), default is 10MB
+}
+
+var hmacHashes = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// HMACGuard create a HMAC-signed request guard.
+// The signature is the HMAC of "METHOD\nPATH?QUERY\nTIMESTAMP\nNONCE\nHEX(SHA256(BODY))" encoded in hex or base64.
+// The nonces are kept for twice the tolerance, the in-memory store evicts the oldest nonces before they expired if it
+// receives more than the nonces (capacity) requests in the period, use a shared store on the busy or the multi-instance servers.
+func HMACGuard(option HMACOption) (gin.HandlerFunc, error) {
+
+	if len(option.Secrets) == 0 {
+		return nil, fmt.Errorf("secrets is required")
+	}
+
+	if option.Algorithm == "" {
+		option.Algorithm = "SHA256"
+	}
+
+	newHash, has := hmacHashes[strings.ToUpper(option.Algorithm)]
+	if !has {
+		return nil, fmt.Errorf("the algorithm %s does not support", option.Algorithm)
+	}
+
+	secrets := map[string][]byte{}
+	for id, secret := range option.Secrets {
+		secrets[id] = []byte(helper.EnvString(secret))
+	}
+
+	option.Header = defaultString(option.Header, "X-Signature")
+	option.KeyHeader = defaultString(option.KeyHeader, "X-Key-Id")
+	option.Timestamp = defaultString(option.Timestamp, "X-Timestamp")
+	option.Nonce = defaultString(option.Nonce, "X-Nonce")
+	if option.Tolerance <= 0 {
+		option.Tolerance = 300
+	}
+
+	if option.Nonces <= 0 {
+		option.Nonces = 102400
+	}
+
+	if option.MaxBody <= 0 {
+		option.MaxBody = 10 << 20
+	}
+
+	var nonces store.Store
+	if option.Store == "" {
+		var err error
+		nonces, err = store.New(nil, store.Option{"size": option.Nonces})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func(c *gin.Context) {
+
+		id := c.GetHeader(option.KeyHeader)
+		secret, has := secrets[id]
+		if !has {
+			unauthorized(c, "the key id is invalid")
+			return
+		}
+
+		timestamp := c.GetHeader(option.Timestamp)
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || math.Abs(float64(time.Now().Unix()-ts)) > float64(option.Tolerance) {
+			unauthorized(c, "the timestamp is invalid or expired")
+			return
+		}
+
+		nonce := c.GetHeader(option.Nonce)
+		if nonce == "" {
+			unauthorized(c, "the nonce is required")
+			return
+		}
+
+		// the body is read before the signature is verified, it is limited
+		if c.Request.ContentLength > option.MaxBody {
+			abort(c, 413, "the body is too large", nil)
+			return
+		}
+
+		body := []byte{}
+		if c.Request.Body != nil {
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, option.MaxBody))
+			if err != nil {
+				if _, ok := err.(*http.MaxBytesError); ok {
+					abort(c, 413, "the body is too large", nil)
+					return
+				}
+				unauthorized(c, err.Error())
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+		}
+
+		bodyHash := sha256.Sum256(body)
+		payload := strings.Join([]string{
+			c.Request.Method,
+			c.Request.URL.RequestURI(),
+			timestamp,
+			nonce,
+			hex.EncodeToString(bodyHash[:]),
+		}, "\n")
+
+		mac := hmac.New(newHash, secret)
+		mac.Write([]byte(payload))
+		expected := mac.Sum(nil)
+
+		signature := c.GetHeader(option.Header)
+		actual, err := hex.DecodeString(signature)
+		if err != nil {
+			actual, err = base64.StdEncoding.DecodeString(signature)
+		}
+
+		if err != nil || !hmac.Equal(expected, actual) {
+			unauthorized(c, "the signature is invalid")
+			return
+		}
+
+		// replay protection
+		kv := nonces
+		if option.Store != "" {
+			kv, has = store.Pools[option.Store]
+			if !has {
+				unauthorized(c, fmt.Sprintf("the store %s does not load", option.Store))
+				return
+			}
+		}
+
+		key := fmt.Sprintf("__hmac_nonce:%s:%s", id, nonce)
+		fresh, err := kv.SetNX(key, ts, time.Duration(option.Tolerance*2)*time.Second)
+		if err != nil {
+			unauthorized(c, fmt.Sprintf("the nonce can not be checked (%s)", err.Error()))
+			return
+		}
+
+		if !fresh {
+			unauthorized(c, "the nonce has been used")
+			return
+		}
+
+		setGlobal(c, map[string]interface{}{"__hmac_key": id})
+	}, nil
+}
+
+func defaultString(value string, defaults string) string {
+	if value == "" {
+		return defaults
+	}
+	return value
+}
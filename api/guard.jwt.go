@@ -0,0 +1,344 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/ssl"
+)
+
+// JWTOption the JWT bearer guard option
+type JWTOption struct {
+	Secret     string            `json:"secret,omitempty"`     // the HMAC secret (HS256, HS384, HS512)
+	Cert       string            `json:"cert,omitempty"`       // the name of the loaded certificate (RS256, ES256 ...)
+	JWKS       string            `json:"jwks,omitempty"`       // the JWKS file (RS256, ES256 ...)
+	Algorithms []string          `json:"algorithms,omitempty"` // the allowed algorithms, all of the supported algorithms are allowed if not given
+	Issuer     string            `json:"issuer,omitempty"`
+	Audience   string            `json:"audience,omitempty"`
+	Leeway     int               `json:"leeway,omitempty"` // seconds
+	Header     string            `json:"header,omitempty"` // the token header, default is Authorization
+	Query      string            `json:"query,omitempty"`  // read the token from the query string if the header is empty
+	SID        string            `json:"sid,omitempty"`    // the claim of the session id, default is sid
+	Global     map[string]string `json:"global,omitempty"` // global name -> claim name, all of the claims will be set if not given
+}
+
+// the JWT algorithms  name -> [hash, key size (ECDSA)]
+var jwtAlgorithms = map[string]struct {
+	hash crypto.Hash
+	name string
+	size int
+}{
+	"HS256": {crypto.SHA256, "SHA256", 0},
+	"HS384": {crypto.SHA384, "SHA384", 0},
+	"HS512": {crypto.SHA512, "SHA512", 0},
+	"RS256": {crypto.SHA256, "SHA256", 0},
+	"RS384": {crypto.SHA384, "SHA384", 0},
+	"RS512": {crypto.SHA512, "SHA512", 0},
+	"ES256": {crypto.SHA256, "SHA256", 32},
+	"ES384": {crypto.SHA384, "SHA384", 48},
+	"ES512": {crypto.SHA512, "SHA512", 66},
+}
+
+type jwtVerifier struct {
+	option JWTOption
+	allows map[string]bool
+	secret []byte
+	keys   map[string]*ssl.Certificate
+}
+
+// JWTGuard create a JWT bearer guard
+func JWTGuard(option JWTOption) (gin.HandlerFunc, error) {
+	verifier, err := newJWTVerifier(option)
+	if err != nil {
+		return nil, err
+	}
+
+	header := option.Header
+	if header == "" {
+		header = "Authorization"
+	}
+
+	sidClaim := option.SID
+	if sidClaim == "" {
+		sidClaim = "sid"
+	}
+
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(c.GetHeader(header))
+		if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+			token = strings.TrimSpace(token[7:])
+		}
+
+		if token == "" && option.Query != "" {
+			token = c.Query(option.Query)
+		}
+
+		if token == "" {
+			unauthorized(c, "the token is required")
+			return
+		}
+
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			unauthorized(c, err.Error())
+			return
+		}
+
+		if sid, ok := claims[sidClaim].(string); ok && sid != "" {
+			c.Set("__sid", sid)
+		}
+
+		global := map[string]interface{}{}
+		if len(option.Global) == 0 {
+			global = claims
+		}
+		for name, claim := range option.Global {
+			if value, has := claims[claim]; has {
+				global[name] = value
+			}
+		}
+
+		setGlobal(c, global)
+		c.Set("__jwt", claims)
+	}, nil
+}
+
+func newJWTVerifier(option JWTOption) (*jwtVerifier, error) {
+	verifier := &jwtVerifier{
+		option: option,
+		allows: map[string]bool{},
+		keys:   map[string]*ssl.Certificate{},
+	}
+
+	for _, alg := range option.Algorithms {
+		if _, has := jwtAlgorithms[alg]; !has {
+			return nil, fmt.Errorf("the algorithm %s does not support", alg)
+		}
+		verifier.allows[alg] = true
+	}
+
+	if option.Secret != "" {
+		verifier.secret = []byte(helper.EnvString(option.Secret))
+	}
+
+	if option.Cert != "" {
+		cert, has := ssl.Certificates[option.Cert]
+		if !has {
+			return nil, fmt.Errorf("the certificate %s does not load", option.Cert)
+		}
+		verifier.keys[""] = cert
+	}
+
+	if option.JWKS != "" {
+		data, err := application.App.Read(option.JWKS)
+		if err != nil {
+			return nil, err
+		}
+
+		err = verifier.loadJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if verifier.secret == nil && len(verifier.keys) == 0 {
+		return nil, fmt.Errorf("secret, cert or jwks is required")
+	}
+
+	return verifier, nil
+}
+
+// loadJWKS load the public keys from the JWKS
+func (verifier *jwtVerifier) loadJWKS(data []byte) error {
+	jwks := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+
+	err := jsoniter.Unmarshal(data, &jwks)
+	if err != nil {
+		return err
+	}
+
+	for _, jwk := range jwks.Keys {
+		var key any
+		switch jwk["kty"] {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(jwk["n"])
+			if err != nil {
+				return err
+			}
+			e, err := base64.RawURLEncoding.DecodeString(jwk["e"])
+			if err != nil {
+				return err
+			}
+			key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk["crv"] {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return fmt.Errorf("the curve %s does not support", jwk["crv"])
+			}
+
+			x, err := base64.RawURLEncoding.DecodeString(jwk["x"])
+			if err != nil {
+				return err
+			}
+			y, err := base64.RawURLEncoding.DecodeString(jwk["y"])
+			if err != nil {
+				return err
+			}
+			key = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+		default:
+			continue
+		}
+
+		cert, err := ssl.NewPublicKey(key)
+		if err != nil {
+			return err
+		}
+		verifier.keys[jwk["kid"]] = cert
+	}
+
+	return nil
+}
+
+// Verify verify the token and returns the claims
+func (verifier *jwtVerifier) Verify(token string) (map[string]interface{}, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("the token is invalid")
+	}
+
+	header := map[string]interface{}{}
+	if err := jwtDecode(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("the token header is invalid")
+	}
+
+	alg, _ := header["alg"].(string)
+	algorithm, has := jwtAlgorithms[alg]
+	if !has || (len(verifier.allows) > 0 && !verifier.allows[alg]) {
+		return nil, fmt.Errorf("the algorithm %s is not allowed", alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("the token signature is invalid")
+	}
+
+	data := []byte(parts[0] + "." + parts[1])
+	if strings.HasPrefix(alg, "HS") {
+		if verifier.secret == nil {
+			return nil, fmt.Errorf("the algorithm %s is not allowed", alg)
+		}
+		mac := hmac.New(algorithm.hash.New, verifier.secret)
+		mac.Write(data)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, fmt.Errorf("the token signature is invalid")
+		}
+
+	} else {
+		kid, _ := header["kid"].(string)
+		cert, has := verifier.keys[kid]
+		if !has && kid == "" && len(verifier.keys) == 1 {
+			for _, cert = range verifier.keys {
+				has = true
+			}
+		}
+
+		if !has {
+			return nil, fmt.Errorf("the key %s does not found", kid)
+		}
+
+		// JWT ECDSA signature is R || S, convert it to ASN.1
+		if algorithm.size > 0 {
+			if len(signature) != algorithm.size*2 {
+				return nil, fmt.Errorf("the token signature is invalid")
+			}
+			signature, err = asn1.Marshal(struct{ R, S *big.Int }{
+				R: new(big.Int).SetBytes(signature[:algorithm.size]),
+				S: new(big.Int).SetBytes(signature[algorithm.size:]),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ok, err := ssl.Verify(data, signature, cert, algorithm.name)
+		if err != nil || !ok {
+			return nil, fmt.Errorf("the token signature is invalid")
+		}
+	}
+
+	claims := map[string]interface{}{}
+	if err := jwtDecode(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("the token payload is invalid")
+	}
+
+	return claims, verifier.validate(claims)
+}
+
+// validate the registered claims
+func (verifier *jwtVerifier) validate(claims map[string]interface{}) error {
+	now := time.Now().Unix()
+	leeway := int64(verifier.option.Leeway)
+
+	if exp, ok := claims["exp"].(float64); ok && now > int64(exp)+leeway {
+		return fmt.Errorf("the token is expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now+leeway < int64(nbf) {
+		return fmt.Errorf("the token is not valid yet")
+	}
+
+	if verifier.option.Issuer != "" && claims["iss"] != verifier.option.Issuer {
+		return fmt.Errorf("the token issuer is invalid")
+	}
+
+	if verifier.option.Audience != "" {
+		switch aud := claims["aud"].(type) {
+		case string:
+			if aud == verifier.option.Audience {
+				return nil
+			}
+		case []interface{}:
+			for _, v := range aud {
+				if v == verifier.option.Audience {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("the token audience is invalid")
+	}
+
+	return nil
+}
+
+func jwtDecode(segment string, v interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return jsoniter.Unmarshal(bytes, v)
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/session"
+	"github.com/yaoapp/kun/log"
+)
+
+// SessionOption the session cookie/header guard option
+type SessionOption struct {
+	Secret    string `json:"secret"`              // the signing or encryption secret, supports $ENV.NAME
+	Mode      string `json:"mode,omitempty"`      // sign (HMAC-SHA256), encrypt (AES-256-GCM), default is sign
+	Cookie    string `json:"cookie,omitempty"`    // the cookie name, default is __sid, "-" means the cookie is not used
+	Header    string `json:"header,omitempty"`    // the header name (e.g. X-Session-Id), the header is not used if not given
+	Domain    string `json:"domain,omitempty"`    // the cookie domain
+	Path      string `json:"path,omitempty"`      // the cookie path, default is /
+	MaxAge    int    `json:"maxAge,omitempty"`    // seconds, the cookie is a session cookie if not given
+	Secure    bool   `json:"secure,omitempty"`    // send the cookie over https only
+	HTTPOnly  *bool  `json:"httpOnly,omitempty"`  // the cookie can not be read by scripts, default is true
+	SameSite  string `json:"sameSite,omitempty"`  // lax, strict, none, default is lax
+	Required  bool   `json:"required,omitempty"`  // abort with 401 if the request has no valid session, a new session is created if false
+	Stateless bool   `json:"stateless,omitempty"` // keep the session data in the encrypted cookie (mode must be encrypt)
+	MaxSize   int    `json:"maxSize,omitempty"`   // the max size of the cookie value (bytes), default is 4096
+}
+
+// the session token payload
+type sessionPayload struct {
+	SID       string                 `json:"sid"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	ExpiredAt int64                  `json:"exp,omitempty"` // unix seconds
+}
+
+type sessionTransport struct {
+	option   SessionOption
+	secret   []byte
+	aead     cipher.AEAD
+	sameSite http.SameSite
+}
+
+// the response writer sets the session cookie and header before the response headers are sent
+type sessionWriter struct {
+	gin.ResponseWriter
+	commit func()
+}
+
+var sessionSameSite = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// SessionGuard create a guard reads and writes the session id from the signed or encrypted cookie (or header), and sets the __sid.
+// In the stateless mode the session data is kept in the encrypted cookie, it is the __session of the context
+// and the guard processes can change it by returning {"__session": {...}}.
+func SessionGuard(option SessionOption) (gin.HandlerFunc, error) {
+	transport, err := newSessionTransport(option)
+	if err != nil {
+		return nil, err
+	}
+	option = transport.option
+
+	return func(c *gin.Context) {
+		payload, err := transport.read(c)
+		if err != nil {
+			log.Warn("[Guard] session %s", err.Error())
+		}
+
+		if payload == nil && option.Required {
+			unauthorized(c, "the session is required")
+			return
+		}
+
+		if payload == nil {
+			payload = &sessionPayload{SID: session.ID()}
+		}
+
+		c.Set("__sid", payload.SID)
+		if option.Stateless {
+			if payload.Data == nil {
+				payload.Data = map[string]interface{}{}
+			}
+			c.Set("__session", payload.Data)
+		}
+
+		committed := false
+		commit := func() {
+			if committed {
+				return
+			}
+			committed = true
+			transport.write(c, payload)
+		}
+
+		writer := c.Writer
+		c.Writer = &sessionWriter{ResponseWriter: writer, commit: commit}
+		c.Next()
+		commit()
+		c.Writer = writer
+	}, nil
+}
+
+// statelessSession the session data of the stateless session guard
+func statelessSession(c *gin.Context) (map[string]interface{}, bool) {
+	if data, has := c.Get("__session"); has {
+		data, ok := data.(map[string]interface{})
+		return data, ok
+	}
+	return nil, false
+}
+
+func newSessionTransport(option SessionOption) (*sessionTransport, error) {
+	secret := helper.EnvString(option.Secret)
+	if secret == "" {
+		return nil, fmt.Errorf("secret is required")
+	}
+
+	option.Mode = strings.ToLower(defaultString(option.Mode, "sign"))
+	option.Cookie = defaultString(option.Cookie, "__sid")
+	option.Path = defaultString(option.Path, "/")
+	option.SameSite = strings.ToLower(defaultString(option.SameSite, "lax"))
+	if option.HTTPOnly == nil {
+		httpOnly := true
+		option.HTTPOnly = &httpOnly
+	}
+
+	if option.MaxSize <= 0 {
+		option.MaxSize = 4096
+	}
+
+	if option.Cookie == "-" && option.Header == "" {
+		return nil, fmt.Errorf("the cookie or the header is required")
+	}
+
+	sameSite, has := sessionSameSite[option.SameSite]
+	if !has {
+		return nil, fmt.Errorf("the sameSite %s does not support", option.SameSite)
+	}
+
+	transport := &sessionTransport{option: option, secret: []byte(secret), sameSite: sameSite}
+	switch option.Mode {
+	case "sign":
+		if option.Stateless {
+			return nil, fmt.Errorf("the stateless mode requires the encrypt mode")
+		}
+
+	case "encrypt":
+		key := sha256.Sum256([]byte(secret))
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+
+		transport.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("the mode %s does not support", option.Mode)
+	}
+
+	return transport, nil
+}
+
+// read the session of the request, the header takes precedence over the cookie. nil if the request has no session
+func (transport *sessionTransport) read(c *gin.Context) (*sessionPayload, error) {
+	token := ""
+	if transport.option.Header != "" {
+		token = c.GetHeader(transport.option.Header)
+	}
+
+	if token == "" && transport.option.Cookie != "-" {
+		token, _ = c.Cookie(transport.option.Cookie)
+	}
+
+	if token == "" {
+		return nil, nil
+	}
+	return transport.Decode(token)
+}
+
+// write the session cookie and header, the session id may be changed by the guard processes
+func (transport *sessionTransport) write(c *gin.Context, payload *sessionPayload) {
+	if sid := c.GetString("__sid"); sid != "" {
+		payload.SID = sid
+	}
+
+	if transport.option.Stateless {
+		if data, ok := statelessSession(c); ok {
+			payload.Data = data
+		}
+	}
+
+	payload.ExpiredAt = 0
+	if transport.option.MaxAge > 0 {
+		payload.ExpiredAt = time.Now().Unix() + int64(transport.option.MaxAge)
+	}
+
+	token, err := transport.Encode(payload)
+	if err != nil {
+		log.Error("[Guard] session %s", err.Error())
+		return
+	}
+
+	if len(token) > transport.option.MaxSize {
+		log.Error("[Guard] session the cookie is too large (%d > %d bytes)", len(token), transport.option.MaxSize)
+		return
+	}
+
+	if transport.option.Header != "" {
+		c.Header(transport.option.Header, token)
+	}
+
+	if transport.option.Cookie != "-" {
+		c.SetSameSite(transport.sameSite)
+		c.SetCookie(transport.option.Cookie, token, transport.option.MaxAge, transport.option.Path, transport.option.Domain, transport.option.Secure, *transport.option.HTTPOnly)
+	}
+}
+
+// Encode the session payload to the token
+// sign: BASE64URL(JSON) "." BASE64URL(HMAC-SHA256(BASE64URL(JSON))), encrypt: BASE64URL(NONCE + AES-GCM(JSON))
+func (transport *sessionTransport) Encode(payload *sessionPayload) (string, error) {
+	data, err := jsoniter.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	if transport.aead == nil {
+		encoded := base64.RawURLEncoding.EncodeToString(data)
+		return encoded + "." + base64.RawURLEncoding.EncodeToString(transport.sign(encoded)), nil
+	}
+
+	nonce := make([]byte, transport.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(transport.aead.Seal(nonce, nonce, data, nil)), nil
+}
+
+// Decode the token to the session payload, the token must be valid and not expired
+func (transport *sessionTransport) Decode(token string) (*sessionPayload, error) {
+	var data []byte
+	if transport.aead == nil {
+		parts := strings.Split(token, ".")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("the token is malformed")
+		}
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil || !hmac.Equal(signature, transport.sign(parts[0])) {
+			return nil, fmt.Errorf("the signature is invalid")
+		}
+
+		data, err = base64.RawURLEncoding.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("the token is malformed")
+		}
+
+	} else {
+		sealed, err := base64.RawURLEncoding.DecodeString(token)
+		size := transport.aead.NonceSize()
+		if err != nil || len(sealed) < size {
+			return nil, fmt.Errorf("the token is malformed")
+		}
+
+		data, err = transport.aead.Open(nil, sealed[:size], sealed[size:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("the token can not be decrypted")
+		}
+	}
+
+	payload := &sessionPayload{}
+	if err := jsoniter.Unmarshal(data, payload); err != nil || payload.SID == "" {
+		return nil, fmt.Errorf("the token is malformed")
+	}
+
+	if payload.ExpiredAt > 0 && payload.ExpiredAt < time.Now().Unix() {
+		return nil, fmt.Errorf("the session was expired")
+	}
+	return payload, nil
+}
+
+func (transport *sessionTransport) sign(data string) []byte {
+	mac := hmac.New(sha256.New, transport.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// WriteHeader set the session before the status code is written
+func (w *sessionWriter) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// WriteHeaderNow set the session before the headers are sent
+func (w *sessionWriter) WriteHeaderNow() {
+	w.commit()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write set the session before the body is written
+func (w *sessionWriter) Write(data []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString set the session before the body is written
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.commit()
+	return w.ResponseWriter.WriteString(s)
+}
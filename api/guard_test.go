@@ -0,0 +1,340 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/ssl"
+)
+
+func TestJWTGuardHS256(t *testing.T) {
+	guard, err := NewGuard("jwt", map[string]interface{}{"secret": "unit-test-secret", "issuer": "yao"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := guardRouter(guard)
+
+	token := jwtTestToken(t, "HS256", map[string]interface{}{"sid": "unit-sid", "iss": "yao", "user_id": 1, "exp": time.Now().Add(time.Hour).Unix()}, nil)
+	response := testRequest(router, "GET", "/guard", nil, map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, 200, response.Code)
+	res := responseMap(response)
+	assert.Equal(t, "unit-sid", res["sid"])
+	assert.Equal(t, float64(1), res["global"].(map[string]interface{})["user_id"])
+
+	// expired
+	token = jwtTestToken(t, "HS256", map[string]interface{}{"iss": "yao", "exp": time.Now().Add(-time.Hour).Unix()}, nil)
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, 401, response.Code)
+
+	// wrong issuer
+	token = jwtTestToken(t, "HS256", map[string]interface{}{"iss": "others"}, nil)
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, 401, response.Code)
+
+	// tampered
+	token = jwtTestToken(t, "HS256", map[string]interface{}{"iss": "yao"}, nil)
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Authorization": "Bearer " + token + "x"})
+	assert.Equal(t, 401, response.Code)
+}
+
+func TestJWTGuardES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := ssl.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssl.Certificates["unit-test-es256"] = cert
+
+	guard, err := JWTGuard(JWTOption{Cert: "unit-test-es256", Algorithms: []string{"ES256"}, Global: map[string]string{"uid": "user_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := guardRouter(guard)
+
+	token := jwtTestToken(t, "ES256", map[string]interface{}{"user_id": 2}, key)
+	response := testRequest(router, "GET", "/guard", nil, map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, float64(2), responseMap(response)["global"].(map[string]interface{})["uid"])
+
+	// the algorithm is not allowed
+	token = jwtTestToken(t, "HS256", map[string]interface{}{"user_id": 2}, nil)
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, 401, response.Code)
+}
+
+func TestAPIKeyGuard(t *testing.T) {
+	guard, err := NewGuard("api-key", map[string]interface{}{"keys": []string{"key-1", "key-2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := guardRouter(guard)
+
+	response := testRequest(router, "GET", "/guard", nil, map[string]string{"X-API-Key": "key-2"})
+	assert.Equal(t, 200, response.Code)
+
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"X-API-Key": "key-3"})
+	assert.Equal(t, 401, response.Code)
+
+	response = testRequest(router, "GET", "/guard", nil, nil)
+	assert.Equal(t, 401, response.Code)
+}
+
+func TestHMACGuard(t *testing.T) {
+	guard, err := NewGuard("hmac", map[string]interface{}{"secrets": map[string]string{"app-1": "unit-test-secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := guardRouter(guard)
+
+	body := []byte(`{"foo":"bar"}`)
+	headers := hmacTestHeaders("app-1", "unit-test-secret", "POST", "/guard?hello=world", "nonce-1", body)
+	response := testRequest(router, "POST", "/guard?hello=world", body, headers)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "app-1", responseMap(response)["global"].(map[string]interface{})["__hmac_key"])
+
+	// replay
+	response = testRequest(router, "POST", "/guard?hello=world", body, headers)
+	assert.Equal(t, 401, response.Code)
+
+	// concurrent replays
+	headers = hmacTestHeaders("app-1", "unit-test-secret", "POST", "/guard?hello=world", "nonce-4", body)
+	accepted := int32(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if testRequest(router, "POST", "/guard?hello=world", body, headers).Code == 200 {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), accepted)
+
+	// tampered body
+	headers = hmacTestHeaders("app-1", "unit-test-secret", "POST", "/guard?hello=world", "nonce-2", body)
+	response = testRequest(router, "POST", "/guard?hello=world", []byte(`{"foo":"baz"}`), headers)
+	assert.Equal(t, 401, response.Code)
+
+	// expired
+	headers = hmacTestHeaders("app-1", "unit-test-secret", "POST", "/guard?hello=world", "nonce-3", body)
+	headers["X-Timestamp"] = fmt.Sprintf("%d", time.Now().Add(-time.Hour).Unix())
+	response = testRequest(router, "POST", "/guard?hello=world", body, headers)
+	assert.Equal(t, 401, response.Code)
+
+	// the body is too large
+	guard, err = NewGuard("hmac", map[string]interface{}{"secrets": map[string]string{"app-1": "unit-test-secret"}, "maxBody": 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers = hmacTestHeaders("app-1", "unit-test-secret", "POST", "/guard?hello=world", "nonce-5", body)
+	response = testRequest(guardRouter(guard), "POST", "/guard?hello=world", body, headers)
+	assert.Equal(t, 413, response.Code)
+}
+
+func guardRouter(guard gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	handler := func(c *gin.Context) {
+		global, _ := c.Get("__global")
+		c.JSON(200, gin.H{"sid": c.GetString("__sid"), "global": global})
+	}
+	router.GET("/guard", guard, handler)
+	router.POST("/guard", guard, handler)
+	return router
+}
+
+func TestSessionGuard(t *testing.T) {
+	guard, err := NewGuard("session", map[string]interface{}{
+		"secret": "unit-test-secret", "header": "X-Session-Id", "domain": "example.com",
+		"secure": true, "sameSite": "strict", "maxAge": 3600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := guardRouter(guard)
+
+	// a new session
+	response := testRequest(router, "GET", "/guard", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	sid := responseMap(response)["sid"].(string)
+	assert.NotEmpty(t, sid)
+
+	cookie := response.Header().Get("Set-Cookie")
+	assert.Contains(t, cookie, "__sid=")
+	assert.Contains(t, cookie, "Domain=example.com")
+	assert.Contains(t, cookie, "Max-Age=3600")
+	assert.Contains(t, cookie, "HttpOnly")
+	assert.Contains(t, cookie, "Secure")
+	assert.Contains(t, cookie, "SameSite=Strict")
+
+	// the cookie
+	token := response.Result().Cookies()[0].Value
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Cookie": "__sid=" + token})
+	assert.Equal(t, sid, responseMap(response)["sid"])
+
+	// the header
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"X-Session-Id": response.Header().Get("X-Session-Id")})
+	assert.Equal(t, sid, responseMap(response)["sid"])
+
+	// the tampered cookie
+	parts := strings.Split(token, ".")
+	forged, _ := jsoniter.Marshal(map[string]interface{}{"sid": "forged"})
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Cookie": "__sid=" + base64.RawURLEncoding.EncodeToString(forged) + "." + parts[1]})
+	assert.NotEqual(t, "forged", responseMap(response)["sid"])
+	assert.NotEqual(t, sid, responseMap(response)["sid"])
+
+	// required
+	guard, err = NewGuard("session", map[string]interface{}{"secret": "unit-test-secret", "required": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response = guardRequest(guardRouter(guard), "GET", "/guard", nil, map[string]string{"Cookie": "__sid=invalid"})
+	assert.Equal(t, 401, response.Code)
+
+	_, err = NewGuard("session", map[string]interface{}{"secret": "unit-test-secret", "stateless": true})
+	assert.NotNil(t, err)
+}
+
+func TestSessionGuardStateless(t *testing.T) {
+	guard, err := NewGuard("session", map[string]interface{}{"secret": "unit-test-secret", "mode": "encrypt", "stateless": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET("/guard", guard, func(c *gin.Context) {
+		data := c.MustGet("__session").(map[string]interface{})
+		count, _ := data["count"].(float64)
+		data["count"] = count + 1
+		c.JSON(200, gin.H{"sid": c.GetString("__sid"), "count": data["count"]})
+	})
+
+	response := testRequest(router, "GET", "/guard", nil, nil)
+	sid := responseMap(response)["sid"]
+	for i := 2; i <= 3; i++ {
+		token := response.Result().Cookies()[0].Value
+		assert.NotContains(t, token, "count")
+		response = testRequest(router, "GET", "/guard", nil, map[string]string{"Cookie": "__sid=" + token})
+		assert.Equal(t, float64(i), responseMap(response)["count"])
+		assert.Equal(t, sid, responseMap(response)["sid"])
+	}
+
+	// the cookie encrypted by the other secret
+	other, _ := NewGuard("session", map[string]interface{}{"secret": "other-secret", "mode": "encrypt", "stateless": true})
+	response = guardRequest(guardRouter(other), "GET", "/guard", nil, map[string]string{"Cookie": "__sid=" + response.Result().Cookies()[0].Value})
+	assert.NotEqual(t, sid, responseMap(response)["sid"])
+}
+
+func TestCSRFGuard(t *testing.T) {
+	sessionGuard, err := NewGuard("session", map[string]interface{}{"secret": "unit-test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	csrfGuard, err := NewGuard("csrf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(sessionGuard, csrfGuard)
+	router.GET("/guard", func(c *gin.Context) { c.JSON(200, gin.H{"sid": c.GetString("__sid")}) })
+	router.POST("/guard", func(c *gin.Context) { c.JSON(200, gin.H{"sid": c.GetString("__sid")}) })
+
+	response := testRequest(router, "GET", "/guard", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	token := response.Header().Get("X-CSRF-Token")
+	assert.NotEmpty(t, token)
+
+	cookie := "__sid=" + response.Result().Cookies()[0].Value
+	response = testRequest(router, "GET", "/guard", nil, map[string]string{"Cookie": cookie})
+	assert.Equal(t, token, response.Header().Get("X-CSRF-Token"))
+
+	// the header
+	response = testRequest(router, "POST", "/guard", nil, map[string]string{"Cookie": cookie, "X-CSRF-Token": token})
+	assert.Equal(t, 200, response.Code)
+
+	// the form
+	body := []byte("_csrf=" + url.QueryEscape(token))
+	response = testRequest(router, "POST", "/guard", body, map[string]string{"Cookie": cookie, "Content-Type": "application/x-www-form-urlencoded"})
+	assert.Equal(t, 200, response.Code)
+
+	// the token is missing or invalid
+	response = testRequest(router, "POST", "/guard", nil, map[string]string{"Cookie": cookie})
+	assert.Equal(t, 403, response.Code)
+
+	response = testRequest(router, "POST", "/guard", nil, map[string]string{"Cookie": cookie, "X-CSRF-Token": "invalid"})
+	assert.Equal(t, 403, response.Code)
+
+	// the token of the other session
+	response = testRequest(router, "POST", "/guard", nil, map[string]string{"X-CSRF-Token": token})
+	assert.Equal(t, 403, response.Code)
+}
+
+func hmacTestHeaders(id, secret, method, uri, nonce string, body []byte) map[string]string {
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{method, uri, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")))
+	return map[string]string{
+		"X-Key-Id":    id,
+		"X-Timestamp": timestamp,
+		"X-Nonce":     nonce,
+		"X-Signature": hex.EncodeToString(mac.Sum(nil)),
+	}
+}
+
+func jwtTestToken(t *testing.T, alg string, claims map[string]interface{}, key *ecdsa.PrivateKey) string {
+	header, _ := jsoniter.Marshal(map[string]interface{}{"alg": alg, "typ": "JWT"})
+	payload, _ := jsoniter.Marshal(claims)
+	data := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, []byte("unit-test-secret"))
+		mac.Write([]byte(data))
+		signature = mac.Sum(nil)
+
+	case "ES256":
+		hashed := crypto.SHA256.New()
+		hashed.Write([]byte(data))
+		r, s, err := ecdsa.Sign(rand.Reader, key, hashed.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(padBytes(r, 32), padBytes(s, 32)...)
+	}
+
+	return data + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func padBytes(n *big.Int, size int) []byte {
+	bytes := n.Bytes()
+	return append(make([]byte, size-len(bytes)), bytes...)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/observe"
 	"github.com/yaoapp/gou/process"
 	v8 "github.com/yaoapp/gou/runtime/v8"
 	"github.com/yaoapp/gou/runtime/v8/bridge"
@@ -46,6 +47,9 @@ func (path Path) defaultHandler(getArgs func(c *gin.Context) []interface{}) func
 			// Set ContentType
 			contentType = path.setResponseHeaders(c, resp, contentType)
 
+			// Transform
+			resp = path.After.apply(resp)
+
 			// Format Body
 			body := resp
 			if path.Out.Body != nil {
@@ -58,7 +62,7 @@ func (path Path) defaultHandler(getArgs func(c *gin.Context) []interface{}) func
 
 			switch data := body.(type) {
 			case maps.Map, map[string]interface{}, []interface{}, []maps.Map, []map[string]interface{}:
-				c.JSON(status, data)
+				path.render(c, status, data)
 				c.Done()
 				return
 
@@ -69,15 +73,15 @@ func (path Path) defaultHandler(getArgs func(c *gin.Context) []interface{}) func
 
 			case error:
 				ex := exception.Err(data, 500)
-				c.JSON(ex.Code, gin.H{"message": ex.Message, "code": ex.Code})
+				path.renderError(c, ex.Code, ex.Message, nil)
 
 			case nil:
 				c.Done()
 				return
 
 			default:
-				if strings.HasPrefix(contentType, "application/json") {
-					c.JSON(status, body)
+				if strings.HasPrefix(contentType, "application/json") || len(path.Out.Formats) > 0 {
+					path.render(c, status, body)
 					c.Done()
 					return
 				}
@@ -132,61 +136,7 @@ func (path Path) redirectHandler(getArgs func(c *gin.Context) []interface{}) fun
 	}
 }
 
-func (path Path) streamHandler(getArgs func(c *gin.Context) []interface{}) func(c *gin.Context) {
-	return func(c *gin.Context) {
-
-		path.setPayload(c)
-		path.reqContentType(c)
-
-		chanStream := make(chan ssEventData, 1)
-		chanError := make(chan error, 1)
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		go func() {
-			defer func() {
-				close(chanStream)
-				close(chanError)
-			}()
-
-			path.runStreamScript(ctx, c, getArgs,
-				// event
-				func(name string, message interface{}) {
-					chanStream <- ssEventData{Name: name, Message: message}
-				},
-
-				// cancel
-				func() { cancel() },
-
-				// error
-				func(err error) {
-					chanError <- err
-				},
-			)
-		}()
-
-		c.Stream(func(w io.Writer) bool {
-			select {
-			case err := <-chanError:
-				if err != nil {
-					log.Error("[Stream] %s Error: %v", path.Path, err)
-				}
-				return false
-
-			case msg := <-chanStream:
-				log.Trace("[Stream] %s %s %s %v", path.Path, path.Process, msg.Name, msg.Message)
-				c.SSEvent(msg.Name, msg.Message)
-				return true
-
-			case <-ctx.Done():
-				// log.Info("[DONE] %s", path.Path)
-				return false
-			}
-		})
-	}
-}
-
-func (path Path) runStreamScript(ctx context.Context, c *gin.Context, getArgs func(c *gin.Context) []interface{}, onEvent func(name string, message interface{}), onCancel func(), onError func(error)) {
+func (path Path) runStreamScript(ctx context.Context, sid string, global map[string]interface{}, args []interface{}, onEvent func(name string, message interface{}), onCancel func(), onError func(error)) {
 
 	if !strings.HasPrefix(path.Process, "scripts") {
 		onError(fmt.Errorf("process must be a script"))
@@ -202,20 +152,6 @@ func (path Path) runStreamScript(ctx context.Context, c *gin.Context, getArgs fu
 		return
 	}
 
-	// bind session and global data
-	sid := ""
-	global := map[string]interface{}{}
-	if v, has := c.Get("__sid"); has { // set session id
-		if v, ok := v.(string); ok {
-			sid = v
-		}
-	}
-	if v, has := c.Get("__global"); has { // set global
-		if v, ok := v.(map[string]interface{}); ok {
-			global = v
-		}
-	}
-
 	// make a new script context
 	v8ctx, err := script.NewContext(sid, global)
 	if err != nil {
@@ -245,7 +181,6 @@ func (path Path) runStreamScript(ctx context.Context, c *gin.Context, getArgs fu
 		return v8go.Null(info.Context().Isolate())
 	})
 
-	args := getArgs(c)
 	_, err = v8ctx.CallWith(ctx, method, args...)
 	if err != nil {
 		onError(err)
@@ -275,7 +210,7 @@ func (path Path) execProcess(ctx context.Context, chRes chan<- interface{}, c *g
 		}
 	}
 
-	process.WithContext(ctx)
+	process.WithContext(observe.Propagate(ctx, c.Request.Context()))
 	res, err := process.Exec()
 	if err != nil {
 		log.Error("[Path] %s %s", path.Path, err.Error())
@@ -302,7 +237,7 @@ func (path Path) runProcess(ctx context.Context, c *gin.Context, getArgs func(c
 		}
 	}
 
-	process.WithContext(ctx)
+	process.WithContext(observe.Propagate(ctx, c.Request.Context()))
 	return process.Run()
 }
 
@@ -339,6 +274,11 @@ func (path Path) setResponseHeaders(c *gin.Context, resp interface{}, contentTyp
 
 func (path Path) setPayload(c *gin.Context) {
 
+	// the payloads has been set by the validator
+	if _, has := c.Get("__payloads"); has {
+		return
+	}
+
 	if strings.HasPrefix(strings.ToLower(c.GetHeader("content-type")), "application/json") {
 
 		if c.Request.Body == nil {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/observe"
 	"github.com/yaoapp/gou/process"
 	"github.com/yaoapp/gou/session"
 	"github.com/yaoapp/gou/types"
@@ -56,8 +57,7 @@ func ProcessGuard(name string) gin.HandlerFunc {
 
 		process, err := process.Of(name, args...)
 		if err != nil {
-			c.JSON(400, gin.H{"code": 400, "message": fmt.Sprintf("Guard: %s %s", name, err.Error())})
-			c.Abort()
+			abort(c, 400, fmt.Sprintf("Guard: %s %s", name, err.Error()), nil)
 			return
 		}
 
@@ -82,6 +82,14 @@ func ProcessGuard(name string) gin.HandlerFunc {
 			if global, ok := data["__global"].(map[string]interface{}); ok {
 				c.Set("__global", global)
 			}
+
+			if values, ok := data["__session"].(map[string]interface{}); ok { // the stateless session data
+				if current, ok := statelessSession(c); ok {
+					for name, value := range values {
+						current[name] = value
+					}
+				}
+			}
 		}
 	}
 }
@@ -126,10 +134,7 @@ func IsAllowed(c *gin.Context, allowsMap map[string]bool) bool {
 // Routes 配置转换为路由
 func (http HTTP) Routes(router *gin.Engine, path string, allows ...string) {
 	var group gin.IRoutes = router
-	if http.Group != "" {
-		path = filepath.Join(path, "/", http.Group)
-	}
-	group = router.Group(path)
+	group = router.Group(http.prefix(path))
 	for _, path := range http.Paths {
 		path.Method = strings.ToUpper(path.Method)
 		http.Route(group, path, allows...)
@@ -140,7 +145,9 @@ func (http HTTP) Routes(router *gin.Engine, path string, allows ...string) {
 // Route 路径配置转换为路由
 func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 	getArgs := http.parseIn(path.In)
-	handlers := []gin.HandlerFunc{}
+
+	// the errors of the path are written with its error envelope
+	handlers := []gin.HandlerFunc{func(c *gin.Context) { c.Set("__path", path) }}
 
 	// 跨域访问
 	if allows != nil && len(allows) > 0 {
@@ -156,8 +163,7 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 			if origin != "" {
 
 				if !IsAllowed(c, allowsMap) {
-					c.JSON(403, gin.H{"code": 403, "message": "referer is not allowed. allows: " + strings.Join(allows, ",")})
-					c.Abort()
+					abort(c, 403, "referer is not allowed. allows: "+strings.Join(allows, ","), nil)
 					return
 				}
 
@@ -173,11 +179,42 @@ func (http HTTP) Route(router gin.IRoutes, path Path, allows ...string) {
 		})
 	}
 
+	// deprecation headers
+	if path.Deprecated || path.Sunset != "" || http.Deprecated || http.Sunset != "" {
+		handlers = append(handlers, path.deprecationHandler(http.Deprecated, http.Sunset))
+	}
+
+	// validate the request before the guards
+	if path.Schema != nil {
+		handlers = append(handlers, path.validator())
+	}
+
 	// set middlewares
 	http.guard(&handlers, path.Guard, http.Guard)
 
+	// annotate the observed request
+	handlers = append(handlers, func(c *gin.Context) { observe.Annotate(c, path.Process) })
+
+	// stream the uploaded files to the file system
+	if path.uploads() {
+		handlers = append(handlers, path.uploadHandler())
+	}
+
+	// transform the payloads
+	if path.Before != nil {
+		handlers = append(handlers, path.beforeHandler())
+	}
+
+	// response cache
+	if path.Cache != nil && path.Cache.Store != "" {
+		handlers = append(handlers, path.cacheHandler(http.Group, path.guarded(http.Guard)))
+	}
+
 	// set http handler
-	if path.Out.Redirect != nil {
+	if path.resource != nil {
+		handlers = append(handlers, path.resourceHandler())
+
+	} else if path.Out.Redirect != nil {
 		handlers = append(handlers, path.redirectHandler(getArgs))
 
 	} else if path.ProcessHandler {
@@ -199,18 +236,35 @@ func (http HTTP) guard(handlers *[]gin.HandlerFunc, guard string, defaults strin
 	if guard == "" {
 		guard = defaults
 	}
+	*handlers = append(*handlers, Guards(guard)...)
+}
 
-	if guard != "-" {
-		guards := strings.Split(guard, ",")
-		for _, name := range guards {
-			name = strings.TrimSpace(name)
-			if handler, has := HTTPGuards[name]; has {
-				*handlers = append(*handlers, handler)
-			} else { // run process process
-				*handlers = append(*handlers, ProcessGuard(name))
-			}
+// guarded check if the path is guarded, the guard of the path takes precedence over the default guard
+func (path Path) guarded(defaults string) bool {
+	guard := path.Guard
+	if guard == "" {
+		guard = defaults
+	}
+	return guard != "-"
+}
+
+// Guards the handlers of the comma separated guards, the registered HTTPGuards or the guard processes. "-" means no guard,
+// an empty guard is a process guard without the process, it rejects all the requests.
+func Guards(guard string) []gin.HandlerFunc {
+	handlers := []gin.HandlerFunc{}
+	if guard == "-" {
+		return handlers
+	}
+
+	for _, name := range strings.Split(guard, ",") {
+		name = strings.TrimSpace(name)
+		if handler, has := HTTPGuards[name]; has {
+			handlers = append(handlers, handler)
+		} else { // run process process
+			handlers = append(handlers, ProcessGuard(name))
 		}
 	}
+	return handlers
 }
 
 // setCorsOption 跨域许可
@@ -307,6 +361,26 @@ func (http HTTP) parseIn(in []interface{}) func(c *gin.Context) []interface{} {
 				return c
 			})
 			continue
+		} else if v == ":files" {
+			getValues = append(getValues, func(c *gin.Context) interface{} {
+				files := []interface{}{}
+				for _, file := range uploadedFiles(c) {
+					files = append(files, file.Map())
+				}
+				return files
+			})
+			continue
+		} else if strings.HasPrefix(v, ":file.") {
+			field := strings.TrimPrefix(v, ":file.")
+			getValues = append(getValues, func(c *gin.Context) interface{} {
+				for _, file := range uploadedFiles(c) {
+					if file.Field == field {
+						return file.Map()
+					}
+				}
+				return nil
+			})
+			continue
 		}
 
 		arg := strings.Split(v, ".")
@@ -317,11 +391,17 @@ func (http HTTP) parseIn(in []interface{}) func(c *gin.Context) []interface{} {
 			})
 		} else if arg[0] == "$param" && length == 2 {
 			getValues = append(getValues, func(c *gin.Context) interface{} {
+				if value, has := coerced(c, "params", arg[1]); has {
+					return value
+				}
 				return c.Param(arg[1])
 			})
 
 		} else if arg[0] == "$query" && length == 2 {
 			getValues = append(getValues, func(c *gin.Context) interface{} {
+				if value, has := coerced(c, "query", arg[1]); has {
+					return value
+				}
 				return c.Query(arg[1])
 			})
 
@@ -337,6 +417,10 @@ func (http HTTP) parseIn(in []interface{}) func(c *gin.Context) []interface{} {
 
 		} else if arg[0] == "$session" && length == 2 {
 			getValues = append(getValues, func(c *gin.Context) interface{} {
+				if data, ok := statelessSession(c); ok {
+					return data[arg[1]]
+				}
+
 				if sid := c.GetString("__sid"); sid != "" {
 					name := arg[1]
 					return session.Global().ID(sid).MustGet(name)
@@ -346,6 +430,9 @@ func (http HTTP) parseIn(in []interface{}) func(c *gin.Context) []interface{} {
 
 		} else if arg[0] == "$header" && length == 2 {
 			getValues = append(getValues, func(c *gin.Context) interface{} {
+				if value, has := coerced(c, "headers", arg[1]); has {
+					return value
+				}
 				return c.GetHeader(arg[1])
 			})
 
@@ -413,6 +500,9 @@ func (http HTTP) method(name string, path string, router gin.IRoutes, handlers .
 	case "DELETE":
 		router.DELETE(path, handlers...)
 		return
+	case "PATCH":
+		router.PATCH(path, handlers...)
+		return
 	case "HEAD":
 		router.HEAD(path, handlers...)
 		return
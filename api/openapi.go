@@ -0,0 +1,546 @@
+package api
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/model"
+)
+
+// OpenAPIRoute the route of the OpenAPI document (relative to the api root, e.g. /openapi.json), the route is not registered if it is empty
+var OpenAPIRoute = ""
+
+// OpenAPIGuard the comma separated guards of the OpenAPI document route, "-" means no guard.
+// The document publishes the processes and the guards of all the paths, the route rejects all the requests if it is empty.
+var OpenAPIGuard = ""
+
+// OpenAPIInfo the info object of the OpenAPI document
+var OpenAPIInfo = map[string]interface{}{"title": "API Reference", "version": "1.0.0"}
+
+var reRouteParam = regexp.MustCompile(`[:*]([a-zA-Z_][0-9a-zA-Z_]*)`)
+
+// openapi the OpenAPI document builder
+type openapi struct {
+	paths   map[string]map[string]interface{}
+	schemas map[string]interface{}
+}
+
+// OpenAPI generate the OpenAPI 3.1 document from the loaded APIs
+func OpenAPI(root string) map[string]interface{} {
+
+	doc := &openapi{
+		paths:   map[string]map[string]interface{}{},
+		schemas: map[string]interface{}{},
+	}
+
+	ids := []string{}
+	for id := range APIs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		api := APIs[id]
+		http := api.resolve()
+		prefix := http.prefix(root)
+		for _, path := range http.Paths {
+			route := reRouteParam.ReplaceAllString(filepath.Join(prefix, path.Path), "{$1}")
+			if _, has := doc.paths[route]; !has {
+				doc.paths[route] = map[string]interface{}{}
+			}
+
+			method := strings.ToUpper(path.Method)
+			methods := []string{method}
+			if method == "ANY" {
+				methods = []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+			}
+
+			for _, method := range methods {
+				doc.paths[route][strings.ToLower(method)] = doc.operation(api, http, path, method)
+			}
+		}
+	}
+
+	paths := map[string]interface{}{}
+	for route, operations := range doc.paths {
+		paths[route] = operations
+	}
+
+	return map[string]interface{}{
+		"openapi":    "3.1.0",
+		"info":       OpenAPIInfo,
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": doc.schemas},
+	}
+}
+
+// openAPIHandler serve the OpenAPI document
+func openAPIHandler(root string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(200, OpenAPI(root))
+	}
+}
+
+// operation make the operation object of the path
+func (doc *openapi) operation(api *API, http HTTP, path Path, method string) map[string]interface{} {
+
+	operation := map[string]interface{}{
+		"operationId": fmt.Sprintf("%s.%s%s", api.ID, strings.ToLower(method), strings.ReplaceAll(reRouteParam.ReplaceAllString(path.Path, "$1"), "/", ".")),
+		"tags":        []string{api.ID},
+		"x-process":   path.Process,
+	}
+
+	if path.Label != "" {
+		operation["summary"] = path.Label
+	}
+
+	if path.Description != "" {
+		operation["description"] = path.Description
+	}
+
+	if path.Deprecated || http.Deprecated {
+		operation["deprecated"] = true
+	}
+
+	if http.Versioning != "" && http.Version != "" {
+		operation["x-version"] = http.Version
+	}
+
+	guard := path.Guard
+	if guard == "" {
+		guard = http.Guard
+	}
+	if guard != "" && guard != "-" {
+		operation["x-guard"] = guard
+	}
+
+	parameters, body := doc.parameters(path)
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if body != nil && method != "GET" && method != "HEAD" && method != "DELETE" {
+		operation["requestBody"] = body
+	}
+
+	operation["responses"] = doc.responses(path)
+	return operation
+}
+
+// parameters infer the parameters and the request body from the path params, the in bindings and the schema
+func (doc *openapi) parameters(path Path) ([]interface{}, map[string]interface{}) {
+
+	parameters := []interface{}{}
+	registered := map[string]bool{}
+	addParameter := func(in string, name string, schema map[string]interface{}, required bool, description string) {
+		key := fmt.Sprintf("%s.%s", in, name)
+		if registered[key] {
+			return
+		}
+		registered[key] = true
+		parameter := map[string]interface{}{"name": name, "in": in, "schema": schema}
+		if required || in == "path" {
+			parameter["required"] = true
+		}
+		if description != "" {
+			parameter["description"] = description
+		}
+		parameters = append(parameters, parameter)
+	}
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	contentType := "application/json"
+	hasBody := false
+
+	// the schema fields first
+	if path.Schema != nil {
+		for _, field := range path.Schema.Params {
+			addParameter("path", field.Name, field.openAPISchema(), true, field.Description)
+		}
+		for _, field := range path.Schema.Query {
+			addParameter("query", field.Name, field.openAPISchema(), field.Required, field.Description)
+		}
+		for _, field := range path.Schema.Headers {
+			addParameter("header", field.Name, field.openAPISchema(), field.Required, field.Description)
+		}
+		for _, field := range path.Schema.Body {
+			hasBody = true
+			properties[field.Name] = field.openAPISchema()
+			if field.Required {
+				required = append(required, field.Name)
+			}
+		}
+	}
+
+	// the route params
+	for _, match := range reRouteParam.FindAllStringSubmatch(path.Path, -1) {
+		addParameter("path", match[1], map[string]interface{}{"type": "string"}, true, "")
+	}
+
+	// the in bindings
+	var bodySchema map[string]interface{}
+	for _, value := range path.In {
+		v, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		switch v {
+		case ":query-param", ":params": // both bind the query string as the query param (see parseIn), the route params are $param.<name>
+			for _, parameter := range doc.queryParamParameters(path.Process) {
+				addParameter("query", parameter["name"].(string), parameter["schema"].(map[string]interface{}), false, "")
+			}
+			continue
+
+		case ":payload", ":body":
+			hasBody = true
+			bodySchema = doc.payloadSchema(path.Process)
+			continue
+
+		case ":form":
+			hasBody = true
+			contentType = "application/x-www-form-urlencoded"
+			continue
+
+		case ":files":
+			hasBody = true
+			contentType = "multipart/form-data"
+			continue
+		}
+
+		if strings.HasPrefix(v, ":file.") {
+			hasBody = true
+			contentType = "multipart/form-data"
+			properties[strings.TrimPrefix(v, ":file.")] = map[string]interface{}{"type": "string", "format": "binary"}
+			continue
+		}
+
+		arg := strings.Split(v, ".")
+		if len(arg) != 2 {
+			continue
+		}
+
+		switch arg[0] {
+		case "$param":
+			addParameter("path", arg[1], map[string]interface{}{"type": "string"}, true, "")
+		case "$query":
+			addParameter("query", arg[1], map[string]interface{}{"type": "string"}, false, "")
+		case "$header":
+			addParameter("header", arg[1], map[string]interface{}{"type": "string"}, false, "")
+		case "$payload":
+			hasBody = true
+			if _, has := properties[arg[1]]; !has {
+				properties[arg[1]] = map[string]interface{}{}
+			}
+		case "$form":
+			hasBody = true
+			contentType = "application/x-www-form-urlencoded"
+			if _, has := properties[arg[1]]; !has {
+				properties[arg[1]] = map[string]interface{}{"type": "string"}
+			}
+		case "$file":
+			hasBody = true
+			contentType = "multipart/form-data"
+			properties[arg[1]] = map[string]interface{}{"type": "string", "format": "binary"}
+		}
+	}
+
+	if !hasBody {
+		return parameters, nil
+	}
+
+	if bodySchema == nil {
+		bodySchema = map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			bodySchema["required"] = required
+		}
+	}
+
+	return parameters, map[string]interface{}{
+		"content": map[string]interface{}{
+			contentType: map[string]interface{}{"schema": bodySchema},
+		},
+	}
+}
+
+// responses make the responses object of the path
+func (doc *openapi) responses(path Path) map[string]interface{} {
+
+	status := path.Out.Status
+	if status == 0 {
+		status = 200
+	}
+
+	response := map[string]interface{}{"description": fmt.Sprintf("%d", status)}
+	if path.Out.Redirect != nil {
+		code := path.Out.Redirect.Code
+		if code == 0 {
+			code = 301
+		}
+		response["headers"] = map[string]interface{}{
+			"Location": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+		return map[string]interface{}{fmt.Sprintf("%d", code): response}
+	}
+
+	if len(path.Out.Headers) > 0 {
+		headers := map[string]interface{}{}
+		for name := range path.Out.Headers {
+			headers[name] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+		response["headers"] = headers
+	}
+
+	contentType := path.Out.Type
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	schema := map[string]interface{}{}
+	if strings.HasPrefix(contentType, "application/json") {
+		schema = doc.responseSchema(path.Process)
+	}
+	response["content"] = map[string]interface{}{contentType: map[string]interface{}{"schema": schema}}
+
+	return map[string]interface{}{
+		fmt.Sprintf("%d", status): response,
+		"400":                     doc.errorResponse("Bad Request"),
+		"500":                     doc.errorResponse("Internal Server Error"),
+	}
+}
+
+// errorResponse the error response object
+func (doc *openapi) errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"code":    map[string]interface{}{"type": "integer"},
+						"message": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// queryParamParameters the query string parameters of the model query param
+func (doc *openapi) queryParamParameters(name string) []map[string]interface{} {
+	parameters := []map[string]interface{}{
+		{"name": "select", "schema": map[string]interface{}{"type": "string"}},
+		{"name": "with", "schema": map[string]interface{}{"type": "string"}},
+		{"name": "order", "schema": map[string]interface{}{"type": "string"}},
+		{"name": "page", "schema": map[string]interface{}{"type": "integer"}},
+		{"name": "pagesize", "schema": map[string]interface{}{"type": "integer"}},
+	}
+
+	mod, _ := doc.model(name)
+	if mod == nil {
+		return parameters
+	}
+
+	for _, column := range mod.MetaData.Columns {
+		parameters = append(parameters, map[string]interface{}{
+			"name":   fmt.Sprintf("where.%s.eq", column.Name),
+			"schema": columnOpenAPISchema(column),
+		})
+	}
+	return parameters
+}
+
+// payloadSchema the request body schema of the model processes
+func (doc *openapi) payloadSchema(name string) map[string]interface{} {
+	mod, method := doc.model(name)
+	if mod == nil {
+		return map[string]interface{}{"type": "object"}
+	}
+
+	switch method {
+	case "create", "update", "save":
+		return doc.ref(mod)
+	case "insert", "eachsave", "eachsaveafterdelete":
+		return map[string]interface{}{"type": "array", "items": doc.ref(mod)}
+	}
+	return map[string]interface{}{"type": "object"}
+}
+
+// responseSchema the response schema of the model processes
+func (doc *openapi) responseSchema(name string) map[string]interface{} {
+	mod, method := doc.model(name)
+	if mod == nil {
+		return map[string]interface{}{}
+	}
+
+	switch method {
+	case "find":
+		return doc.ref(mod)
+
+	case "get":
+		return map[string]interface{}{"type": "array", "items": doc.ref(mod)}
+
+	case "paginate":
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"data":     map[string]interface{}{"type": "array", "items": doc.ref(mod)},
+				"total":    map[string]interface{}{"type": "integer"},
+				"page":     map[string]interface{}{"type": "integer"},
+				"pagesize": map[string]interface{}{"type": "integer"},
+				"pagecnt":  map[string]interface{}{"type": "integer"},
+				"next":     map[string]interface{}{"type": "integer"},
+				"prev":     map[string]interface{}{"type": "integer"},
+			},
+		}
+
+	case "create", "save", "delete", "destroy", "updatewhere", "deletewhere", "destroywhere":
+		return map[string]interface{}{"type": "integer"}
+	}
+
+	return map[string]interface{}{}
+}
+
+// model get the model and the method of the models process
+func (doc *openapi) model(name string) (*model.Model, string) {
+	fields := strings.Split(name, ".")
+	if len(fields) < 3 || strings.ToLower(fields[0]) != "models" {
+		return nil, ""
+	}
+
+	id := strings.ToLower(strings.Join(fields[1:len(fields)-1], "."))
+	mod, has := model.Models[id]
+	if !has {
+		return nil, ""
+	}
+	return mod, strings.ToLower(fields[len(fields)-1])
+}
+
+// ref register the model schema and return the reference
+func (doc *openapi) ref(mod *model.Model) map[string]interface{} {
+	if _, has := doc.schemas[mod.ID]; !has {
+		properties := map[string]interface{}{}
+		for _, column := range mod.MetaData.Columns {
+			properties[column.Name] = columnOpenAPISchema(column)
+		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if mod.MetaData.Name != "" {
+			schema["title"] = mod.MetaData.Name
+		}
+		doc.schemas[mod.ID] = schema
+	}
+	return map[string]interface{}{"$ref": fmt.Sprintf("#/components/schemas/%s", mod.ID)}
+}
+
+// openAPISchema the OpenAPI schema of the request field
+func (field Field) openAPISchema() map[string]interface{} {
+	schema := map[string]interface{}{}
+	switch strings.ToLower(field.Type) {
+	case "int", "integer":
+		schema["type"] = "integer"
+	case "float", "number":
+		schema["type"] = "number"
+	case "bool", "boolean":
+		schema["type"] = "boolean"
+	case "array", "object", "string":
+		schema["type"] = strings.ToLower(field.Type)
+	}
+
+	if field.Default != nil {
+		schema["default"] = field.Default
+	}
+
+	for _, validation := range field.Validations {
+		if len(validation.Args) == 0 {
+			continue
+		}
+		switch validation.Method {
+		case "min":
+			schema["minimum"] = validation.Args[0]
+		case "max":
+			schema["maximum"] = validation.Args[0]
+		case "minLength":
+			schema["minLength"] = validation.Args[0]
+		case "maxLength":
+			schema["maxLength"] = validation.Args[0]
+		case "pattern":
+			schema["pattern"] = validation.Args[0]
+		case "enum":
+			schema["enum"] = validation.Args
+		}
+	}
+	return schema
+}
+
+// columnOpenAPISchema the OpenAPI schema of the model column
+func columnOpenAPISchema(column model.Column) map[string]interface{} {
+
+	var typ interface{} = "string"
+	schema := map[string]interface{}{}
+	switch column.Type {
+	case "tinyInteger", "unsignedTinyInteger", "tinyIncrements",
+		"smallInteger", "unsignedSmallInteger", "smallIncrements",
+		"integer", "unsignedInteger", "increments",
+		"bigInteger", "unsignedBigInteger", "bigIncrements", "id", "ID", "year":
+		typ = "integer"
+
+	case "decimal", "unsignedDecimal", "float", "unsignedFloat", "double", "unsignedDouble":
+		typ = "number"
+
+	case "Boolean", "boolean":
+		typ = "boolean"
+
+	case "json", "JSON", "jsonb", "JSONB":
+		typ = []interface{}{"object", "array"}
+
+	case "date":
+		schema["format"] = "date"
+
+	case "datetime", "datetimeTz", "timestamp", "timestampTz":
+		schema["format"] = "date-time"
+
+	case "time", "timeTz":
+		schema["format"] = "time"
+
+	case "uuid":
+		schema["format"] = "uuid"
+
+	case "enum":
+		if len(column.Option) > 0 {
+			schema["enum"] = column.Option
+		}
+	}
+
+	if column.Nullable {
+		if types, ok := typ.([]interface{}); ok {
+			typ = append(types, "null")
+		} else {
+			typ = []interface{}{typ, "null"}
+		}
+	}
+	schema["type"] = typ
+
+	if column.Label != "" {
+		schema["title"] = column.Label
+	}
+
+	if column.Comment != "" {
+		schema["description"] = column.Comment
+	}
+
+	if column.Length > 0 && typ == "string" {
+		schema["maxLength"] = column.Length
+	}
+
+	if column.Default != nil {
+		schema["default"] = column.Default
+	}
+
+	return schema
+}
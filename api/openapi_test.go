@@ -0,0 +1,109 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestOpenAPI(t *testing.T) {
+	defer openAPITestAPIs()()
+
+	doc := OpenAPI("/api")
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	paths := doc["paths"].(map[string]interface{})
+	assert.Contains(t, paths, "/api/pets/{id}")
+	assert.Contains(t, paths, "/api/pets")
+
+	find := paths["/api/pets/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "Find a pet", find["summary"])
+	assert.Equal(t, "bearer-jwt", find["x-guard"])
+	assert.Equal(t, "unit.openapi.find", find["x-process"])
+
+	parameters := find["parameters"].([]interface{})
+	names := []string{}
+	for _, parameter := range parameters {
+		p := parameter.(map[string]interface{})
+		names = append(names, p["in"].(string)+"."+p["name"].(string))
+	}
+	assert.ElementsMatch(t, []string{"path.id", "query.select", "header.Authorization"}, names)
+
+	create := paths["/api/pets"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.NotContains(t, create, "x-guard")
+	body := create["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	assert.Equal(t, []string{"name"}, body["required"])
+	assert.Contains(t, create["responses"], "201")
+}
+
+func TestOpenAPIRoute(t *testing.T) {
+	defer openAPITestAPIs()()
+	process.Register("unit.openapi.find", func(process *process.Process) interface{} { return nil })
+	process.Register("unit.openapi.create", func(process *process.Process) interface{} { return nil })
+
+	gin.SetMode(gin.ReleaseMode)
+
+	// disabled by default
+	router := gin.New()
+	SetRoutes(router, "/api")
+	response := testRequest(router, "GET", "/api/openapi.json", nil, nil)
+	assert.Equal(t, 404, response.Code)
+
+	route, guard := OpenAPIRoute, OpenAPIGuard
+	defer func() { OpenAPIRoute, OpenAPIGuard = route, guard }()
+	OpenAPIRoute = "/openapi.json"
+
+	// the guard is required
+	router = gin.New()
+	SetRoutes(router, "/api")
+	response = testRequest(router, "GET", "/api/openapi.json", nil, nil)
+	assert.NotEqual(t, 200, response.Code)
+
+	OpenAPIGuard = "-"
+	router = gin.New()
+	SetRoutes(router, "/api")
+	response = testRequest(router, "GET", "/api/openapi.json", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "3.1.0", responseMap(response)["openapi"])
+
+	res := process.New("api.openapi", "/api").Run()
+	assert.Contains(t, res.(map[string]interface{})["paths"], "/api/pets/{id}")
+}
+
+func openAPITestAPIs() func() {
+	apis := APIs
+	APIs = map[string]*API{
+		"pets": {
+			ID:   "pets",
+			Type: "http",
+			HTTP: HTTP{
+				Name:  "pets",
+				Group: "pets",
+				Guard: "bearer-jwt",
+				Paths: []Path{
+					{
+						Label:   "Find a pet",
+						Path:    "/:id",
+						Method:  "GET",
+						Process: "unit.openapi.find",
+						In:      []interface{}{"$param.id", "$query.select", "$header.Authorization"},
+						Out:     Out{Status: 200, Type: "application/json"},
+					},
+					{
+						Label:   "Create a pet",
+						Path:    "",
+						Method:  "POST",
+						Guard:   "-",
+						Process: "unit.openapi.create",
+						In:      []interface{}{"$payload.name", "$payload.type"},
+						Out:     Out{Status: 201, Type: "application/json"},
+						Schema:  &Schema{Body: []Field{{Name: "name", Type: "string", Required: true}}},
+					},
+				},
+			},
+		},
+	}
+	return func() { APIs = apis }
+}
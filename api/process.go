@@ -4,7 +4,9 @@ import "github.com/yaoapp/gou/process"
 
 func init() {
 	process.RegisterGroup("api", map[string]process.Handler{
-		"list": processList,
+		"list":       processList,
+		"openapi":    processOpenAPI,
+		"invalidate": processInvalidate,
 	})
 }
 
@@ -32,3 +34,21 @@ func processList(process *process.Process) interface{} {
 	}
 	return apis
 }
+
+// processOpenAPI api.OpenAPI generate the OpenAPI document of the loaded apis
+func processOpenAPI(process *process.Process) interface{} {
+	root := "/"
+	if process.NumOfArgs() > 0 {
+		root = process.ArgsString(0)
+	}
+	return OpenAPI(root)
+}
+
+// processInvalidate api.Invalidate remove the cached responses by tags
+func processInvalidate(process *process.Process) interface{} {
+	tags := []string{}
+	for i := range process.Args {
+		tags = append(tags, process.ArgsString(i))
+	}
+	return InvalidateCache(tags...)
+}
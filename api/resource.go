@@ -0,0 +1,473 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+// resourceOp the operation of the generated resource path
+type resourceOp struct {
+	Resource
+	op string
+}
+
+// ResourceMaxPageSize the max page size of the resource lists, the default page size of the resource is allowed if it is larger
+var ResourceMaxPageSize = 100
+
+// the default operations of the resource, the bulk operations must be allowed explicitly
+var resourceOps = []string{"list", "find", "create", "update", "patch", "delete"}
+
+// resourceError the resource error with the status code
+type resourceError struct {
+	Code    int
+	Message string
+}
+
+func (err resourceError) Error() string {
+	return err.Message
+}
+
+// resourcePaths generate the paths of the allowed operations of the resources
+func (http HTTP) resourcePaths() []Path {
+
+	paths := []Path{}
+	for _, res := range http.Resources {
+
+		prefix := res.Prefix
+		if prefix == "" {
+			prefix = "/" + strings.ReplaceAll(strings.ToLower(res.Model), ".", "/")
+		}
+		prefix = strings.Trim(prefix, "/")
+		if prefix != "" {
+			prefix = "/" + prefix
+		}
+		item := prefix + "/:id"
+		bulk := prefix + "/bulk"
+
+		ops := res.Ops
+		if len(ops) == 0 {
+			ops = resourceOps
+		}
+
+		for _, op := range ops {
+			switch strings.ToLower(op) {
+			case "list":
+				paths = append(paths, res.path("list", "GET", prefix, "Paginate", 200, ":query-param", "$query.page", "$query.pagesize"))
+			case "find":
+				paths = append(paths, res.path("find", "GET", item, "Find", 200, "$param.id", ":query-param"))
+			case "create":
+				paths = append(paths, res.path("create", "POST", prefix, "Create", 201, ":payload"))
+			case "update":
+				paths = append(paths, res.path("update", "PUT", item, "Update", 200, "$param.id", ":payload"))
+			case "patch":
+				paths = append(paths, res.path("patch", "PATCH", item, "Update", 200, "$param.id", ":payload"))
+			case "delete":
+				paths = append(paths, res.path("delete", "DELETE", item, "Delete", 204, "$param.id"))
+			case "bulk":
+				paths = append(paths,
+					res.path("bulk.save", "POST", bulk, "EachSave", 200, ":payload"),
+					res.path("bulk.update", "PATCH", bulk, "UpdateWhere", 200, ":query-param", ":payload"),
+					res.path("bulk.delete", "DELETE", bulk, "DeleteWhere", 200, ":query-param"),
+				)
+			}
+		}
+	}
+	return paths
+}
+
+func (res Resource) path(op string, method string, route string, name string, status int, in ...interface{}) Path {
+	return Path{
+		Label:    fmt.Sprintf("%s %s", op, res.Model),
+		Path:     route,
+		Method:   method,
+		Process:  fmt.Sprintf("models.%s.%s", res.Model, name),
+		Guard:    res.Guard,
+		In:       in,
+		Out:      Out{Status: status, Type: "application/json"},
+		resource: &resourceOp{Resource: res, op: op},
+	}
+}
+
+// resourceHandler the handler of the generated resource path
+func (path Path) resourceHandler() gin.HandlerFunc {
+	res := path.resource
+	return func(c *gin.Context) {
+
+		mod, has := model.Models[res.Model]
+		if !has {
+			path.renderError(c, 404, fmt.Sprintf("model %s not found", res.Model), nil)
+			return
+		}
+
+		columns := res.columns(mod)
+		value, err := res.run(c, mod, columns)
+		if err != nil {
+			if e, ok := err.(resourceError); ok {
+				path.renderError(c, e.Code, e.Message, nil)
+				return
+			}
+			ex := exception.Err(err, 500)
+			path.renderError(c, ex.Code, ex.Message, nil)
+			return
+		}
+
+		if path.Out.Status == 204 {
+			c.Status(204)
+			return
+		}
+		path.render(c, path.Out.Status, value)
+	}
+}
+
+// run the operation
+func (res *resourceOp) run(c *gin.Context, mod *model.Model, columns []string) (interface{}, error) {
+
+	exposed := map[string]bool{}
+	for _, name := range columns {
+		exposed[name] = true
+	}
+
+	switch res.op {
+	case "list":
+		param, err := res.queryParam(c, columns, exposed)
+		if err != nil {
+			return nil, err
+		}
+
+		pagesize := res.PageSize
+		if pagesize <= 0 {
+			pagesize = 20
+		}
+
+		value, err := res.call(c, "Paginate", param, queryInt(c, "page", 1), resourcePageSize(c, pagesize))
+		if err != nil {
+			return nil, err
+		}
+
+		result, _ := helper.Normalize(value).(map[string]interface{})
+		if rows, ok := result["data"].([]interface{}); ok {
+			for i, row := range rows {
+				rows[i] = resourceProject(row, exposed)
+			}
+		}
+		return result, nil
+
+	case "find":
+		param, err := res.queryParam(c, columns, exposed)
+		if err != nil {
+			return nil, err
+		}
+		return res.find(c, c.Param("id"), param, exposed)
+
+	case "create", "update", "patch":
+		payload, err := resourcePayload(c)
+		if err != nil {
+			return nil, err
+		}
+
+		row, ok := payload.(map[string]interface{})
+		if !ok {
+			return nil, resourceError{Code: 400, Message: "the payload must be an object"}
+		}
+		row = resourcePick(row, exposed, mod.PrimaryKey)
+
+		id := interface{}(c.Param("id"))
+		switch res.op {
+		case "create":
+			id, err = res.call(c, "Create", row)
+
+		case "update":
+			err = res.replace(mod, row, columns)
+			if err == nil {
+				_, err = res.call(c, "Update", id, row)
+			}
+
+		case "patch":
+			_, err = res.call(c, "Update", id, row)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return res.find(c, id, model.QueryParam{Select: resourceSelect(columns, mod.PrimaryKey)}, exposed)
+
+	case "delete":
+		return res.call(c, "Delete", c.Param("id"))
+
+	case "bulk.save":
+		payload, err := resourcePayload(c)
+		if err != nil {
+			return nil, err
+		}
+
+		list, ok := payload.([]interface{})
+		if !ok {
+			return nil, resourceError{Code: 400, Message: "the payload must be an array of objects"}
+		}
+
+		rows := []interface{}{}
+		for _, item := range list {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, resourceError{Code: 400, Message: "the payload must be an array of objects"}
+			}
+			rows = append(rows, resourcePick(row, exposed, ""))
+		}
+		return res.call(c, "EachSave", rows)
+
+	case "bulk.update", "bulk.delete":
+		param, err := res.queryParam(c, columns, exposed)
+		if err != nil {
+			return nil, err
+		}
+
+		// prevent the whole table from being updated or deleted
+		if len(param.Wheres) == 0 {
+			return nil, resourceError{Code: 400, Message: "the bulk operation requires at least one where condition"}
+		}
+		param.Select = nil
+
+		if res.op == "bulk.delete" {
+			affected, err := res.call(c, "DeleteWhere", param)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"affected": affected}, nil
+		}
+
+		payload, err := resourcePayload(c)
+		if err != nil {
+			return nil, err
+		}
+
+		row, ok := payload.(map[string]interface{})
+		if !ok {
+			return nil, resourceError{Code: 400, Message: "the payload must be an object"}
+		}
+
+		affected, err := res.call(c, "UpdateWhere", param, resourcePick(row, exposed, mod.PrimaryKey))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"affected": affected}, nil
+	}
+
+	return nil, resourceError{Code: 405, Message: fmt.Sprintf("the operation %s is not supported", res.op)}
+}
+
+func (res *resourceOp) find(c *gin.Context, id interface{}, param model.QueryParam, exposed map[string]bool) (interface{}, error) {
+	value, err := res.call(c, "Find", id, param)
+	if err != nil {
+		return nil, err
+	}
+	return resourceProject(helper.Normalize(value), exposed), nil
+}
+
+// call the model process with the session and the global data of the request
+func (res *resourceOp) call(c *gin.Context, method string, args ...interface{}) (interface{}, error) {
+	p, err := process.Of(fmt.Sprintf("models.%s.%s", res.Model, method), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Dispose()
+
+	if sid, ok := c.Get("__sid"); ok {
+		if sid, ok := sid.(string); ok {
+			p.WithSID(sid)
+		}
+	}
+
+	if global, ok := c.Get("__global"); ok {
+		if global, ok := global.(map[string]interface{}); ok {
+			p.WithGlobal(global)
+		}
+	}
+
+	p.WithContext(c.Request.Context())
+	return p.Exec()
+}
+
+// replace set the missing columns to the defaults, the payload of PUT replaces the whole record
+func (res *resourceOp) replace(mod *model.Model, row map[string]interface{}, columns []string) error {
+	for _, name := range columns {
+		if _, has := row[name]; has || name == mod.PrimaryKey {
+			continue
+		}
+
+		column, has := mod.Columns[name]
+		if !has {
+			continue
+		}
+
+		if column.Default == nil && !column.Nullable {
+			return resourceError{Code: 400, Message: fmt.Sprintf("the column %s is required", name)}
+		}
+		row[name] = column.Default
+	}
+	return nil
+}
+
+// queryParam the query param of the url query, restricted to the exposed columns
+func (res *resourceOp) queryParam(c *gin.Context, columns []string, exposed map[string]bool) (model.QueryParam, error) {
+
+	param := model.URLToQueryParam(c.Request.URL.Query())
+	param.Withs = nil
+
+	selects := []interface{}{}
+	for _, column := range param.Select {
+		if name, ok := column.(string); ok && exposed[name] {
+			selects = append(selects, name)
+		}
+	}
+	if len(selects) == 0 {
+		selects = resourceSelect(columns, "")
+	}
+	param.Select = selects
+
+	if err := resourceCheckWheres(param.Wheres, exposed); err != nil {
+		return param, err
+	}
+
+	for _, order := range param.Orders {
+		if !exposed[order.Column] {
+			return param, resourceError{Code: 400, Message: fmt.Sprintf("the column %s can not be ordered", order.Column)}
+		}
+	}
+
+	return param, nil
+}
+
+// columns the exposed columns of the model, the encrypted columns (e.g. the passwords) are not exposed by default
+func (res *resourceOp) columns(mod *model.Model) []string {
+	columns := []string{}
+	if len(res.Columns) == 0 {
+		for _, column := range mod.MetaData.Columns {
+			if column.Crypt != "" {
+				continue
+			}
+			columns = append(columns, column.Name)
+		}
+		return columns
+	}
+
+	for _, name := range res.Columns {
+		if _, has := mod.Columns[name]; has {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+func resourceCheckWheres(wheres []model.QueryWhere, exposed map[string]bool) error {
+	for _, where := range wheres {
+		if len(where.Wheres) > 0 {
+			if err := resourceCheckWheres(where.Wheres, exposed); err != nil {
+				return err
+			}
+			continue
+		}
+
+		column, _ := where.Column.(string)
+		if where.Rel != "" || !exposed[column] {
+			return resourceError{Code: 400, Message: fmt.Sprintf("the column %v can not be filtered", where.Column)}
+		}
+	}
+	return nil
+}
+
+func resourceSelect(columns []string, primary string) []interface{} {
+	selects := []interface{}{}
+	hasPrimary := primary == ""
+	for _, name := range columns {
+		selects = append(selects, name)
+		if name == primary {
+			hasPrimary = true
+		}
+	}
+
+	if !hasPrimary {
+		selects = append(selects, primary)
+	}
+	return selects
+}
+
+// resourcePick pick the exposed columns of the row, the primary key is omitted if given
+func resourcePick(row map[string]interface{}, exposed map[string]bool, primary string) map[string]interface{} {
+	res := map[string]interface{}{}
+	for name, value := range row {
+		if exposed[name] && name != primary {
+			res[name] = value
+		}
+	}
+	return res
+}
+
+// resourceProject remove the unexposed fields of the record
+func resourceProject(value interface{}, exposed map[string]bool) interface{} {
+	row, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	for name := range row {
+		if !exposed[name] {
+			delete(row, name)
+		}
+	}
+	return row
+}
+
+// resourcePayload read the json payload, the object or the array
+func resourcePayload(c *gin.Context) (interface{}, error) {
+
+	// the payloads has been parsed by the validator
+	if payloads, has := c.Get("__payloads"); has {
+		return payloads, nil
+	}
+
+	if c.Request.Body == nil {
+		return nil, resourceError{Code: 400, Message: "the payload is required"}
+	}
+
+	bytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload interface{}
+	err = jsoniter.Unmarshal(bytes, &payload)
+	if err != nil {
+		return nil, resourceError{Code: 400, Message: fmt.Sprintf("the payload is not a valid json. %s", err.Error())}
+	}
+	return payload, nil
+}
+
+func queryInt(c *gin.Context, name string, defaults int) int {
+	value, err := strconv.Atoi(c.Query(name))
+	if err != nil || value <= 0 {
+		return defaults
+	}
+	return value
+}
+
+// resourcePageSize the page size of the url query, it is clamped to the ResourceMaxPageSize (or the default if larger)
+func resourcePageSize(c *gin.Context, defaults int) int {
+	max := ResourceMaxPageSize
+	if defaults > max {
+		max = defaults
+	}
+
+	pagesize := queryInt(c, "pagesize", defaults)
+	if pagesize > max {
+		return max
+	}
+	return pagesize
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+)
+
+func TestResourcePaths(t *testing.T) {
+	api, err := LoadSource("<source>.http.yao", []byte(`{
+		"name": "resource", "guard": "-",
+		"resources": [
+			{ "model": "unit.resource", "ops": ["list", "find", "patch", "bulk"], "columns": ["id", "name"] },
+			{ "model": "unit.other", "prefix": "/others/" }
+		]
+	}`), "unit.resource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(APIs, "unit.resource")
+
+	routes := []string{}
+	for _, path := range api.HTTP.Paths {
+		routes = append(routes, path.Method+" "+path.Path+" "+path.Process)
+	}
+
+	assert.Equal(t, []string{
+		"GET /unit/resource models.unit.resource.Paginate",
+		"GET /unit/resource/:id models.unit.resource.Find",
+		"PATCH /unit/resource/:id models.unit.resource.Update",
+		"POST /unit/resource/bulk models.unit.resource.EachSave",
+		"PATCH /unit/resource/bulk models.unit.resource.UpdateWhere",
+		"DELETE /unit/resource/bulk models.unit.resource.DeleteWhere",
+		"GET /others models.unit.other.Paginate",
+		"GET /others/:id models.unit.other.Find",
+		"POST /others models.unit.other.Create",
+		"PUT /others/:id models.unit.other.Update",
+		"PATCH /others/:id models.unit.other.Update",
+		"DELETE /others/:id models.unit.other.Delete",
+	}, routes)
+}
+
+func TestResourceRestrict(t *testing.T) {
+	router := resourceRouter(t)
+	defer delete(model.Models, "unit.resource")
+
+	for _, url := range []string{
+		"/unit/resource?where.secret.eq=1",
+		"/unit/resource?group.g.where.secret.eq=1",
+		"/unit/resource?where.owner.name.eq=1",
+		"/unit/resource?order=secret.desc",
+	} {
+		response := testRequest(router, "GET", url, nil, nil)
+		assert.Equal(t, 400, response.Code, url)
+	}
+
+	response := testRequest(router, "DELETE", "/unit/resource/bulk", nil, nil)
+	assert.Equal(t, 400, response.Code)
+	assert.Contains(t, response.Body.String(), "where condition")
+
+	// the model is not loaded
+	delete(model.Models, "unit.resource")
+	response = testRequest(router, "GET", "/unit/resource/1", nil, nil)
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestResourceQueryParam(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	res := &resourceOp{Resource: Resource{Model: "unit.resource", Columns: []string{"id", "name"}}, op: "list"}
+	exposed := map[string]bool{"id": true, "name": true}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/unit/resource?select=name,secret&with=owner&where.name.eq=foo&order=id.desc", nil)
+	param, err := res.queryParam(c, []string{"id", "name"}, exposed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []interface{}{"name"}, param.Select)
+	assert.Nil(t, param.Withs)
+	assert.Equal(t, "name", param.Wheres[0].Column)
+	assert.Equal(t, "desc", param.Orders[0].Option)
+
+	row := resourcePick(map[string]interface{}{"id": 1, "name": "foo", "secret": "bar"}, exposed, "id")
+	assert.Equal(t, map[string]interface{}{"name": "foo"}, row)
+}
+
+func TestResourceDefaults(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", fmt.Sprintf("/unit/resource?pagesize=%d", ResourceMaxPageSize+1), nil)
+	assert.Equal(t, ResourceMaxPageSize, resourcePageSize(c, 20))
+	assert.Equal(t, ResourceMaxPageSize+1, resourcePageSize(c, ResourceMaxPageSize+5))
+
+	c.Request, _ = http.NewRequest("GET", "/unit/resource", nil)
+	assert.Equal(t, 20, resourcePageSize(c, 20))
+
+	res := &resourceOp{Resource: Resource{Model: "unit.resource"}, op: "list"}
+	mod := &model.Model{MetaData: model.MetaData{Columns: []model.Column{
+		{Name: "id", Type: "ID", Primary: true},
+		{Name: "password", Type: "string", Crypt: "PASSWORD"},
+	}}}
+	assert.Equal(t, []string{"id"}, res.columns(mod))
+}
+
+func resourceRouter(t *testing.T) *gin.Engine {
+	model.Models["unit.resource"] = &model.Model{
+		ID:         "unit.resource",
+		PrimaryKey: "id",
+		MetaData: model.MetaData{Columns: []model.Column{
+			{Name: "id", Type: "ID", Primary: true},
+			{Name: "name", Type: "string"},
+			{Name: "secret", Type: "string"},
+		}},
+		Columns: map[string]*model.Column{
+			"id":     {Name: "id", Type: "ID", Primary: true},
+			"name":   {Name: "name", Type: "string"},
+			"secret": {Name: "secret", Type: "string"},
+		},
+	}
+
+	api, err := LoadSource("<source>.http.yao", []byte(`{
+		"name": "resource", "guard": "-",
+		"resources": [{ "model": "unit.resource", "prefix": "/", "ops": ["list", "find", "bulk"], "columns": ["id", "name"] }]
+	}`), "unit.resource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer delete(APIs, "unit.resource")
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	api.HTTP.Routes(router, "/")
+	return router
+}
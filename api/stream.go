@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/observe"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+)
+
+// StreamMaxBuffer the max number of the buffered events of a stream, the oldest events are dropped even if they
+// are not delivered, e.g. the client is disconnected and the process keeps emitting
+var StreamMaxBuffer = 1024
+
+// sseStreams the resumable streams
+var sseStreams = map[string]*sseStream{}
+var sseStreamsMutex sync.Mutex
+
+// sseStream the events of a running stream, the events are kept in a buffer so the stream can be resumed
+type sseStream struct {
+	id        string
+	sid       string
+	size      int
+	ttl       time.Duration
+	events    []ssEventData
+	seq       uint64
+	delivered uint64
+	done      bool
+	clients   int
+	notify    chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+	timer     *time.Timer
+	mutex     sync.Mutex
+}
+
+// streamHandler the server-sent events handler, the process emits events through the process.Emitter (or ssEvent in scripts)
+func (path Path) streamHandler(getArgs func(c *gin.Context) []interface{}) func(c *gin.Context) {
+
+	option := Stream{}
+	if path.Stream != nil {
+		option = *path.Stream
+	}
+
+	heartbeat := 15 * time.Second
+	if option.Heartbeat > 0 {
+		heartbeat = time.Duration(option.Heartbeat) * time.Second
+	} else if option.Heartbeat < 0 {
+		heartbeat = 0
+	}
+
+	ttl := 60 * time.Second
+	if option.TTL > 0 {
+		ttl = time.Duration(option.TTL) * time.Second
+	}
+
+	resumable := option.Replay > 0
+	return func(c *gin.Context) {
+
+		path.setPayload(c)
+		path.reqContentType(c)
+		sid, global := streamSession(c)
+
+		var stream *sseStream
+		var cursor uint64
+		if resumable {
+			stream, cursor = resumeStream(c.GetHeader("Last-Event-ID"), sid)
+		}
+
+		if stream == nil {
+			stream = newStream(sid, option.Replay, ttl)
+			stream.ctx = observe.Propagate(stream.ctx, c.Request.Context())
+			go path.produce(stream, sid, global, getArgs(c))
+		}
+
+		stream.attach()
+		defer stream.detach()
+
+		var tick <-chan time.Time
+		if heartbeat > 0 {
+			ticker := time.NewTicker(heartbeat)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		c.Stream(func(w io.Writer) bool {
+			events, done, notify := stream.since(cursor)
+			if len(events) > 0 {
+				for _, event := range events {
+					log.Trace("[Stream] %s %s %s %v", path.Path, path.Process, event.Name, event.Message)
+					if resumable {
+						c.Render(-1, sse.Event{Id: fmt.Sprintf("%s:%d", stream.id, event.ID), Event: event.Name, Data: event.Message})
+					} else {
+						c.SSEvent(event.Name, event.Message)
+					}
+					cursor = event.ID
+				}
+				stream.ack(cursor)
+				return true
+			}
+
+			if done {
+				return false
+			}
+
+			select {
+			case <-notify:
+				return true
+
+			case <-tick:
+				io.WriteString(w, ": heartbeat\n\n")
+				return true
+
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
+
+// produce run the process and send the events to the stream
+func (path Path) produce(stream *sseStream, sid string, global map[string]interface{}, args []interface{}) {
+
+	ctx := stream.ctx
+	defer func() {
+		stream.cancel()
+		stream.finish()
+	}()
+
+	if strings.HasPrefix(path.Process, "scripts") {
+		path.runStreamScript(ctx, sid, global, args,
+			// event
+			func(name string, message interface{}) { stream.emit(name, message) },
+
+			// cancel
+			func() { stream.cancel() },
+
+			// error
+			func(err error) { log.Error("[Stream] %s Error: %v", path.Path, err) },
+		)
+		return
+	}
+
+	p, err := process.Of(path.Process, args...)
+	if err != nil {
+		log.Error("[Stream] %s Error: %v", path.Path, err)
+		return
+	}
+	defer p.Dispose()
+
+	res, err := p.WithSID(sid).
+		WithGlobal(global).
+		WithContext(ctx).
+		WithEmitter(process.EmitterFunc(stream.emit)).
+		Exec()
+
+	if err != nil {
+		log.Error("[Stream] %s Error: %v", path.Path, err)
+		return
+	}
+
+	// the returned value is the last message
+	if res != nil {
+		stream.emit("message", res)
+	}
+}
+
+// streamSession get the session id and the global vars of the request
+func streamSession(c *gin.Context) (string, map[string]interface{}) {
+	sid := ""
+	global := map[string]interface{}{}
+	if v, has := c.Get("__sid"); has {
+		if v, ok := v.(string); ok {
+			sid = v
+		}
+	}
+	if v, has := c.Get("__global"); has {
+		if v, ok := v.(map[string]interface{}); ok {
+			global = v
+		}
+	}
+	return sid, global
+}
+
+// newStream create a stream, the stream is registered for resuming if the replay buffer is given
+func newStream(sid string, size int, ttl time.Duration) *sseStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &sseStream{
+		id:     uuid.NewString(),
+		sid:    sid,
+		size:   size,
+		ttl:    ttl,
+		events: []ssEventData{},
+		notify: make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	if size > 0 {
+		sseStreamsMutex.Lock()
+		sseStreams[stream.id] = stream
+		sseStreamsMutex.Unlock()
+	}
+	return stream
+}
+
+// resumeStream find the stream by the Last-Event-ID (<stream id>:<sequence>)
+func resumeStream(lastEventID string, sid string) (*sseStream, uint64) {
+	pos := strings.LastIndex(lastEventID, ":")
+	if pos < 0 {
+		return nil, 0
+	}
+
+	seq, err := strconv.ParseUint(lastEventID[pos+1:], 10, 64)
+	if err != nil {
+		return nil, 0
+	}
+
+	sseStreamsMutex.Lock()
+	stream, has := sseStreams[lastEventID[:pos]]
+	sseStreamsMutex.Unlock()
+	if !has || stream.sid != sid {
+		return nil, 0
+	}
+	return stream, seq
+}
+
+// emit append an event to the stream
+func (stream *sseStream) emit(name string, message interface{}) error {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.done {
+		return fmt.Errorf("the stream is closed")
+	}
+
+	stream.seq++
+	stream.events = append(stream.events, ssEventData{ID: stream.seq, Name: name, Message: message})
+	stream.trim()
+	stream.broadcast()
+	return nil
+}
+
+// since the events after the cursor, the notify channel is closed when a new event comes
+func (stream *sseStream) since(cursor uint64) ([]ssEventData, bool, chan struct{}) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	events := []ssEventData{}
+	for _, event := range stream.events {
+		if event.ID > cursor {
+			events = append(events, event)
+		}
+	}
+	return events, stream.done, stream.notify
+}
+
+// ack mark the events have been delivered
+func (stream *sseStream) ack(cursor uint64) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if cursor > stream.delivered {
+		stream.delivered = cursor
+	}
+	stream.trim()
+}
+
+// trim drop the delivered events out of the replay buffer, and the oldest ones if the buffer is full
+func (stream *sseStream) trim() {
+	limit := StreamMaxBuffer
+	if stream.size > limit {
+		limit = stream.size
+	}
+
+	for len(stream.events) > stream.size && (stream.events[0].ID <= stream.delivered || len(stream.events) > limit) {
+		stream.events = stream.events[1:]
+	}
+}
+
+// broadcast wake up the clients
+func (stream *sseStream) broadcast() {
+	close(stream.notify)
+	stream.notify = make(chan struct{})
+}
+
+// finish mark the stream as done, the replay buffer is kept for the ttl
+func (stream *sseStream) finish() {
+	stream.mutex.Lock()
+	stream.done = true
+	stream.broadcast()
+	stream.mutex.Unlock()
+
+	if stream.size > 0 {
+		time.AfterFunc(stream.ttl, stream.remove)
+	}
+}
+
+// attach a client to the stream
+func (stream *sseStream) attach() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.clients++
+	if stream.timer != nil {
+		stream.timer.Stop()
+		stream.timer = nil
+	}
+}
+
+// detach a client from the stream, the process is canceled if no client resumes in the ttl
+func (stream *sseStream) detach() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.clients--
+	if stream.clients > 0 || stream.done {
+		return
+	}
+
+	if stream.size == 0 {
+		stream.cancel()
+		return
+	}
+
+	stream.timer = time.AfterFunc(stream.ttl, func() {
+		stream.mutex.Lock()
+		clients := stream.clients
+		stream.mutex.Unlock()
+		if clients == 0 {
+			stream.cancel()
+			stream.remove()
+		}
+	})
+}
+
+// remove the stream from the resumable streams
+func (stream *sseStream) remove() {
+	sseStreamsMutex.Lock()
+	defer sseStreamsMutex.Unlock()
+	delete(sseStreams, stream.id)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestStreamProcess(t *testing.T) {
+	router := streamRouter(nil)
+
+	body := testRequest(router, "GET", "/stream", nil, nil).Body.String()
+	assert.Equal(t, "event:message\ndata:foo0\n\nevent:message\ndata:foo1\n\nevent:message\ndata:foo2\n\nevent:message\ndata:done\n\n", body)
+}
+
+func TestStreamResume(t *testing.T) {
+	router := streamRouter(&Stream{Replay: 10, TTL: 5})
+
+	body := testRequest(router, "GET", "/stream", nil, nil).Body.String()
+	ids := regexp.MustCompile(`id:([^\n]+)\n`).FindAllStringSubmatch(body, -1)
+	assert.Len(t, ids, 4)
+
+	// resume after the second event
+	body = testRequest(router, "GET", "/stream", nil, map[string]string{"Last-Event-ID": ids[1][1]}).Body.String()
+	assert.Len(t, regexp.MustCompile(`event:message`).FindAllString(body, -1), 2)
+	assert.Contains(t, body, "data:foo2")
+	assert.Contains(t, body, "data:done")
+	assert.NotContains(t, body, "data:foo1")
+
+	// the unknown stream starts a new one
+	body = testRequest(router, "GET", "/stream", nil, map[string]string{"Last-Event-ID": "unknown:1"}).Body.String()
+	assert.Contains(t, body, "data:foo0")
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	router := streamRouter(&Stream{Heartbeat: 1})
+
+	body := testRequest(router, "GET", "/stream/slow", nil, nil).Body.String()
+	assert.Contains(t, body, ": heartbeat\n\n")
+	assert.Contains(t, body, "data:done")
+}
+
+func TestStreamMaxBuffer(t *testing.T) {
+	max := StreamMaxBuffer
+	defer func() { StreamMaxBuffer = max }()
+	StreamMaxBuffer = 5
+
+	// the events are not delivered, the client is disconnected
+	stream := newStream("", 2, time.Second)
+	defer stream.remove()
+	for i := 0; i < 20; i++ {
+		stream.emit("message", i)
+	}
+
+	events, _, _ := stream.since(0)
+	assert.Len(t, events, 5)
+	assert.Equal(t, uint64(16), events[0].ID)
+
+	// the delivered events are dropped down to the replay size
+	stream.ack(20)
+	events, _, _ = stream.since(0)
+	assert.Len(t, events, 2)
+}
+
+func streamRouter(option *Stream) *gin.Engine {
+	process.Register("unit.api.stream", func(process *process.Process) interface{} {
+		for i := 0; i < 3; i++ {
+			process.Emit("message", fmt.Sprintf("foo%d", i))
+		}
+		return "done"
+	})
+
+	process.Register("unit.api.stream.slow", func(process *process.Process) interface{} {
+		time.Sleep(1500 * time.Millisecond)
+		return "done"
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	http := HTTP{
+		Name:  "stream",
+		Guard: "-",
+		Paths: []Path{
+			{Path: "/stream", Method: "GET", Process: "unit.api.stream", Out: Out{Type: "text/event-stream; charset=utf-8"}, Stream: option},
+			{Path: "/stream/slow", Method: "GET", Process: "unit.api.stream.slow", Out: Out{Type: "text/event-stream; charset=utf-8"}, Stream: option},
+		},
+	}
+	http.Routes(router, "/")
+	return router
+}
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/encoding/csv"
+	"github.com/yaoapp/gou/encoding/json"
+	"github.com/yaoapp/gou/encoding/xml"
+	"github.com/yaoapp/gou/encoding/yaml"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Encoder the response encoder of the content negotiation
+type Encoder func(v interface{}) ([]byte, error)
+
+// Encoders the registered response encoders, the key is the MIME type
+var Encoders = map[string]Encoder{
+	"application/json": json.Encode,
+	"application/xml":  xml.Encode,
+	"text/xml":         xml.Encode,
+	"application/yaml": yaml.Encode,
+	"text/yaml":        yaml.Encode,
+	"text/csv":         csv.Encode,
+}
+
+// formatAliases the short names of the formats
+var formatAliases = map[string][]string{
+	"json": {"application/json"},
+	"xml":  {"application/xml", "text/xml"},
+	"yaml": {"application/yaml", "text/yaml"},
+	"csv":  {"text/csv"},
+}
+
+// RegisterEncoder register a response encoder
+func RegisterEncoder(mime string, encoder Encoder) {
+	Encoders[mime] = encoder
+}
+
+// beforeHandler transform the payloads before calling the process
+func (path Path) beforeHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path.setPayload(c)
+		payloads, has := c.Get("__payloads")
+		if !has {
+			return
+		}
+
+		if res, ok := path.Before.apply(payloads).(map[string]interface{}); ok {
+			c.Set("__payloads", res)
+		}
+	}
+}
+
+// apply the transform to the value, the fields of a list are transformed item by item
+func (transform *Transform) apply(value interface{}) interface{} {
+
+	if transform == nil || value == nil {
+		return value
+	}
+
+	if _, ok := value.([]byte); ok {
+		return value
+	}
+
+	if _, ok := value.(error); ok {
+		return value
+	}
+
+	value = helper.Normalize(value)
+	switch data := value.(type) {
+	case map[string]interface{}:
+		value = transform.fields(data)
+
+	case []interface{}:
+		for i, item := range data {
+			if row, ok := item.(map[string]interface{}); ok {
+				data[i] = transform.fields(row)
+			}
+		}
+		value = data
+	}
+
+	if transform.Template == nil {
+		return value
+	}
+
+	if row, ok := value.(map[string]interface{}); ok {
+		data := maps.Of(row).Dot()
+		if _, has := row["data"]; !has {
+			data["data"] = row
+		}
+		return helper.Bind(transform.Template, data)
+	}
+	return helper.Bind(transform.Template, maps.Of(map[string]interface{}{"data": value}).Dot())
+}
+
+// fields pick, omit and rename the fields
+func (transform *Transform) fields(row map[string]interface{}) map[string]interface{} {
+
+	if len(transform.Pick) > 0 {
+		picked := map[string]interface{}{}
+		for _, name := range transform.Pick {
+			if value, has := getPath(row, name); has {
+				setPath(picked, name, value)
+			}
+		}
+		row = picked
+	}
+
+	for _, name := range transform.Omit {
+		deletePath(row, name)
+	}
+
+	names := []string{}
+	for name := range transform.Rename {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if value, has := getPath(row, name); has {
+			deletePath(row, name)
+			setPath(row, transform.Rename[name], value)
+		}
+	}
+
+	return row
+}
+
+// negotiate select the content type by the Accept header
+func (path Path) negotiate(c *gin.Context) string {
+	offered := []string{}
+	for _, format := range path.Out.Formats {
+		if mimes, has := formatAliases[strings.ToLower(format)]; has {
+			offered = append(offered, mimes...)
+			continue
+		}
+		offered = append(offered, format)
+	}
+	return c.NegotiateFormat(offered...)
+}
+
+// render write the body in the negotiated format, the body is written as JSON if the formats are not given
+func (path Path) render(c *gin.Context, status int, body interface{}) {
+
+	if len(path.Out.Formats) == 0 {
+		c.JSON(status, body)
+		return
+	}
+
+	mime, data, err := path.encode(c, body)
+	if mime == "" {
+		path.renderError(c, 406, fmt.Sprintf("%s is not acceptable", c.GetHeader("Accept")), nil)
+		return
+	}
+
+	if err != nil {
+		path.renderError(c, 500, err.Error(), nil)
+		return
+	}
+
+	// override the content type of the out
+	c.Writer.Header().Set("Content-Type", mime+"; charset=utf-8")
+	c.Data(status, mime+"; charset=utf-8", data)
+}
+
+// encode encode the body in the negotiated format, the mime is empty if the format is not acceptable
+func (path Path) encode(c *gin.Context, body interface{}) (string, []byte, error) {
+	vary := false
+	for _, value := range c.Writer.Header().Values("Vary") {
+		vary = vary || strings.EqualFold(value, "Accept")
+	}
+	if !vary {
+		c.Writer.Header().Add("Vary", "Accept")
+	}
+
+	mime := path.negotiate(c)
+	encoder, has := Encoders[mime]
+	if !has {
+		return "", nil, nil
+	}
+
+	data, err := encoder(helper.Normalize(body))
+	return mime, data, err
+}
+
+// renderError write the error with the error envelope, the fields are merged into the body or bound to the envelope.
+// the error is written as JSON if it can not be written in the negotiated format
+func (path Path) renderError(c *gin.Context, code int, message string, fields gin.H) {
+	var body interface{}
+	if path.Out.Error == nil {
+		envelope := gin.H{"message": message, "code": code}
+		for name, value := range fields {
+			envelope[name] = value
+		}
+		body = envelope
+
+	} else {
+		data := map[string]interface{}{}
+		for name, value := range fields {
+			data[name] = value
+		}
+		data["code"] = code
+		data["message"] = message
+		data["path"] = c.Request.URL.Path
+		data["method"] = c.Request.Method
+		body = helper.Bind(path.Out.Error, data)
+	}
+
+	if len(path.Out.Formats) > 0 {
+		if mime, data, err := path.encode(c, body); mime != "" && err == nil {
+			c.Writer.Header().Set("Content-Type", mime+"; charset=utf-8")
+			c.Data(code, mime+"; charset=utf-8", data)
+			return
+		}
+	}
+	c.JSON(code, body)
+}
+
+// abort abort the request with the error envelope of the matched path, the validators, guards, uploads and
+// the recovered panics write the errors via it. the error is written as JSON if the request does not match a path
+func abort(c *gin.Context, code int, message string, fields gin.H) {
+	defer c.Abort()
+	if value, has := c.Get("__path"); has {
+		if path, ok := value.(Path); ok {
+			path.renderError(c, code, message, fields)
+			return
+		}
+	}
+
+	body := gin.H{"code": code, "message": message}
+	for name, value := range fields {
+		body[name] = value
+	}
+	c.JSON(code, body)
+}
+
+func getPath(data map[string]interface{}, name string) (interface{}, bool) {
+	keys := strings.Split(name, ".")
+	var current interface{} = data
+	for _, key := range keys {
+		row, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = row[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func setPath(data map[string]interface{}, name string, value interface{}) {
+	keys := strings.Split(name, ".")
+	row := data
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := row[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			row[key] = next
+		}
+		row = next
+	}
+	row[keys[len(keys)-1]] = value
+}
+
+func deletePath(data map[string]interface{}, name string) {
+	keys := strings.Split(name, ".")
+	row := data
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := row[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		row = next
+	}
+	delete(row, keys[len(keys)-1])
+}
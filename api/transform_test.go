@@ -0,0 +1,122 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+func TestTransformApply(t *testing.T) {
+	transform := &Transform{
+		Pick:   []string{"id", "name", "profile.email"},
+		Rename: map[string]string{"profile.email": "email"},
+	}
+
+	res := transform.apply(map[string]interface{}{"id": 1, "name": "foo", "secret": "bar", "profile": map[string]interface{}{"email": "foo@example.com", "phone": "123"}})
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "foo", "email": "foo@example.com", "profile": map[string]interface{}{}}, res)
+
+	// list
+	transform = &Transform{Omit: []string{"secret"}, Template: map[string]interface{}{"items": "{{ data }}"}}
+	res = transform.apply([]interface{}{map[string]interface{}{"id": 1, "secret": "bar"}})
+	assert.Equal(t, map[string]interface{}{"items": []interface{}{map[string]interface{}{"id": float64(1)}}}, res)
+}
+
+func TestTransformRoute(t *testing.T) {
+	router := transformRouter()
+
+	// before and after
+	response := testRequest(router, "POST", "/transform", `{"user_name":"foo","password":"bar"}`, nil)
+	assert.Equal(t, 200, response.Code)
+	res := responseMap(response)
+	assert.Equal(t, map[string]interface{}{"name": "foo"}, res["data"])
+
+	// negotiation
+	response = testRequest(router, "POST", "/transform", `{"user_name":"foo"}`, map[string]string{"Accept": "application/xml"})
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Header().Get("Content-Type"), "application/xml")
+	assert.Contains(t, response.Body.String(), "<name>foo</name>")
+
+	response = testRequest(router, "POST", "/transform", `{"user_name":"foo"}`, map[string]string{"Accept": "text/csv"})
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/csv")
+
+	// the not acceptable error is written as JSON with the error envelope
+	response = testRequest(router, "POST", "/transform", `{"user_name":"foo"}`, map[string]string{"Accept": "image/png"})
+	assert.Equal(t, 406, response.Code)
+	assert.Equal(t, "image/png is not acceptable", responseMap(response)["message"])
+	assert.Equal(t, "Accept", response.Header().Get("Vary"))
+
+	// error envelope
+	response = testRequest(router, "GET", "/transform/error", nil, nil)
+	assert.Equal(t, 403, response.Code)
+	res = responseMap(response)
+	assert.Equal(t, map[string]interface{}{"code": float64(403), "message": "forbidden", "path": "/transform/error"}, res["error"])
+
+	// the validator and guard errors are written with the error envelope
+	response = testRequest(router, "GET", "/transform/guarded?page=x", nil, nil)
+	assert.Equal(t, 400, response.Code)
+	res = responseMap(response)
+	assert.Equal(t, map[string]interface{}{"code": float64(400), "message": "invalid request", "path": "/transform/guarded"}, res["error"])
+	assert.Len(t, res["errors"], 1)
+
+	response = testRequest(router, "GET", "/transform/guarded?page=1", nil, nil)
+	assert.Equal(t, 401, response.Code)
+	res = responseMap(response)
+	assert.Equal(t, map[string]interface{}{"code": float64(401), "message": "denied", "path": "/transform/guarded"}, res["error"])
+}
+
+func transformRouter() *gin.Engine {
+	process.Register("unit.api.transform", func(process *process.Process) interface{} {
+		return process.Args[0]
+	})
+
+	process.Register("unit.api.transform.error", func(process *process.Process) interface{} {
+		exception.New("forbidden", 403).Throw()
+		return nil
+	})
+
+	HTTPGuards["unit-transform-deny"] = func(c *gin.Context) { unauthorized(c, "denied") }
+
+	envelope := map[string]interface{}{
+		"error":  map[string]interface{}{"code": "{{ code }}", "message": "{{ message }}", "path": "{{ path }}"},
+		"errors": "{{ errors }}",
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	http := HTTP{
+		Name:  "transform",
+		Guard: "-",
+		Paths: []Path{
+			{
+				Path:    "/transform",
+				Method:  "POST",
+				Process: "unit.api.transform",
+				In:      []interface{}{":payload"},
+				Out:     Out{Status: 200, Type: "application/json", Formats: []string{"json", "xml", "yaml", "csv"}},
+				Before:  &Transform{Omit: []string{"password"}, Rename: map[string]string{"user_name": "name"}},
+				After:   &Transform{Template: map[string]interface{}{"data": "{{ data }}"}},
+			},
+			{
+				Path:    "/transform/error",
+				Method:  "GET",
+				Process: "unit.api.transform.error",
+				Out: Out{Status: 200, Type: "application/json", Error: map[string]interface{}{
+					"error": map[string]interface{}{"code": "{{ code }}", "message": "{{ message }}", "path": "{{ path }}"},
+				}},
+			},
+			{
+				Path:    "/transform/guarded",
+				Method:  "GET",
+				Guard:   "unit-transform-deny",
+				Process: "unit.api.transform",
+				Out:     Out{Status: 200, Type: "application/json", Error: envelope},
+				Schema:  &Schema{Query: []Field{{Name: "page", Type: "integer"}}},
+			},
+		},
+	}
+	http.Routes(router, "/")
+	return router
+}
@@ -1,5 +1,7 @@
 package api
 
+import "github.com/yaoapp/gou/model"
+
 const allowHeaders = "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Yao-Gateway-Billing, Yao-Request-Uid, Yao-Builder-Uid"
 const allowMethods = "POST, GET, OPTIONS, PUT, DELETE, HEAD, PATCH"
 
@@ -14,12 +16,18 @@ type API struct {
 
 // HTTP http 协议服务
 type HTTP struct {
-	Name        string `json:"name"`
-	Version     string `json:"version"`
-	Description string `json:"description,omitempty"`
-	Group       string `json:"group,omitempty"`
-	Guard       string `json:"guard,omitempty"`
-	Paths       []Path `json:"paths,omitempty"`
+	Name        string     `json:"name"`
+	Version     string     `json:"version"`
+	Description string     `json:"description,omitempty"`
+	Group       string     `json:"group,omitempty"`
+	Guard       string     `json:"guard,omitempty"`
+	Versioning  string     `json:"versioning,omitempty"` // path, header. the version is ignored if not given
+	Default     bool       `json:"default,omitempty"`    // the default version when the Accept-Version header is not given
+	Extends     string     `json:"extends,omitempty"`    // inherit the paths of the given api
+	Deprecated  bool       `json:"deprecated,omitempty"` // deprecate all of the paths
+	Sunset      string     `json:"sunset,omitempty"`     // the sunset date of all of the paths
+	Paths       []Path     `json:"paths,omitempty"`
+	Resources   []Resource `json:"resources,omitempty"` // the REST resources of the models, the paths are generated when loading
 }
 
 // Path HTTP Path
@@ -32,7 +40,96 @@ type Path struct {
 	Guard          string        `json:"guard,omitempty"`
 	In             []interface{} `json:"in,omitempty"`
 	Out            Out           `json:"out,omitempty"`
+	Schema         *Schema       `json:"schema,omitempty"`
+	Cache          *Cache        `json:"cache,omitempty"`
+	Deprecated     bool          `json:"deprecated,omitempty"`
+	Sunset         string        `json:"sunset,omitempty"` // the sunset date, 2006-01-02 or RFC3339
+	Stream         *Stream       `json:"stream,omitempty"`
+	Upload         *Upload       `json:"upload,omitempty"`
+	Before         *Transform    `json:"before,omitempty"` // transform the payload before calling the process
+	After          *Transform    `json:"after,omitempty"`  // transform the result of the process
 	ProcessHandler bool          `json:"processHandler,omitempty"`
+	resource       *resourceOp
+}
+
+// Resource the REST resource of a model, the paths of the allowed operations are generated
+type Resource struct {
+	Model    string   `json:"model"`
+	Prefix   string   `json:"prefix,omitempty"`   // the path prefix, default is /<model id> with the dots replaced by slashes
+	Guard    string   `json:"guard,omitempty"`    // the guard of the resource paths, default is the api guard
+	Ops      []string `json:"ops,omitempty"`      // list, find, create, update, patch, delete, bulk. default is all but bulk
+	Columns  []string `json:"columns,omitempty"`  // the exposed columns, default is all of the columns except the encrypted ones
+	PageSize int      `json:"pagesize,omitempty"` // the default page size of the list, default is 20
+}
+
+// Schema the request schema of the path
+type Schema struct {
+	Params  []Field `json:"params,omitempty"`
+	Query   []Field `json:"query,omitempty"`
+	Headers []Field `json:"headers,omitempty"`
+	Body    []Field `json:"body,omitempty"`
+}
+
+// Field the request field ( model-column style )
+type Field struct {
+	Name        string             `json:"name"`
+	Type        string             `json:"type,omitempty"` // string, integer, number, boolean, array, object
+	Label       string             `json:"label,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Required    bool               `json:"required,omitempty"`
+	Default     interface{}        `json:"default,omitempty"`
+	Validations []model.Validation `json:"validations,omitempty"`
+}
+
+// Cache the response cache of the path
+type Cache struct {
+	Store string   `json:"store"`          // the name of the store
+	TTL   int      `json:"ttl,omitempty"`  // seconds, 0 means never expired
+	Vary  []string `json:"vary,omitempty"` // params, query, headers, headers.<name>, sid, global. default is params and query (and sid, global, headers.Authorization if the path is guarded)
+	Tag   string   `json:"tag,omitempty"`  // the invalidation tag, default is the api group
+}
+
+// Stream the server-sent events option of the path
+type Stream struct {
+	Heartbeat int `json:"heartbeat,omitempty"` // the heartbeat comment interval (seconds), default is 15, -1 disables the heartbeat
+	Replay    int `json:"replay,omitempty"`    // the size of the replay buffer, the events have ids and can be resumed by Last-Event-ID if greater than 0. the undelivered events are buffered up to StreamMaxBuffer
+	TTL       int `json:"ttl,omitempty"`       // keep the replay buffer (seconds) after the stream is finished or disconnected, default is 60
+}
+
+// Transform the declarative transform of the payload or the result
+type Transform struct {
+	Pick     []string          `json:"pick,omitempty"`     // keep the fields, supports dot paths like user.name
+	Omit     []string          `json:"omit,omitempty"`     // remove the fields
+	Rename   map[string]string `json:"rename,omitempty"`   // rename the fields, from -> to
+	Template interface{}       `json:"template,omitempty"` // the helper.Bind template, binds the fields and the whole value as data
+}
+
+// Upload the file upload option of the :file.<field> and :files bindings
+type Upload struct {
+	FS       string   `json:"fs,omitempty"`       // the file system the files are saved to, default is system
+	Dir      string   `json:"dir,omitempty"`      // the directory of the files, default is /<YYYYMMDD>
+	MaxSize  int64    `json:"maxSize,omitempty"`  // the max size of a file (bytes), 0 means no limit
+	MaxFiles int      `json:"maxFiles,omitempty"` // the max number of the files, 0 means no limit
+	Mimes    []string `json:"mimes,omitempty"`    // the allowed MIME types, supports wildcards like image/*
+}
+
+// UploadedFile the metadata of an uploaded file
+type UploadedFile struct {
+	Field string `json:"field"`
+	Name  string `json:"name"`
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Mime  string `json:"mime"`
+	Hash  string `json:"hash"` // sha256 hex
+	FS    string `json:"fs"`
+}
+
+// Violation the request validation error
+type Violation struct {
+	In      string      `json:"in"`
+	Field   string      `json:"field"`
+	Input   interface{} `json:"input,omitempty"`
+	Message string      `json:"message"`
 }
 
 // Out http 输出
@@ -42,6 +139,8 @@ type Out struct {
 	Body     interface{}       `json:"body,omitempty"`
 	Headers  map[string]string `json:"headers,omitempty"`
 	Redirect *Redirect         `json:"redirect,omitempty"`
+	Formats  []string          `json:"formats,omitempty"` // the negotiable formats by Accept, json, xml, yaml, csv or the MIME types of the registered encoders
+	Error    interface{}       `json:"error,omitempty"`   // the error envelope template, binds code, message, path and method
 }
 
 // Redirect out redirect
@@ -51,6 +150,7 @@ type Redirect struct {
 }
 
 type ssEventData struct {
+	ID      uint64
 	Name    string
 	Message interface{}
 }
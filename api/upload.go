@@ -0,0 +1,536 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/fs"
+	"github.com/yaoapp/kun/log"
+)
+
+var reUploadID = regexp.MustCompile(`^[0-9A-Za-z_\-]{1,128}$`)
+var reContentRange = regexp.MustCompile(`^bytes (?:(\d+)-(\d+)|\*)/(\d+)$`)
+
+// errFileTooLarge the file is larger than the max size
+var errFileTooLarge = errors.New("the file is too large")
+
+// UploadChunkTTL the partial file of a chunked upload is removed if it is not modified in the duration
+var UploadChunkTTL = 24 * time.Hour
+
+// UploadMaxFormValues the max number of the form values of a multipart body
+var UploadMaxFormValues = 256
+
+// UploadMaxFormSize the max total size (bytes) of the form values of a multipart body, the max size of the upload is applied as well
+var UploadMaxFormSize int64 = 10 << 20
+
+// chunkSwept the unix time of the last sweep of the partial files
+var chunkSwept int64
+
+// chunkLocks the partial files which are being written
+var chunkLocks = map[string]bool{}
+var chunkLocksMutex sync.Mutex
+
+// uploadError the upload error with the status code
+type uploadError struct {
+	Code    int
+	Message string
+}
+
+func (err uploadError) Error() string {
+	return err.Message
+}
+
+// limitReader count the bytes and stop reading if the limit is exceeded
+type limitReader struct {
+	reader io.Reader
+	limit  int64
+	size   int64
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	if r.limit > 0 && r.size > r.limit {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+// uploads check if the path has the :file.<field> or :files bindings
+func (path Path) uploads() bool {
+	for _, value := range path.In {
+		if v, ok := value.(string); ok && (v == ":files" || strings.HasPrefix(v, ":file.")) {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadHandler stream the uploaded files to the file system, the metadata is set to __uploads.
+// A large file can be uploaded in chunks with the X-Upload-Id and Content-Range (bytes start-end/total) headers,
+// send Content-Range: bytes */total to get the received offset and resume the upload.
+func (path Path) uploadHandler() gin.HandlerFunc {
+
+	option := Upload{}
+	if path.Upload != nil {
+		option = *path.Upload
+	}
+
+	if option.FS == "" {
+		option.FS = "system"
+	}
+
+	return func(c *gin.Context) {
+
+		stor, err := fs.Get(option.FS)
+		if err != nil {
+			abort(c, 500, err.Error(), nil)
+			return
+		}
+
+		var files []UploadedFile
+		if c.GetHeader("X-Upload-Id") != "" && c.GetHeader("Content-Range") != "" {
+			files, err = option.chunk(c, stor)
+		} else {
+			files, err = option.save(c, stor)
+		}
+
+		if err != nil {
+			code := 500
+			if e, ok := err.(uploadError); ok {
+				code = e.Code
+			}
+			log.Error("[Path] %s upload %s", path.Path, err.Error())
+			abort(c, code, err.Error(), nil)
+			return
+		}
+
+		// the chunk is received, the upload is not completed yet
+		if c.IsAborted() {
+			return
+		}
+
+		// validate the form values of the multipart body
+		if path.Schema != nil {
+			violations := path.Schema.validateBody(c, []Violation{})
+			if len(violations) > 0 {
+				option.remove(stor, files)
+				abort(c, 400, "invalid request", gin.H{"errors": violations})
+				return
+			}
+		}
+
+		c.Set("__uploads", files)
+	}
+}
+
+// save stream the multipart files to the file system
+func (option Upload) save(c *gin.Context, stor fs.FileSystem) ([]UploadedFile, error) {
+
+	files := []UploadedFile{}
+
+	// the multipart form has been parsed (by the csrf guard)
+	if c.Request.MultipartForm != nil {
+		for field, headers := range c.Request.MultipartForm.File {
+			for _, header := range headers {
+				if option.MaxFiles > 0 && len(files) >= option.MaxFiles {
+					option.remove(stor, files)
+					return nil, uploadError{Code: 400, Message: fmt.Sprintf("the number of the files exceeds %d", option.MaxFiles)}
+				}
+
+				file, err := header.Open()
+				if err != nil {
+					option.remove(stor, files)
+					return nil, uploadError{Code: 400, Message: err.Error()}
+				}
+
+				uploaded, err := option.write(stor, field, header.Filename, file)
+				file.Close()
+				if err != nil {
+					option.remove(stor, files)
+					return nil, err
+				}
+				files = append(files, uploaded)
+			}
+		}
+		return files, nil
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, uploadError{Code: 400, Message: err.Error()}
+	}
+
+	form := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			option.remove(stor, files)
+			return nil, uploadError{Code: 400, Message: err.Error()}
+		}
+
+		if part.FileName() == "" {
+			if err := option.readFormValue(form, part); err != nil {
+				option.remove(stor, files)
+				return nil, err
+			}
+			continue
+		}
+
+		if option.MaxFiles > 0 && len(files) >= option.MaxFiles {
+			option.remove(stor, files)
+			return nil, uploadError{Code: 400, Message: fmt.Sprintf("the number of the files exceeds %d", option.MaxFiles)}
+		}
+
+		uploaded, err := option.write(stor, part.FormName(), part.FileName(), part)
+		part.Close()
+		if err != nil {
+			option.remove(stor, files)
+			return nil, err
+		}
+		files = append(files, uploaded)
+	}
+
+	c.Request.PostForm = form
+	return files, nil
+}
+
+// chunk append the chunk to the partial file, the file is saved to the file system when the last chunk is received
+func (option Upload) chunk(c *gin.Context, stor fs.FileSystem) ([]UploadedFile, error) {
+
+	id := c.GetHeader("X-Upload-Id")
+	if !reUploadID.MatchString(id) {
+		return nil, uploadError{Code: 400, Message: "the upload id is invalid"}
+	}
+
+	matches := reContentRange.FindStringSubmatch(c.GetHeader("Content-Range"))
+	if matches == nil {
+		return nil, uploadError{Code: 400, Message: "the content range is invalid"}
+	}
+
+	total, _ := strconv.ParseInt(matches[3], 10, 64)
+	if option.MaxSize > 0 && total > option.MaxSize {
+		return nil, uploadError{Code: 413, Message: errFileTooLarge.Error()}
+	}
+
+	// the partial file is bound to the session
+	hash := sha256.Sum256([]byte(c.GetString("__sid") + ":" + id))
+	dir := filepath.Join(os.TempDir(), "gou-uploads")
+	partial := filepath.Join(dir, hex.EncodeToString(hash[:]))
+
+	go sweepChunks(dir)
+
+	// the chunks of an upload are written one by one
+	if !lockChunk(partial) {
+		return nil, uploadError{Code: 409, Message: "the upload is in progress"}
+	}
+	defer unlockChunk(partial)
+
+	offset := int64(0)
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	}
+
+	// the total size of the chunks is saved in the .size file when the first chunk is received
+	if offset > 0 {
+		size, err := os.ReadFile(partial + ".size")
+		if err == nil && string(size) != strconv.FormatInt(total, 10) {
+			return nil, uploadError{Code: 400, Message: fmt.Sprintf("the size %d does not match the upload size %s", total, size)}
+		}
+	}
+
+	// get the received offset
+	if matches[1] == "" {
+		c.JSON(202, gin.H{"id": id, "offset": offset, "size": total})
+		c.Abort()
+		return nil, nil
+	}
+
+	start, _ := strconv.ParseInt(matches[1], 10, 64)
+	end, _ := strconv.ParseInt(matches[2], 10, 64)
+	if end < start || end >= total {
+		return nil, uploadError{Code: 400, Message: "the content range is invalid"}
+	}
+
+	if start != offset {
+		abort(c, 409, "the chunk does not match the received offset", gin.H{"id": id, "offset": offset, "size": total})
+		return nil, nil
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, uploadError{Code: 400, Message: err.Error()}
+	}
+
+	form := url.Values{}
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if err == io.EOF {
+			return nil, uploadError{Code: 400, Message: "the chunk is required"}
+		}
+
+		if err != nil {
+			return nil, uploadError{Code: 400, Message: err.Error()}
+		}
+
+		if part.FileName() != "" {
+			break
+		}
+
+		if err := option.readFormValue(form, part); err != nil {
+			return nil, err
+		}
+	}
+	defer part.Close()
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	if offset == 0 {
+		err = os.WriteFile(partial+".size", []byte(strconv.FormatInt(total, 10)), 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	size := end - start + 1
+	n, err := io.Copy(file, io.LimitReader(part, size))
+	file.Close()
+	if err != nil || n != size {
+		os.Truncate(partial, offset)
+		return nil, uploadError{Code: 400, Message: fmt.Sprintf("the chunk size is %d, %d expected", n, size)}
+	}
+
+	if end+1 < total {
+		c.JSON(202, gin.H{"id": id, "offset": end + 1, "size": total})
+		c.Abort()
+		return nil, nil
+	}
+
+	// the last chunk
+	file, err = os.Open(partial)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(partial + ".size")
+	defer os.Remove(partial)
+	defer file.Close()
+
+	uploaded, err := option.write(stor, part.FormName(), part.FileName(), file)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Request.PostForm = form
+	return []UploadedFile{uploaded}, nil
+}
+
+// lockChunk lock the partial file, returns false if it is locked by another request
+func lockChunk(partial string) bool {
+	chunkLocksMutex.Lock()
+	defer chunkLocksMutex.Unlock()
+	if chunkLocks[partial] {
+		return false
+	}
+	chunkLocks[partial] = true
+	return true
+}
+
+// unlockChunk unlock the partial file
+func unlockChunk(partial string) {
+	chunkLocksMutex.Lock()
+	defer chunkLocksMutex.Unlock()
+	delete(chunkLocks, partial)
+}
+
+// sweepChunks remove the partial files which are not modified in the UploadChunkTTL, it runs once a minute at most
+func sweepChunks(dir string) {
+	now := time.Now()
+	last := atomic.LoadInt64(&chunkSwept)
+	if now.Unix()-last < 60 || !atomic.CompareAndSwapInt64(&chunkSwept, last, now.Unix()) {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(dir, entry.Name())
+		if strings.HasSuffix(name, ".size") {
+			if _, err := os.Stat(strings.TrimSuffix(name, ".size")); err == nil {
+				continue // removed with the partial file
+			}
+		}
+
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < UploadChunkTTL {
+			continue
+		}
+
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			log.Error("[Upload] remove the partial file %s", err.Error())
+			continue
+		}
+		if !strings.HasSuffix(name, ".size") {
+			os.Remove(name + ".size")
+		}
+	}
+}
+
+// write check the MIME type and the size, and stream the file to the file system
+func (option Upload) write(stor fs.FileSystem, field string, name string, reader io.Reader) (UploadedFile, error) {
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return UploadedFile{}, uploadError{Code: 400, Message: err.Error()}
+	}
+	head = head[:n]
+
+	mime := mimetype.Detect(head).String()
+	if !option.allowed(mime) {
+		return UploadedFile{}, uploadError{Code: 415, Message: fmt.Sprintf("the file type %s is not allowed", mime)}
+	}
+
+	dir := option.Dir
+	if dir == "" {
+		dir = "/" + time.Now().Format("20060102")
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%s%s", strings.ToUpper(uuid.NewString()), filepath.Ext(name)))
+	hash := sha256.New()
+	limited := &limitReader{reader: io.MultiReader(bytes.NewReader(head), reader), limit: option.MaxSize}
+	_, err = stor.Write(filename, io.TeeReader(limited, hash), uint32(os.ModePerm))
+	if err != nil {
+		stor.Remove(filename)
+		if errors.Is(err, errFileTooLarge) || (option.MaxSize > 0 && limited.size > option.MaxSize) {
+			return UploadedFile{}, uploadError{Code: 413, Message: errFileTooLarge.Error()}
+		}
+		return UploadedFile{}, err
+	}
+
+	return UploadedFile{
+		Field: field,
+		Name:  name,
+		Path:  filename,
+		Size:  limited.size,
+		Mime:  mime,
+		Hash:  hex.EncodeToString(hash.Sum(nil)),
+		FS:    option.FS,
+	}, nil
+}
+
+// allowed check the MIME type
+func (option Upload) allowed(mime string) bool {
+	if len(option.Mimes) == 0 {
+		return true
+	}
+
+	mime = strings.TrimSpace(strings.Split(mime, ";")[0])
+	for _, pattern := range option.Mimes {
+		if pattern == mime || pattern == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mime, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove the saved files when the upload fails
+func (option Upload) remove(stor fs.FileSystem, files []UploadedFile) {
+	for _, file := range files {
+		stor.Remove(file.Path)
+	}
+}
+
+// readFormValue read the value of the form part, the number and the total size of the form values are limited
+func (option Upload) readFormValue(form url.Values, part *multipart.Part) error {
+	defer part.Close()
+
+	count := 0
+	size := int64(len(part.FormName()))
+	for name, values := range form {
+		count += len(values)
+		size += int64(len(name))
+		for _, value := range values {
+			size += int64(len(value))
+		}
+	}
+
+	if count >= UploadMaxFormValues {
+		return uploadError{Code: 400, Message: fmt.Sprintf("the number of the form values exceeds %d", UploadMaxFormValues)}
+	}
+
+	limit := UploadMaxFormSize
+	if option.MaxSize > 0 && option.MaxSize < limit {
+		limit = option.MaxSize
+	}
+
+	value, err := io.ReadAll(io.LimitReader(part, limit-size+1))
+	if err != nil {
+		return uploadError{Code: 400, Message: err.Error()}
+	}
+
+	if size+int64(len(value)) > limit {
+		return uploadError{Code: 413, Message: "the form values are too large"}
+	}
+
+	form.Add(part.FormName(), string(value))
+	return nil
+}
+
+// uploadedFiles get the uploaded files of the request
+func uploadedFiles(c *gin.Context) []UploadedFile {
+	if value, has := c.Get("__uploads"); has {
+		if files, ok := value.([]UploadedFile); ok {
+			return files
+		}
+	}
+	return []UploadedFile{}
+}
+
+// Map the metadata map of the uploaded file
+func (file UploadedFile) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"field": file.Field,
+		"name":  file.Name,
+		"path":  file.Path,
+		"size":  file.Size,
+		"mime":  file.Mime,
+		"hash":  file.Hash,
+		"fs":    file.FS,
+	}
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/fs"
+	"github.com/yaoapp/gou/fs/system"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestUploadFiles(t *testing.T) {
+	root := t.TempDir()
+	router := uploadRouter(root, &Upload{FS: "unit-upload", Dir: "/uploads", MaxFiles: 2, Mimes: []string{"text/*"}})
+
+	content := []byte("hello world")
+	body, contentType := uploadBody(t, map[string][]byte{"avatar": content}, map[string]string{"name": "foo"})
+	response := testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 200, response.Code)
+
+	res := responseMap(response)
+	file := res["file"].(map[string]interface{})
+	hash := sha256.Sum256(content)
+	assert.Equal(t, "avatar.txt", file["name"])
+	assert.Equal(t, float64(len(content)), file["size"])
+	assert.Equal(t, hex.EncodeToString(hash[:]), file["hash"])
+	assert.Contains(t, file["mime"], "text/plain")
+	assert.Equal(t, "foo", res["name"])
+	assert.Len(t, res["files"], 1)
+
+	data, err := os.ReadFile(filepath.Join(root, file["path"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, data)
+
+	// too many files
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": content, "a": content, "b": content}, nil)
+	response = testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 400, response.Code)
+
+	// the MIME type is not allowed
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": {0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}}, nil)
+	response = testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 415, response.Code)
+}
+
+func TestUploadSchema(t *testing.T) {
+	root := t.TempDir()
+	uploadRouter(root, nil)
+	router := gin.New()
+	http := HTTP{
+		Name:  "upload",
+		Guard: "-",
+		Paths: []Path{{
+			Path:    "/upload/schema",
+			Method:  "POST",
+			Process: "unit.api.upload",
+			In:      []interface{}{":file.avatar", ":files", "$form.name"},
+			Out:     Out{Status: 200, Type: "application/json"},
+			Upload:  &Upload{FS: "unit-upload", Dir: "/uploads"},
+			Schema:  &Schema{Body: []Field{{Name: "name", Type: "string", Required: true}}},
+		}},
+	}
+	http.Routes(router, "/")
+
+	// the multipart body is not parsed by the validator, the files are streamed by the upload handler
+	content := []byte("hello world")
+	body, contentType := uploadBody(t, map[string][]byte{"avatar": content}, map[string]string{"name": "foo"})
+	response := testRequest(router, "POST", "/upload/schema", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 200, response.Code)
+
+	res := responseMap(response)
+	assert.Equal(t, "foo", res["name"])
+	assert.Equal(t, float64(len(content)), res["file"].(map[string]interface{})["size"])
+
+	// the form values are validated when the body is read, the saved files are removed
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": content}, nil)
+	response = testRequest(router, "POST", "/upload/schema", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 400, response.Code)
+	assert.Contains(t, response.Body.String(), "name is required")
+
+	entries, err := os.ReadDir(filepath.Join(root, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, entries, 1)
+}
+
+func TestUploadMaxSize(t *testing.T) {
+	router := uploadRouter(t.TempDir(), &Upload{FS: "unit-upload", MaxSize: 8})
+	body, contentType := uploadBody(t, map[string][]byte{"avatar": []byte("hello world")}, nil)
+	response := testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 413, response.Code)
+}
+
+func TestUploadFormLimit(t *testing.T) {
+	router := uploadRouter(t.TempDir(), &Upload{FS: "unit-upload", MaxSize: 16})
+	body, contentType := uploadBody(t, map[string][]byte{"avatar": []byte("hello")}, map[string]string{"name": "a very long name value"})
+	response := testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 413, response.Code)
+
+	values := map[string]string{}
+	for i := 0; i <= UploadMaxFormValues; i++ {
+		values[fmt.Sprintf("v%d", i)] = "1"
+	}
+	router = uploadRouter(t.TempDir(), &Upload{FS: "unit-upload"})
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": []byte("hello")}, values)
+	response = testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType})
+	assert.Equal(t, 400, response.Code)
+}
+
+func TestUploadChunkLock(t *testing.T) {
+	assert.True(t, lockChunk("unit-partial"))
+	assert.False(t, lockChunk("unit-partial"))
+	unlockChunk("unit-partial")
+	assert.True(t, lockChunk("unit-partial"))
+	unlockChunk("unit-partial")
+}
+
+func TestUploadChunks(t *testing.T) {
+	root := t.TempDir()
+	router := uploadRouter(root, &Upload{FS: "unit-upload", Dir: "/chunks"})
+	content := []byte("hello chunked world")
+	id := fmt.Sprintf("unit-%d", os.Getpid())
+
+	// the first chunk
+	body, contentType := uploadBody(t, map[string][]byte{"avatar": content[:5]}, nil)
+	response := testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType, "X-Upload-Id": id, "Content-Range": fmt.Sprintf("bytes 0-4/%d", len(content))})
+	assert.Equal(t, 202, response.Code)
+	assert.Equal(t, float64(5), responseMap(response)["offset"])
+
+	// the received offset
+	response = testRequest(router, "POST", "/upload", nil, map[string]string{"X-Upload-Id": id, "Content-Range": fmt.Sprintf("bytes */%d", len(content))})
+	assert.Equal(t, 202, response.Code)
+	assert.Equal(t, float64(5), responseMap(response)["offset"])
+
+	// the size does not match the first chunk
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": content[5:]}, nil)
+	response = testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType, "X-Upload-Id": id, "Content-Range": fmt.Sprintf("bytes 5-%d/%d", len(content), len(content)+1)})
+	assert.Equal(t, 400, response.Code)
+
+	// the chunk does not match the offset
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": content[8:]}, nil)
+	response = testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType, "X-Upload-Id": id, "Content-Range": fmt.Sprintf("bytes 8-%d/%d", len(content)-1, len(content))})
+	assert.Equal(t, 409, response.Code)
+
+	// the last chunk
+	body, contentType = uploadBody(t, map[string][]byte{"avatar": content[5:]}, nil)
+	response = testRequest(router, "POST", "/upload", body, map[string]string{"Content-Type": contentType, "X-Upload-Id": id, "Content-Range": fmt.Sprintf("bytes 5-%d/%d", len(content)-1, len(content))})
+	assert.Equal(t, 200, response.Code)
+
+	file := responseMap(response)["file"].(map[string]interface{})
+	data, err := os.ReadFile(filepath.Join(root, file["path"].(string)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, content, data)
+}
+
+func TestUploadSweepChunks(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "stale")
+	fresh := filepath.Join(dir, "fresh")
+	for _, name := range []string{stale, stale + ".size", fresh, fresh + ".size"} {
+		if err := os.WriteFile(name, []byte("1"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expired := time.Now().Add(-UploadChunkTTL - time.Minute)
+	os.Chtimes(stale, expired, expired)
+	os.Chtimes(fresh+".size", expired, expired)
+
+	atomic.StoreInt64(&chunkSwept, 0)
+	sweepChunks(dir)
+	for _, name := range []string{stale, stale + ".size"} {
+		_, err := os.Stat(name)
+		assert.True(t, os.IsNotExist(err))
+	}
+	for _, name := range []string{fresh, fresh + ".size"} {
+		_, err := os.Stat(name)
+		assert.Nil(t, err)
+	}
+}
+
+func uploadRouter(root string, option *Upload) *gin.Engine {
+	fs.Register("unit-upload", system.New(root))
+	process.Register("unit.api.upload", func(process *process.Process) interface{} {
+		return map[string]interface{}{"file": process.Args[0], "files": process.Args[1], "name": process.Args[2]}
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	http := HTTP{
+		Name:  "upload",
+		Guard: "-",
+		Paths: []Path{{
+			Path:    "/upload",
+			Method:  "POST",
+			Process: "unit.api.upload",
+			In:      []interface{}{":file.avatar", ":files", "$form.name"},
+			Out:     Out{Status: 200, Type: "application/json"},
+			Upload:  option,
+		}},
+	}
+	http.Routes(router, "/")
+	return router
+}
+
+func uploadBody(t *testing.T, files map[string][]byte, values map[string]string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range values {
+		writer.WriteField(name, value)
+	}
+
+	for field, content := range files {
+		part, err := writer.CreateFormFile(field, field+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(content)
+	}
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/kun/maps"
+	"github.com/yaoapp/kun/str"
+)
+
+// the context keys of the coerced values
+var coercedKeys = map[string]string{
+	"params":  "__params",
+	"query":   "__query",
+	"headers": "__headers",
+}
+
+// validator validate the request via the path schema, it should be run before the guards
+func (path Path) validator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path.setPayload(c)
+		c.Set("__upload", path.uploads())
+		violations := path.Schema.Validate(c)
+		if len(violations) > 0 {
+			abort(c, 400, "invalid request", gin.H{"errors": violations})
+			return
+		}
+	}
+}
+
+// Validate validate the request and coerce the values, returns all of the violations
+func (schema *Schema) Validate(c *gin.Context) []Violation {
+	violations := []Violation{}
+
+	// params
+	params := map[string]interface{}{}
+	for _, field := range schema.Params {
+		value, has := c.Params.Get(field.Name)
+		violations = field.check("params", value, has && value != "", params, violations)
+	}
+	c.Set(coercedKeys["params"], params)
+
+	// query
+	query := map[string]interface{}{}
+	values := c.Request.URL.Query()
+	for _, field := range schema.Query {
+		value, has := values[field.Name]
+		violations = field.check("query", field.multi(value), has, query, violations)
+	}
+	c.Set(coercedKeys["query"], query)
+
+	// headers
+	headers := map[string]interface{}{}
+	for _, field := range schema.Headers {
+		value := c.Request.Header.Values(field.Name)
+		violations = field.check("headers", field.multi(value), len(value) > 0, headers, violations)
+	}
+	c.Set(coercedKeys["headers"], headers)
+
+	// body, the multipart body of the upload path is streamed, it is validated by the upload handler when the form is read
+	if !c.GetBool("__upload") || c.ContentType() != "multipart/form-data" {
+		violations = schema.validateBody(c, violations)
+	}
+
+	return violations
+}
+
+// validateBody validate the body and coerce the values
+func (schema *Schema) validateBody(c *gin.Context, violations []Violation) []Violation {
+	if len(schema.Body) == 0 {
+		return violations
+	}
+
+	payloads := requestPayloads(c)
+	for _, field := range schema.Body {
+		value, has := payloads[field.Name]
+		violations = field.check("body", value, has && value != nil, payloads, violations)
+	}
+	return violations
+}
+
+// check check the value and set the coerced value to the values
+func (field Field) check(in string, value interface{}, has bool, values map[string]interface{}, violations []Violation) []Violation {
+
+	if !has {
+		if field.Default != nil {
+			value = field.Default
+		} else if field.Required {
+			return append(violations, Violation{In: in, Field: field.Name, Message: fmt.Sprintf("%s is required", field.Name)})
+		} else {
+			return violations
+		}
+	}
+
+	v, err := field.Coerce(value)
+	if err != nil {
+		return append(violations, Violation{In: in, Field: field.Name, Input: value, Message: err.Error()})
+	}
+
+	for _, validation := range field.Validations {
+		method, has := model.Validations[validation.Method]
+		if !has {
+			continue
+		}
+
+		if !method(v, maps.MapStrAny(values), validation.Args...) {
+			message := fmt.Sprintf("%s %s validation failed", field.Name, validation.Method)
+			if validation.Message != "" {
+				message = str.Bind(validation.Message, map[string]interface{}{
+					"name":  field.Name,
+					"label": field.Label,
+					"input": v,
+				})
+			}
+			violations = append(violations, Violation{In: in, Field: field.Name, Input: v, Message: message})
+		}
+	}
+
+	values[field.Name] = v
+	return violations
+}
+
+// multi returns the first value unless the field is an array
+func (field Field) multi(values []string) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if field.Type == "array" {
+		res := []interface{}{}
+		for _, value := range values {
+			res = append(res, value)
+		}
+		return res
+	}
+
+	return values[0]
+}
+
+// Coerce convert the value to the type of the field
+func (field Field) Coerce(value interface{}) (interface{}, error) {
+
+	switch strings.ToLower(field.Type) {
+
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case nil, map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("%s should be a string", field.Name)
+		default:
+			return fmt.Sprintf("%v", v), nil
+		}
+
+	case "integer", "int":
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v == float64(int(v)) {
+				return int(v), nil
+			}
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("%s should be an integer", field.Name)
+
+	case "number", "float":
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("%s should be a number", field.Name)
+
+	case "boolean", "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+		return nil, fmt.Errorf("%s should be a boolean", field.Name)
+
+	case "array":
+		switch v := value.(type) {
+		case []interface{}:
+			return v, nil
+		case string:
+			res := []interface{}{}
+			for _, item := range strings.Split(v, ",") {
+				res = append(res, strings.TrimSpace(item))
+			}
+			return res, nil
+		}
+		return nil, fmt.Errorf("%s should be an array", field.Name)
+
+	case "object":
+		switch v := value.(type) {
+		case map[string]interface{}:
+			return v, nil
+		case string:
+			res := map[string]interface{}{}
+			if err := jsoniter.Unmarshal([]byte(v), &res); err == nil {
+				return res, nil
+			}
+		}
+		return nil, fmt.Errorf("%s should be an object", field.Name)
+	}
+
+	return value, nil
+}
+
+// requestPayloads get the payloads of the request, the form values will be used if the body is not JSON
+func requestPayloads(c *gin.Context) map[string]interface{} {
+	if value, has := c.Get("__payloads"); has {
+		if payloads, ok := value.(map[string]interface{}); ok {
+			return payloads
+		}
+	}
+
+	payloads := map[string]interface{}{}
+	if c.Request.PostForm == nil {
+		if c.ContentType() == "multipart/form-data" {
+			c.Request.ParseMultipartForm(32 << 20)
+		} else {
+			c.Request.ParseForm()
+		}
+	}
+
+	for name, values := range c.Request.PostForm {
+		if len(values) == 1 {
+			payloads[name] = values[0]
+			continue
+		}
+
+		items := []interface{}{}
+		for _, value := range values {
+			items = append(items, value)
+		}
+		payloads[name] = items
+	}
+
+	c.Set("__payloads", payloads)
+	return payloads
+}
+
+// coerced get the coerced value from the context
+func coerced(c *gin.Context, in string, name string) (interface{}, bool) {
+	values, has := c.Get(coercedKeys[in])
+	if !has {
+		return nil, false
+	}
+
+	valuesMap, ok := values.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	value, has := valuesMap[name]
+	return value, has
+}
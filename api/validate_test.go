@@ -0,0 +1,99 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestValidateSchema(t *testing.T) {
+	router := validateRouter(t)
+
+	response := testRequest(router, "POST", "/validate/1?page=2", `{"name":"foo","age":"18"}`, nil)
+	assert.Equal(t, 200, response.Code)
+
+	res := responseMap(response)
+	assert.Equal(t, []interface{}{float64(1), float64(2), float64(20), "foo", float64(18)}, res["args"])
+}
+
+func TestValidateSchemaViolations(t *testing.T) {
+	router := validateRouter(t)
+
+	response := testRequest(router, "POST", "/validate/abc?page=0", `{"age":"eighteen"}`, nil)
+	assert.Equal(t, 400, response.Code)
+
+	res := responseMap(response)
+	assert.Equal(t, float64(400), res["code"])
+
+	errors, ok := res["errors"].([]interface{})
+	if !ok {
+		t.Fatal("errors should be an array")
+	}
+
+	fields := []string{}
+	for _, err := range errors {
+		fields = append(fields, err.(map[string]interface{})["in"].(string)+"."+err.(map[string]interface{})["field"].(string))
+	}
+	assert.ElementsMatch(t, []string{"params.id", "query.page", "body.name", "body.age"}, fields)
+}
+
+func TestFieldCoerce(t *testing.T) {
+	v, err := Field{Name: "page", Type: "integer"}.Coerce("10")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, v)
+
+	v, err = Field{Name: "price", Type: "number"}.Coerce("0.618")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.618, v)
+
+	v, err = Field{Name: "enabled", Type: "boolean"}.Coerce("true")
+	assert.Nil(t, err)
+	assert.Equal(t, true, v)
+
+	v, err = Field{Name: "ids", Type: "array"}.Coerce("1,2")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"1", "2"}, v)
+
+	v, err = Field{Name: "data", Type: "object"}.Coerce(`{"foo":"bar"}`)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, v)
+
+	_, err = Field{Name: "page", Type: "integer"}.Coerce("1.5")
+	assert.Equal(t, "page should be an integer", err.Error())
+}
+
+func validateRouter(t *testing.T) *gin.Engine {
+	process.Register("unit.api.validate", func(process *process.Process) interface{} {
+		return map[string]interface{}{"args": process.Args}
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	http := HTTP{
+		Name:  "validate",
+		Guard: "-",
+		Paths: []Path{{
+			Path:    "/validate/:id",
+			Method:  "POST",
+			Process: "unit.api.validate",
+			In:      []interface{}{"$param.id", "$query.page", "$query.size", "$payload.name", "$payload.age"},
+			Out:     Out{Status: 200, Type: "application/json"},
+			Schema: &Schema{
+				Params: []Field{{Name: "id", Type: "integer", Required: true}},
+				Query: []Field{
+					{Name: "page", Type: "integer", Validations: []model.Validation{{Method: "min", Args: []interface{}{1}}}},
+					{Name: "size", Type: "integer", Default: 20},
+				},
+				Body: []Field{
+					{Name: "name", Type: "string", Required: true},
+					{Name: "age", Type: "integer"},
+				},
+			},
+		}},
+	}
+	http.Routes(router, "/")
+	return router
+}
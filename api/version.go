@@ -0,0 +1,194 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+)
+
+// prefix the route prefix of the api, the version is a part of the prefix if the versioning is enabled
+func (http HTTP) prefix(root string) string {
+	if http.Versioning != "" && http.Version != "" {
+		root = filepath.Join(root, "/", versionPrefix(http.Version))
+	}
+
+	if http.Group != "" {
+		return filepath.Join(root, "/", http.Group)
+	}
+	return root
+}
+
+// resolve returns the http with the inherited paths
+func (api *API) resolve() HTTP {
+	return api.HTTP.inherit(map[string]bool{api.ID: true})
+}
+
+// inherit merge the paths of the extended api, the paths of the api take precedence
+func (http HTTP) inherit(visited map[string]bool) HTTP {
+	if http.Extends == "" {
+		return http
+	}
+
+	if visited[http.Extends] {
+		log.Error("[API] %s circular extends %s", http.Name, http.Extends)
+		return http
+	}
+	visited[http.Extends] = true
+
+	base, has := APIs[http.Extends]
+	if !has {
+		log.Error("[API] %s extends %s does not load", http.Name, http.Extends)
+		return http
+	}
+
+	parent := base.HTTP.inherit(visited)
+	paths := append([]Path{}, http.Paths...)
+	defined := map[string]bool{}
+	for _, path := range http.Paths {
+		defined[fmt.Sprintf("%s.%s", strings.ToUpper(path.Method), path.Path)] = true
+	}
+
+	for _, path := range parent.Paths {
+		if defined[fmt.Sprintf("%s.%s", strings.ToUpper(path.Method), path.Path)] {
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	http.Paths = paths
+	return http
+}
+
+// setVersionRoutes set the Accept-Version header dispatchers of the apis which versioning is header
+func setVersionRoutes(router *gin.Engine, root string) {
+
+	groups := map[string][]*API{}
+	for _, api := range APIs {
+		if api.HTTP.Versioning != "header" || api.HTTP.Version == "" {
+			continue
+		}
+		groups[api.HTTP.Group] = append(groups[api.HTTP.Group], api)
+	}
+
+	for group, apis := range groups {
+
+		// the latest version first
+		sort.Slice(apis, func(i, j int) bool {
+			return compareVersion(apis[i].HTTP.Version, apis[j].HTTP.Version) > 0
+		})
+
+		versions := map[string]string{}
+		defaults := versionPrefix(apis[0].HTTP.Version)
+		for _, api := range apis {
+			versions[strings.TrimPrefix(api.HTTP.Version, "v")] = versionPrefix(api.HTTP.Version)
+			if api.HTTP.Default {
+				defaults = versionPrefix(api.HTTP.Version)
+			}
+		}
+
+		base := filepath.Join(root, "/", group)
+		dispatcher := versionDispatcher(router, root, base, versions, defaults)
+		routes := router.Group(base)
+		registered := map[string]bool{}
+		for _, api := range apis {
+			http := api.resolve()
+			for _, path := range http.Paths {
+				method := strings.ToUpper(path.Method)
+				key := fmt.Sprintf("%s.%s", method, path.Path)
+				if registered[key] {
+					continue
+				}
+				registered[key] = true
+				http.method(method, path.Path, routes, dispatcher)
+			}
+		}
+	}
+}
+
+// versionDispatcher dispatch the request to the version selected by the Accept-Version header
+func versionDispatcher(router *gin.Engine, root string, base string, versions map[string]string, defaults string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Version")
+		prefix := defaults
+		if version := c.GetHeader("Accept-Version"); version != "" {
+			var has bool
+			prefix, has = versions[strings.TrimPrefix(version, "v")]
+			if !has {
+				abort(c, 400, fmt.Sprintf("the version %s does not support", version), nil)
+				return
+			}
+		}
+
+		c.Request.URL.Path = filepath.Join(root, "/", prefix) + c.Request.URL.Path[len(strings.TrimSuffix(root, "/")):]
+		c.Request.URL.RawPath = ""
+		router.HandleContext(c)
+		c.Abort()
+	}
+}
+
+// deprecationHandler set the Deprecation and Sunset headers
+func (path Path) deprecationHandler(deprecated bool, sunset string) gin.HandlerFunc {
+
+	if path.Sunset != "" {
+		sunset = path.Sunset
+	}
+
+	sunsetAt := ""
+	if sunset != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02", http.TimeFormat} {
+			if t, err := time.Parse(layout, sunset); err == nil {
+				sunsetAt = t.UTC().Format(http.TimeFormat)
+				break
+			}
+		}
+
+		if sunsetAt == "" {
+			log.Error("[Path] %s the sunset %s is invalid", path.Path, sunset)
+		}
+	}
+
+	deprecated = deprecated || path.Deprecated
+	return func(c *gin.Context) {
+		if deprecated {
+			c.Writer.Header().Set("Deprecation", "true")
+		}
+
+		if sunsetAt != "" {
+			c.Writer.Header().Set("Sunset", sunsetAt)
+		}
+	}
+}
+
+// versionPrefix the route prefix of the version. 1.0 -> v1.0, v2 -> v2
+func versionPrefix(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
+
+// compareVersion compare the versions by the numeric segments
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		av, bv := 0, 0
+		if i < len(as) {
+			av, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bv, _ = strconv.Atoi(bs[i])
+		}
+		if av != bv {
+			if av > bv {
+				return 1
+			}
+			return -1
+		}
+	}
+	return strings.Compare(a, b)
+}
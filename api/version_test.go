@@ -0,0 +1,133 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestVersionPath(t *testing.T) {
+	defer versionTestAPIs("path")()
+	router := versionRouter()
+
+	response := testRequest(router, "GET", "/api/v1/users/hello", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v1", responseMap(response)["version"])
+
+	response = testRequest(router, "GET", "/api/v2/users/hello", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v2", responseMap(response)["version"])
+
+	// inherited from v1
+	response = testRequest(router, "GET", "/api/v2/users/legacy", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v1", responseMap(response)["version"])
+
+	response = testRequest(router, "GET", "/api/users/hello", nil, nil)
+	assert.Equal(t, 404, response.Code)
+}
+
+func TestVersionHeader(t *testing.T) {
+	defer versionTestAPIs("header")()
+	router := versionRouter()
+
+	// the default version
+	response := testRequest(router, "GET", "/api/users/hello", nil, nil)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v1", responseMap(response)["version"])
+	assert.Equal(t, "Accept-Version", response.Header().Get("Vary"))
+
+	response = testRequest(router, "GET", "/api/users/hello", nil, map[string]string{"Accept-Version": "2"})
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v2", responseMap(response)["version"])
+
+	response = testRequest(router, "GET", "/api/users/legacy", nil, map[string]string{"Accept-Version": "v2"})
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "v1", responseMap(response)["version"])
+
+	response = testRequest(router, "GET", "/api/users/hello", nil, map[string]string{"Accept-Version": "3"})
+	assert.Equal(t, 400, response.Code)
+}
+
+func TestVersionDeprecation(t *testing.T) {
+	defer versionTestAPIs("path")()
+	router := versionRouter()
+
+	response := testRequest(router, "GET", "/api/v1/users/hello", nil, nil)
+	assert.Equal(t, "true", response.Header().Get("Deprecation"))
+	assert.Equal(t, "Thu, 31 Dec 2026 00:00:00 GMT", response.Header().Get("Sunset"))
+
+	response = testRequest(router, "GET", "/api/v2/users/hello", nil, nil)
+	assert.Empty(t, response.Header().Get("Deprecation"))
+
+	response = testRequest(router, "GET", "/api/v2/users/old", nil, nil)
+	assert.Equal(t, "true", response.Header().Get("Deprecation"))
+
+	doc := OpenAPI("/api")
+	paths := doc["paths"].(map[string]interface{})
+	assert.Equal(t, true, paths["/api/v1/users/hello"].(map[string]interface{})["get"].(map[string]interface{})["deprecated"])
+	assert.NotContains(t, paths["/api/v2/users/hello"].(map[string]interface{})["get"], "deprecated")
+}
+
+func TestCompareVersion(t *testing.T) {
+	assert.Equal(t, 1, compareVersion("2.0", "1.9.9"))
+	assert.Equal(t, -1, compareVersion("v1.2", "1.10"))
+	assert.Equal(t, 0, compareVersion("1.0", "1.0"))
+}
+
+func versionRouter() *gin.Engine {
+	process.Register("unit.version.v1", func(process *process.Process) interface{} {
+		return map[string]interface{}{"version": "v1"}
+	})
+	process.Register("unit.version.v2", func(process *process.Process) interface{} {
+		return map[string]interface{}{"version": "v2"}
+	})
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	SetRoutes(router, "/api")
+	return router
+}
+
+func versionTestAPIs(versioning string) func() {
+	apis := APIs
+	APIs = map[string]*API{
+		"users.v1": {
+			ID:   "users.v1",
+			Type: "http",
+			HTTP: HTTP{
+				Name:       "users v1",
+				Version:    "1",
+				Versioning: versioning,
+				Default:    true,
+				Deprecated: true,
+				Sunset:     "2026-12-31",
+				Group:      "users",
+				Guard:      "-",
+				Paths: []Path{
+					{Path: "/hello", Method: "GET", Process: "unit.version.v1"},
+					{Path: "/legacy", Method: "GET", Process: "unit.version.v1"},
+				},
+			},
+		},
+		"users.v2": {
+			ID:   "users.v2",
+			Type: "http",
+			HTTP: HTTP{
+				Name:       "users v2",
+				Version:    "2",
+				Versioning: versioning,
+				Extends:    "users.v1",
+				Group:      "users",
+				Guard:      "-",
+				Paths: []Path{
+					{Path: "/hello", Method: "GET", Process: "unit.version.v2"},
+					{Path: "/old", Method: "GET", Process: "unit.version.v2", Deprecated: true},
+				},
+			},
+		},
+	}
+	return func() { APIs = apis }
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector/database"
+	"github.com/yaoapp/gou/connector/elasticsearch"
 	mongo "github.com/yaoapp/gou/connector/mongo"
 	"github.com/yaoapp/gou/connector/openai"
 	"github.com/yaoapp/gou/connector/redis"
@@ -13,16 +14,21 @@ import (
 // Connectors the loaded connectors
 var Connectors = map[string]Connector{}
 
-// Load a connector from source
+// Load a connector from the file
 func Load(file string, id string) (Connector, error) {
 
-	dsl := DSL{}
 	data, err := application.App.Read(file)
 	if err != nil {
 		return nil, err
 	}
+	return LoadSource(data, id, file)
+}
+
+// LoadSource load a connector from the source
+func LoadSource(data []byte, id string, file string) (Connector, error) {
 
-	err = application.Parse(file, data, &dsl)
+	dsl := DSL{}
+	err := application.Parse(file, data, &dsl)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +88,10 @@ func make(typ string) (Connector, error) {
 	case OPENAI:
 		c := &openai.Connector{}
 		return c, nil
+
+	case ELASTICSEARCH:
+		c := &elasticsearch.Connector{}
+		return c, nil
 	}
 
 	return nil, fmt.Errorf("%s does not support yet", typ)
@@ -1,17 +1,26 @@
 package connector
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector/database"
+	"github.com/yaoapp/gou/connector/elasticsearch"
 	mongo "github.com/yaoapp/gou/connector/mongo"
 	"github.com/yaoapp/gou/connector/openai"
 	"github.com/yaoapp/gou/connector/redis"
+	"github.com/yaoapp/gou/process"
 )
 
 func TestLoadMysql(t *testing.T) {
@@ -57,6 +66,33 @@ func TestLoadSQLite(t *testing.T) {
 	assert.Equal(t, "sqlite", Connectors["sqlite"].ID())
 }
 
+func TestDatabaseStatus(t *testing.T) {
+	file := prepare(t, "sqlite")
+	_, err := Load(file, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Remove("sqlite")
+
+	xun := Connectors["sqlite"].(*database.Xun)
+	xun.Check()
+
+	status, ok := process.New("connectors.sqlite.Status").Run().(map[string]interface{})
+	if !ok {
+		t.Fatal("the status is not a map")
+	}
+
+	hosts := status["hosts"].([]map[string]interface{})
+	assert.Equal(t, "sqlite", status["id"])
+	assert.Len(t, hosts, 1)
+	assert.Equal(t, true, hosts[0]["healthy"])
+	assert.Equal(t, int64(1), hosts[0]["pings"])
+	assert.Contains(t, hosts[0], "pool")
+
+	_, err = Connectors["sqlite"].Query()
+	assert.Nil(t, err)
+}
+
 func TestLoadRedis(t *testing.T) {
 	file := prepare(t, "redis")
 	_, err := Load(file, "redis")
@@ -127,6 +163,160 @@ func TestLoadOpenAI(t *testing.T) {
 	assert.Contains(t, setting["key"], "sk-")
 }
 
+func TestLoadElasticsearch(t *testing.T) {
+	server := elasticsearchServer()
+	defer server.Close()
+
+	dsl := fmt.Sprintf(`{"type":"elasticsearch","options":{"hosts":["%s"],"prefix":"test_"}}`, server.URL)
+	_, err := LoadSource([]byte(dsl), "es", "es.conn.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Remove("es")
+
+	if !Connectors["es"].Is(ELASTICSEARCH) {
+		t.Fatal("the connector is not a ELASTICSEARCH")
+	}
+
+	if _, ok := Connectors["es"].(*elasticsearch.Connector); !ok {
+		t.Fatal("the es connector is not a *elasticsearch.Connector")
+	}
+	assert.Equal(t, "es", Connectors["es"].ID())
+
+	assert.Equal(t, false, process.New("connectors.es.IndexExists", "pets").Run())
+	process.New("connectors.es.CreateIndex", "pets", map[string]interface{}{"mappings": map[string]interface{}{}}).Run()
+	assert.Equal(t, true, process.New("connectors.es.IndexExists", "pets").Run())
+
+	process.New("connectors.es.Index", "pets", 1, map[string]interface{}{"name": "Cookie"}).Run()
+	res := process.New("connectors.es.Index", "pets", nil, map[string]interface{}{"name": "Baby"}).Run().(map[string]interface{})
+	assert.NotEmpty(t, res["_id"])
+
+	doc := process.New("connectors.es.Get", "pets", 1).Run().(map[string]interface{})
+	assert.Equal(t, "Cookie", doc["name"])
+	assert.Nil(t, process.New("connectors.es.Get", "pets", 404).Run())
+
+	process.New("connectors.es.Bulk", "pets", []interface{}{
+		map[string]interface{}{"action": "index", "id": 2, "doc": map[string]interface{}{"name": "Max"}},
+		map[string]interface{}{"action": "update", "id": 1, "doc": map[string]interface{}{"name": "Cookie Monster"}},
+		map[string]interface{}{"action": "delete", "id": 2},
+	}).Run()
+	doc = process.New("connectors.es.Get", "pets", 1).Run().(map[string]interface{})
+	assert.Equal(t, "Cookie Monster", doc["name"])
+	assert.Nil(t, process.New("connectors.es.Get", "pets", 2).Run())
+
+	process.New("connectors.es.Refresh", "pets").Run()
+	res = process.New("connectors.es.Search", "pets", map[string]interface{}{"query": map[string]interface{}{"match_all": map[string]interface{}{}}}).Run().(map[string]interface{})
+	total := res["hits"].(map[string]interface{})["total"].(map[string]interface{})
+	assert.Equal(t, float64(2), total["value"])
+
+	assert.Equal(t, true, process.New("connectors.es.Delete", "pets", 1).Run())
+	assert.Equal(t, false, process.New("connectors.es.Delete", "pets", 1).Run())
+
+	process.New("connectors.es.DeleteIndex", "pets").Run()
+	assert.Equal(t, false, process.New("connectors.es.IndexExists", "pets").Run())
+	assert.Panics(t, func() { process.New("connectors.es.Search", "pets").Run() })
+}
+
+// elasticsearchServer a stub server of the elasticsearch document apis keeps the documents in memory
+func elasticsearchServer() *httptest.Server {
+	mutex := sync.Mutex{}
+	indexes := map[string]map[string]interface{}{}
+	seq := 0
+	notFound := func(w http.ResponseWriter, typ string) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":{"type":"%s","reason":"not found"},"status":404}`, typ)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+
+		paths := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		name := paths[0]
+		docs, has := indexes[name]
+		if !has && !(len(paths) == 1 && r.Method == "PUT") {
+			notFound(w, "index_not_found_exception")
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		source := map[string]interface{}{}
+		if len(paths) == 1 || paths[1] != "_bulk" {
+			jsoniter.Unmarshal(body, &source)
+		}
+
+		switch {
+		case len(paths) == 1 && r.Method == "HEAD":
+		case len(paths) == 1 && r.Method == "PUT":
+			indexes[name] = map[string]interface{}{}
+			fmt.Fprintf(w, `{"acknowledged":true,"index":"%s"}`, name)
+		case len(paths) == 1 && r.Method == "DELETE":
+			delete(indexes, name)
+			fmt.Fprint(w, `{"acknowledged":true}`)
+		case paths[1] == "_refresh":
+			fmt.Fprint(w, `{"_shards":{"total":1,"successful":1,"failed":0}}`)
+		case paths[1] == "_search":
+			hits := []interface{}{}
+			for id, doc := range docs {
+				hits = append(hits, map[string]interface{}{"_id": id, "_source": doc})
+			}
+			data, _ := jsoniter.Marshal(map[string]interface{}{"hits": map[string]interface{}{"total": map[string]interface{}{"value": len(hits)}, "hits": hits}})
+			w.Write(data)
+		case paths[1] == "_bulk":
+			items := []interface{}{}
+			scanner := bufio.NewScanner(strings.NewReader(string(body)))
+			for scanner.Scan() {
+				action := map[string]map[string]interface{}{}
+				jsoniter.Unmarshal(scanner.Bytes(), &action)
+				for typ, meta := range action {
+					id := fmt.Sprintf("%v", meta["_id"])
+					if typ != "delete" {
+						scanner.Scan()
+						doc := map[string]interface{}{}
+						jsoniter.Unmarshal(scanner.Bytes(), &doc)
+						if typ == "update" {
+							doc, _ = doc["doc"].(map[string]interface{})
+						}
+						docs[id] = doc
+					} else {
+						delete(docs, id)
+					}
+					items = append(items, map[string]interface{}{typ: map[string]interface{}{"_id": id, "status": 200}})
+				}
+			}
+			data, _ := jsoniter.Marshal(map[string]interface{}{"errors": false, "items": items})
+			w.Write(data)
+		case paths[1] == "_doc" && r.Method == "POST":
+			seq++
+			id := fmt.Sprintf("auto-%d", seq)
+			docs[id] = source
+			fmt.Fprintf(w, `{"_id":"%s","result":"created"}`, id)
+		case paths[1] == "_doc" && r.Method == "PUT":
+			docs[paths[2]] = source
+			fmt.Fprintf(w, `{"_id":"%s","result":"created"}`, paths[2])
+		case paths[1] == "_doc" && r.Method == "GET":
+			doc, has := docs[paths[2]]
+			if !has {
+				notFound(w, "")
+				return
+			}
+			data, _ := jsoniter.Marshal(map[string]interface{}{"_id": paths[2], "found": true, "_source": doc})
+			w.Write(data)
+		case paths[1] == "_doc" && r.Method == "DELETE":
+			if _, has := docs[paths[2]]; !has {
+				notFound(w, "")
+				return
+			}
+			delete(docs, paths[2])
+			fmt.Fprintf(w, `{"_id":"%s","result":"deleted"}`, paths[2])
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"unsupported"}`)
+		}
+	}))
+}
+
 func prepare(t *testing.T, name string) string {
 	root := os.Getenv("GOU_TEST_APPLICATION")
 	app, err := application.OpenFromDisk(root) // Load app
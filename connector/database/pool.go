@@ -0,0 +1,270 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/capsule"
+)
+
+// XunPool the connection pool options of each host
+type XunPool struct {
+	MaxOpen     int `json:"maxOpen,omitempty"`     // the max number of the open connections, 0 means unlimited
+	MaxIdle     int `json:"maxIdle,omitempty"`     // the max number of the idle connections, default is 2
+	MaxLifetime int `json:"maxLifetime,omitempty"` // the max lifetime of a connection (seconds), 0 means forever
+	MaxIdleTime int `json:"maxIdleTime,omitempty"` // the max idle time of a connection (seconds), 0 means forever
+}
+
+// XunHealth the health check options
+type XunHealth struct {
+	Interval int `json:"interval,omitempty"` // the ping interval (seconds), default is 30, -1 means the health check is disabled
+	Timeout  int `json:"timeout,omitempty"`  // the ping timeout (seconds), default is options.timeout
+	Failures int `json:"failures,omitempty"` // the host is unhealthy after the consecutive failures, default is 1
+}
+
+// hostStatus the health metrics of a host
+type hostStatus struct {
+	healthy   bool
+	failures  int // the consecutive failures
+	pings     int64
+	errors    int64
+	latency   time.Duration
+	checkedAt time.Time
+	lastError string
+}
+
+// Check ping all the hosts, and fail over to the next healthy primary host if the active primary is unhealthy
+func (x *Xun) Check() {
+	for i := range x.Options.Hosts {
+		x.ping(i)
+	}
+	x.failover()
+}
+
+// Status the pool statistics and the health metrics of the hosts
+func (x *Xun) Status() map[string]interface{} {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	hosts := []map[string]interface{}{}
+	for i, host := range x.Options.Hosts {
+		addr := host.File
+		if host.Host != "" {
+			addr = fmt.Sprintf("%s:%s", host.Host, host.Port)
+		}
+
+		item := map[string]interface{}{
+			"index":   i,
+			"host":    addr,
+			"primary": host.Primary,
+			"active":  host.Primary && i == x.primary,
+			"healthy": true,
+		}
+
+		if i < len(x.status) {
+			st := x.status[i]
+			item["healthy"] = st.healthy
+			item["failures"] = st.failures
+			item["pings"] = st.pings
+			item["errors"] = st.errors
+			item["latency"] = st.latency.Milliseconds()
+			item["error"] = st.lastError
+			if !st.checkedAt.IsZero() {
+				item["checked_at"] = st.checkedAt.UnixMilli()
+			}
+		}
+
+		if conn, err := x.connection(i); err == nil {
+			stats := conn.DB.Stats()
+			item["pool"] = map[string]interface{}{
+				"max_open":             stats.MaxOpenConnections,
+				"open":                 stats.OpenConnections,
+				"in_use":               stats.InUse,
+				"idle":                 stats.Idle,
+				"wait_count":           stats.WaitCount,
+				"wait_duration":        stats.WaitDuration.Milliseconds(),
+				"max_idle_closed":      stats.MaxIdleClosed,
+				"max_idle_time_closed": stats.MaxIdleTimeClosed,
+				"max_lifetime_closed":  stats.MaxLifetimeClosed,
+			}
+		}
+		hosts = append(hosts, item)
+	}
+
+	return map[string]interface{}{
+		"id":        x.id,
+		"driver":    x.Driver,
+		"primary":   x.primary,
+		"failovers": atomic.LoadInt64(&x.failovers),
+		"hosts":     hosts,
+	}
+}
+
+// setPool apply the pool options to the connection
+func (x *Xun) setPool(conn *capsule.Connection) {
+	pool := x.Options.Pool
+	if pool.MaxOpen > 0 {
+		conn.DB.SetMaxOpenConns(pool.MaxOpen)
+	}
+
+	if pool.MaxIdle > 0 {
+		conn.DB.SetMaxIdleConns(pool.MaxIdle)
+	}
+
+	if pool.MaxLifetime > 0 {
+		conn.DB.SetConnMaxLifetime(time.Duration(pool.MaxLifetime) * time.Second)
+	}
+
+	if pool.MaxIdleTime > 0 {
+		conn.DB.SetConnMaxIdleTime(time.Duration(pool.MaxIdleTime) * time.Second)
+	}
+}
+
+// startMonitor reset the health metrics and ping the hosts periodically
+func (x *Xun) startMonitor() {
+	x.stopMonitor()
+
+	x.mutex.Lock()
+	x.status = make([]*hostStatus, len(x.Options.Hosts))
+	for i := range x.status {
+		x.status[i] = &hostStatus{healthy: true}
+	}
+
+	x.primary = -1
+	for i, host := range x.Options.Hosts {
+		if host.Primary {
+			x.primary = i
+			break
+		}
+	}
+	x.mutex.Unlock()
+
+	if x.Options.Health.Interval < 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	x.cancel = cancel
+	go func() {
+		ticker := time.NewTicker(time.Duration(x.Options.Health.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				x.Check()
+			}
+		}
+	}()
+}
+
+func (x *Xun) stopMonitor() {
+	if x.cancel != nil {
+		x.cancel()
+		x.cancel = nil
+	}
+}
+
+// ping the host and update the health metrics
+func (x *Xun) ping(i int) {
+	start := time.Now()
+	conn, err := x.connection(i)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(x.Options.Health.Timeout)*time.Second)
+		err = conn.DB.PingContext(ctx)
+		cancel()
+	}
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if i >= len(x.status) {
+		return
+	}
+
+	st := x.status[i]
+	st.pings++
+	st.latency = time.Since(start)
+	st.checkedAt = start
+	if err == nil {
+		if !st.healthy {
+			log.Info("[Connector] %s hosts.%d was recovered", x.id, i)
+		}
+		st.healthy = true
+		st.failures = 0
+		st.lastError = ""
+		return
+	}
+
+	st.errors++
+	st.failures++
+	st.lastError = err.Error()
+	if st.healthy && st.failures >= x.Options.Health.Failures {
+		st.healthy = false
+		log.Error("[Connector] %s hosts.%d is unhealthy (%s)", x.id, i, err.Error())
+	}
+}
+
+// failover switch the writes to the next healthy primary host if the active primary is unhealthy
+func (x *Xun) failover() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if x.primary < 0 || x.status[x.primary].healthy {
+		return
+	}
+
+	size := len(x.Options.Hosts)
+	for n := 1; n < size; n++ {
+		i := (x.primary + n) % size
+		if x.Options.Hosts[i].Primary && x.status[i].healthy {
+			log.Warn("[Connector] %s fail over the primary hosts.%d to hosts.%d", x.id, x.primary, i)
+			x.primary = i
+			atomic.AddInt64(&x.failovers, 1)
+			return
+		}
+	}
+}
+
+// writer the index of the active primary host, -1 if there is no primary host
+func (x *Xun) writer() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+	return x.primary
+}
+
+// reader the index of a healthy replica host in the round-robin order, -1 if there is no healthy replica
+func (x *Xun) reader() int {
+	x.mutex.RLock()
+	defer x.mutex.RUnlock()
+
+	replicas := []int{}
+	for i, host := range x.Options.Hosts {
+		if !host.Primary && (i >= len(x.status) || x.status[i].healthy) {
+			replicas = append(replicas, i)
+		}
+	}
+
+	if len(replicas) == 0 {
+		return -1
+	}
+	next := atomic.AddUint32(&x.next, 1)
+	return replicas[int(next)%len(replicas)]
+}
+
+// connection the connection of the host
+func (x *Xun) connection(i int) (*capsule.Connection, error) {
+	name := fmt.Sprintf("%s_%d", x.Name, i)
+	c, has := x.Manager.Connections.Load(name)
+	if !has {
+		return nil, fmt.Errorf("connection %s not load", name)
+	}
+
+	db, ok := c.(*capsule.Connection)
+	if !ok {
+		return nil, fmt.Errorf("connection %s type error %#v", name, c)
+	}
+	return db, nil
+}
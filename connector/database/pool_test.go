@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailover(t *testing.T) {
+	x := &Xun{id: "unit-failover", Options: XunOptions{
+		Health: XunHealth{Interval: -1},
+		Hosts: []XunHost{
+			{Host: "primary-0", Primary: true},
+			{Host: "replica-1"},
+			{Host: "primary-2", Primary: true},
+			{Host: "replica-3"},
+		},
+	}}
+	x.startMonitor()
+	assert.Equal(t, 0, x.writer())
+
+	// the active primary is healthy
+	x.failover()
+	assert.Equal(t, 0, x.writer())
+
+	// fail over to the next healthy primary
+	x.status[0].healthy = false
+	x.failover()
+	assert.Equal(t, 2, x.writer())
+	assert.Equal(t, int64(1), x.failovers)
+
+	// there is no healthy primary to fail over to
+	x.status[2].healthy = false
+	x.failover()
+	assert.Equal(t, 2, x.writer())
+	assert.Equal(t, int64(1), x.failovers)
+}
+
+func TestReader(t *testing.T) {
+	x := &Xun{id: "unit-reader", Options: XunOptions{
+		Health: XunHealth{Interval: -1},
+		Hosts: []XunHost{
+			{Host: "primary-0", Primary: true},
+			{Host: "replica-1"},
+			{Host: "replica-2"},
+		},
+	}}
+	x.startMonitor()
+
+	// round-robin the healthy replicas
+	reads := map[int]int{}
+	for i := 0; i < 4; i++ {
+		reads[x.reader()]++
+	}
+	assert.Equal(t, map[int]int{1: 2, 2: 2}, reads)
+
+	// skip the unhealthy replica
+	x.status[1].healthy = false
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, 2, x.reader())
+	}
+
+	// no healthy replica
+	x.status[2].healthy = false
+	assert.Equal(t, -1, x.reader())
+}
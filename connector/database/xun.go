@@ -1,10 +1,12 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/helper"
@@ -17,13 +19,19 @@ import (
 
 // Xun the xun database ORM
 type Xun struct {
-	id      string
-	file    string
-	Manager *capsule.Manager `json:"-"`
-	Name    string           `json:"name,omitempty"`
-	Driver  string           `json:"type"`
-	Version string           `json:"version,omitempty"`
-	Options XunOptions       `json:"options"`
+	id        string
+	file      string
+	mutex     sync.RWMutex
+	status    []*hostStatus
+	primary   int    // the index of the active primary host
+	next      uint32 // the round-robin counter of the replicas
+	failovers int64
+	cancel    context.CancelFunc
+	Manager   *capsule.Manager `json:"-"`
+	Name      string           `json:"name,omitempty"`
+	Driver    string           `json:"type"`
+	Version   string           `json:"version,omitempty"`
+	Options   XunOptions       `json:"options"`
 }
 
 // XunOptions the connetion options
@@ -36,9 +44,12 @@ type XunOptions struct {
 	Timeout     int       `json:"timeout,omitempty"`
 	File        string    `json:"file,omitempty"`
 	Hosts       []XunHost `json:"hosts"`
+	Pool        XunPool   `json:"pool,omitempty"`
+	Health      XunHealth `json:"health,omitempty"`
 }
 
-// XunHost the connection host
+// XunHost the connection host, the writes are sent to the active primary host and the reads are sent to the healthy replicas.
+// If there are more than one primary hosts, the others are the standby hosts of the failover.
 type XunHost struct {
 	File    string `json:"file,omitempty"`
 	Host    string `json:"host,omitempty"`
@@ -85,25 +96,27 @@ func (x *Xun) Query() (query.Query, error) {
 		return nil, fmt.Errorf("connection is empty")
 	}
 
-	conn := &query.Connection{Option: x.Manager.Option}
-	for i, host := range x.Options.Hosts {
-		name := fmt.Sprintf("%s_%d", x.Name, i)
-		c, has := x.Manager.Connections.Load(name)
-		if !has {
-			return nil, fmt.Errorf("connection %s not load", name)
-		}
+	write := x.writer()
+	read := x.reader()
+	if read < 0 {
+		read = write // read from the primary if there is no healthy replica
+	}
 
-		db, ok := c.(*capsule.Connection)
-		if !ok {
-			return nil, fmt.Errorf("connection %s type error %#v", name, c)
+	conn := &query.Connection{Option: x.Manager.Option}
+	if write >= 0 {
+		db, err := x.connection(write)
+		if err != nil {
+			return nil, err
 		}
+		conn.Write = &db.DB
+		conn.WriteConfig = db.Config
+	}
 
-		if host.Primary {
-			conn.Write = &db.DB
-			conn.WriteConfig = db.Config
-			continue
+	if read >= 0 {
+		db, err := x.connection(read)
+		if err != nil {
+			return nil, err
 		}
-
 		conn.Read = &db.DB
 		conn.ReadConfig = db.Config
 	}
@@ -123,6 +136,7 @@ func (x *Xun) Schema() (schema.Schema, error) {
 
 // Close connections
 func (x *Xun) Close() error {
+	x.stopMonitor()
 	return x.Manager.Close()
 }
 
@@ -152,6 +166,15 @@ func (x *Xun) makeConnections() (err error) {
 	}
 
 	x.Manager = manager
+	for i := range x.Options.Hosts {
+		conn, err := x.connection(i)
+		if err != nil {
+			return err
+		}
+		x.setPool(conn)
+	}
+
+	x.startMonitor()
 	return err
 }
 
@@ -162,6 +185,18 @@ func (x *Xun) setDefaults() error {
 		x.Options.Timeout = 5
 	}
 
+	if x.Options.Health.Interval == 0 {
+		x.Options.Health.Interval = 30
+	}
+
+	if x.Options.Health.Timeout <= 0 {
+		x.Options.Health.Timeout = x.Options.Timeout
+	}
+
+	if x.Options.Health.Failures <= 0 {
+		x.Options.Health.Failures = 1
+	}
+
 	// for sqlite3
 	if x.Options.File != "" {
 		x.Options.Hosts = append(x.Options.Hosts, XunHost{File: x.Options.File})
@@ -306,5 +341,7 @@ func (x *Xun) Setting() map[string]interface{} {
 		"timeout":   x.Options.Timeout,
 		"file":      x.Options.File,
 		"hosts":     x.Options.Hosts,
+		"pool":      x.Options.Pool,
+		"health":    x.Options.Health,
 	}
 }
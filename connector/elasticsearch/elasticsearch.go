@@ -0,0 +1,402 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// Connector the elasticsearch (opensearch) connector
+type Connector struct {
+	id      string
+	file    string
+	client  *http.Client
+	next    uint32  // the round-robin counter of the hosts
+	Name    string  `json:"name"`
+	Options Options `json:"options"`
+}
+
+// Options the elasticsearch connector option
+type Options struct {
+	Hosts    []string `json:"hosts,omitempty"`    // the node urls, e.g. http://127.0.0.1:9200
+	Host     string   `json:"host,omitempty"`     // the node url if there is only one node
+	User     string   `json:"user,omitempty"`     // the basic auth user
+	Pass     string   `json:"pass,omitempty"`     // the basic auth password
+	Key      string   `json:"key,omitempty"`      // the API key (base64 encoded id:api_key)
+	Prefix   string   `json:"prefix,omitempty"`   // the index name prefix
+	Timeout  int      `json:"timeout,omitempty"`  // seconds, default is 5
+	Insecure bool     `json:"insecure,omitempty"` // skip the TLS certificate verification
+	Sync     []Sync   `json:"sync,omitempty"`     // sync the model writes into the indexes
+}
+
+// Sync sync the model writes into the index
+type Sync struct {
+	Model   string   `json:"model"`
+	Index   string   `json:"index,omitempty"`   // the index name, default is the table name of the model
+	Columns []string `json:"columns,omitempty"` // the synced columns, the columns of the model except the encrypted ones if not given
+}
+
+// Error the elasticsearch error response
+type Error struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+// Register the connections from dsl
+func (es *Connector) Register(file string, id string, dsl []byte) error {
+
+	es.id = id
+	es.file = file
+
+	err := application.Parse(file, dsl, es)
+	if err != nil {
+		return err
+	}
+
+	err = es.setDefaults()
+	if err != nil {
+		return err
+	}
+
+	es.makeClient()
+	for _, sync := range es.Options.Sync {
+		model.AddSyncer(sync.Model, es.syncName(), &syncer{es: es, option: sync})
+	}
+	return nil
+}
+
+// Is the connections from dsl
+func (es *Connector) Is(typ int) bool {
+	return 4 == typ
+}
+
+// ID get connector id
+func (es *Connector) ID() string {
+	return es.id
+}
+
+// Query get connector query interface
+func (es *Connector) Query() (query.Query, error) {
+	return nil, nil
+}
+
+// Schema get connector schema interface
+func (es *Connector) Schema() (schema.Schema, error) {
+	return nil, nil
+}
+
+// Close connections
+func (es *Connector) Close() error {
+	for _, sync := range es.Options.Sync {
+		model.RemoveSyncer(sync.Model, es.syncName())
+	}
+
+	if es.client != nil {
+		es.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Setting get the connection setting
+func (es *Connector) Setting() map[string]interface{} {
+	return map[string]interface{}{
+		"hosts":   es.Options.Hosts,
+		"user":    es.Options.User,
+		"pass":    es.Options.Pass,
+		"key":     es.Options.Key,
+		"prefix":  es.Options.Prefix,
+		"timeout": es.Options.Timeout,
+	}
+}
+
+// CreateIndex create the index with the settings and mappings
+func (es *Connector) CreateIndex(index string, body map[string]interface{}) (map[string]interface{}, error) {
+	return es.Request("PUT", es.path(index), body)
+}
+
+// DeleteIndex delete the index
+func (es *Connector) DeleteIndex(index string) error {
+	_, err := es.Request("DELETE", es.path(index), nil)
+	return err
+}
+
+// IndexExists check if the index exists
+func (es *Connector) IndexExists(index string) (bool, error) {
+	_, err := es.Request("HEAD", es.path(index), nil)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Refresh refresh the index, the written documents are searchable after the refresh
+func (es *Connector) Refresh(index string) error {
+	_, err := es.Request("POST", es.path(index, "_refresh"), nil)
+	return err
+}
+
+// Index create or replace the document, the id is generated if it is empty
+func (es *Connector) Index(index string, id string, doc map[string]interface{}) (map[string]interface{}, error) {
+	if id == "" {
+		return es.Request("POST", es.path(index, "_doc"), doc)
+	}
+	return es.Request("PUT", es.path(index, "_doc", id), doc)
+}
+
+// Get the source of the document, nil if the document does not exist
+func (es *Connector) Get(index string, id string) (map[string]interface{}, error) {
+	res, err := es.Request("GET", es.path(index, "_doc", id), nil)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	source, _ := res["_source"].(map[string]interface{})
+	return source, nil
+}
+
+// Delete the document, false if the document does not exist
+func (es *Connector) Delete(index string, id string) (bool, error) {
+	_, err := es.Request("DELETE", es.path(index, "_doc", id), nil)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Bulk run the actions in one request, the action is {"action": "index|create|update|delete", "id": "1", "doc": {...}}
+func (es *Connector) Bulk(index string, actions []map[string]interface{}) (map[string]interface{}, error) {
+	body := bytes.NewBuffer(nil)
+	for i, action := range actions {
+		typ, _ := action["action"].(string)
+		if typ == "" {
+			typ = "index"
+		}
+
+		meta := map[string]interface{}{}
+		if id, has := action["id"]; has && id != nil {
+			meta["_id"] = fmt.Sprintf("%v", id)
+		}
+
+		line, err := jsoniter.Marshal(map[string]interface{}{typ: meta})
+		if err != nil {
+			return nil, err
+		}
+		body.Write(line)
+		body.WriteByte('\n')
+
+		switch typ {
+		case "delete":
+			continue
+
+		case "index", "create", "update":
+			doc, has := action["doc"]
+			if !has {
+				return nil, fmt.Errorf("actions.%d.doc is required", i)
+			}
+
+			if typ == "update" {
+				doc = map[string]interface{}{"doc": doc}
+			}
+
+			line, err = jsoniter.Marshal(doc)
+			if err != nil {
+				return nil, err
+			}
+			body.Write(line)
+			body.WriteByte('\n')
+
+		default:
+			return nil, fmt.Errorf("actions.%d.action %s does not support", i, typ)
+		}
+	}
+
+	return es.Request("POST", es.path(index, "_bulk"), body.Bytes())
+}
+
+// Search the documents by the query DSL
+func (es *Connector) Search(index string, query map[string]interface{}) (map[string]interface{}, error) {
+	return es.Request("POST", es.path(index, "_search"), query)
+}
+
+// Request send the request to the nodes in the round-robin order, the next node is tried if the node is unreachable.
+// the body is encoded as JSON, or sent as NDJSON if it is []byte
+func (es *Connector) Request(method string, path string, body interface{}) (map[string]interface{}, error) {
+
+	var err error
+	var data []byte
+	contentType := "application/json"
+	switch value := body.(type) {
+	case nil:
+	case []byte:
+		data = value
+		contentType = "application/x-ndjson"
+	case map[string]interface{}:
+		if value != nil {
+			data, err = jsoniter.Marshal(value)
+		}
+	default:
+		data, err = jsoniter.Marshal(value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(es.Options.Hosts); i++ {
+		host := es.Options.Hosts[int(atomic.AddUint32(&es.next, 1))%len(es.Options.Hosts)]
+		status, res, e := es.send(method, host+path, contentType, data)
+		if e != nil { // the node is unreachable
+			err = e
+			continue
+		}
+		return es.response(status, res)
+	}
+	return nil, err
+}
+
+// Error the error message
+func (err *Error) Error() string {
+	return fmt.Sprintf("elasticsearch %d %s %s", err.Status, err.Type, err.Reason)
+}
+
+// IsNotFound check if the error is the 404 response
+func IsNotFound(err error) bool {
+	if e, ok := err.(*Error); ok {
+		return e.Status == http.StatusNotFound
+	}
+	return false
+}
+
+func (es *Connector) send(method string, endpoint string, contentType string, data []byte) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(es.Options.Timeout)*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	if es.Options.Key != "" {
+		req.Header.Set("Authorization", "ApiKey "+es.Options.Key)
+	} else if es.Options.User != "" {
+		req.SetBasicAuth(es.Options.User, es.Options.Pass)
+	}
+
+	res, err := es.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, body, nil
+}
+
+// response parse the response body, the error responses are converted to *Error
+func (es *Connector) response(status int, body []byte) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if len(body) > 0 {
+		if err := jsoniter.Unmarshal(body, &data); err != nil && status < 400 {
+			return nil, fmt.Errorf("elasticsearch response %s", err.Error())
+		}
+	}
+
+	if status < 400 {
+		return data, nil
+	}
+
+	e := &Error{Status: status, Reason: http.StatusText(status)}
+	switch reason := data["error"].(type) {
+	case string:
+		e.Reason = reason
+	case map[string]interface{}:
+		e.Type, _ = reason["type"].(string)
+		if text, ok := reason["reason"].(string); ok {
+			e.Reason = text
+		}
+	}
+	return nil, e
+}
+
+func (es *Connector) setDefaults() error {
+	es.Options.Host = helper.EnvString(es.Options.Host)
+	if es.Options.Host != "" {
+		es.Options.Hosts = append(es.Options.Hosts, es.Options.Host)
+	}
+
+	if len(es.Options.Hosts) == 0 {
+		return fmt.Errorf("options.hosts is required")
+	}
+
+	for i, host := range es.Options.Hosts {
+		host = strings.TrimRight(helper.EnvString(host), "/")
+		if _, err := url.ParseRequestURI(host); err != nil {
+			return fmt.Errorf("options.hosts.%d %s", i, err.Error())
+		}
+		es.Options.Hosts[i] = host
+	}
+
+	es.Options.User = helper.EnvString(es.Options.User)
+	es.Options.Pass = helper.EnvString(es.Options.Pass)
+	es.Options.Key = helper.EnvString(es.Options.Key)
+	es.Options.Prefix = helper.EnvString(es.Options.Prefix)
+	if es.Options.Timeout <= 0 {
+		es.Options.Timeout = 5
+	}
+
+	for i, sync := range es.Options.Sync {
+		if sync.Model == "" {
+			return fmt.Errorf("options.sync.%d.model is required", i)
+		}
+	}
+	return nil
+}
+
+func (es *Connector) makeClient() {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if es.Options.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	es.client = &http.Client{Transport: transport}
+}
+
+// path the request path of the prefixed index, the path of the cluster api if the index is empty
+func (es *Connector) path(index string, paths ...string) string {
+	segments := []string{}
+	if index != "" {
+		segments = append(segments, url.PathEscape(es.Options.Prefix+index))
+	}
+
+	for _, path := range paths {
+		segments = append(segments, url.PathEscape(path))
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func (es *Connector) syncName() string {
+	return "connectors." + es.id
+}
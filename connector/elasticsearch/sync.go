@@ -0,0 +1,56 @@
+package elasticsearch
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/kun/maps"
+)
+
+// syncer index the written rows of the model
+type syncer struct {
+	es     *Connector
+	option Sync
+}
+
+// Save index the row
+func (s *syncer) Save(mod *model.Model, id interface{}, row maps.MapStr) error {
+	doc := map[string]interface{}{}
+	for _, name := range s.columns(mod) {
+		if value, has := row[name]; has {
+			doc[name] = value
+		}
+	}
+
+	_, err := s.es.Index(s.index(mod), fmt.Sprintf("%v", id), doc)
+	return err
+}
+
+// Remove delete the document of the row
+func (s *syncer) Remove(mod *model.Model, id interface{}) error {
+	_, err := s.es.Delete(s.index(mod), fmt.Sprintf("%v", id))
+	return err
+}
+
+// columns the synced columns, the columns of the model except the encrypted ones (e.g. the passwords) if not given
+func (s *syncer) columns(mod *model.Model) []string {
+	if len(s.option.Columns) > 0 {
+		return s.option.Columns
+	}
+
+	columns := []string{}
+	for _, column := range mod.MetaData.Columns {
+		if column.Crypt != "" {
+			continue
+		}
+		columns = append(columns, column.Name)
+	}
+	return columns
+}
+
+func (s *syncer) index(mod *model.Model) string {
+	if s.option.Index != "" {
+		return s.option.Index
+	}
+	return mod.MetaData.Table.Name
+}
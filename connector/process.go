@@ -0,0 +1,190 @@
+package connector
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/connector/elasticsearch"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+// ConnectorHandlers connector process handlers
+var ConnectorHandlers = map[string]process.Handler{
+	"status":      processStatus,
+	"createindex": processCreateIndex,
+	"deleteindex": processDeleteIndex,
+	"indexexists": processIndexExists,
+	"refresh":     processRefresh,
+	"index":       processIndex,
+	"get":         processGet,
+	"delete":      processDelete,
+	"bulk":        processBulk,
+	"search":      processSearch,
+}
+
+func init() {
+	process.RegisterGroup("connectors", ConnectorHandlers)
+}
+
+// processStatus connectors.<id>.Status the pool statistics and the health metrics of the connector
+func processStatus(process *process.Process) interface{} {
+	c, err := Select(process.ID)
+	if err != nil {
+		exception.New("%s", 404, err.Error()).Throw()
+	}
+
+	monitor, ok := c.(Monitor)
+	if !ok {
+		exception.New("connector %s does not support the status", 400, process.ID).Throw()
+	}
+	return monitor.Status()
+}
+
+// processCreateIndex connectors.<id>.CreateIndex(index, body) create the index with the settings and mappings
+func processCreateIndex(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	es := selectElasticsearch(process)
+
+	var body map[string]interface{}
+	if process.NumOfArgs() > 1 && process.Args[1] != nil {
+		body = process.ArgsMap(1)
+	}
+
+	res, err := es.CreateIndex(process.ArgsString(0), body)
+	if err != nil {
+		throw(process, err)
+	}
+	return res
+}
+
+// processDeleteIndex connectors.<id>.DeleteIndex(index)
+func processDeleteIndex(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	err := selectElasticsearch(process).DeleteIndex(process.ArgsString(0))
+	if err != nil {
+		throw(process, err)
+	}
+	return nil
+}
+
+// processIndexExists connectors.<id>.IndexExists(index)
+func processIndexExists(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	exists, err := selectElasticsearch(process).IndexExists(process.ArgsString(0))
+	if err != nil {
+		throw(process, err)
+	}
+	return exists
+}
+
+// processRefresh connectors.<id>.Refresh(index) the written documents are searchable after the refresh
+func processRefresh(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	err := selectElasticsearch(process).Refresh(process.ArgsString(0))
+	if err != nil {
+		throw(process, err)
+	}
+	return nil
+}
+
+// processIndex connectors.<id>.Index(index, id, doc) create or replace the document, the id is generated if it is null
+func processIndex(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	id := ""
+	if process.Args[1] != nil {
+		id = fmt.Sprintf("%v", process.Args[1])
+	}
+
+	res, err := selectElasticsearch(process).Index(process.ArgsString(0), id, process.ArgsMap(2))
+	if err != nil {
+		throw(process, err)
+	}
+	return res
+}
+
+// processGet connectors.<id>.Get(index, id) the source of the document, null if the document does not exist
+func processGet(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	doc, err := selectElasticsearch(process).Get(process.ArgsString(0), fmt.Sprintf("%v", process.Args[1]))
+	if err != nil {
+		throw(process, err)
+	}
+
+	if doc == nil {
+		return nil
+	}
+	return doc
+}
+
+// processDelete connectors.<id>.Delete(index, id) false if the document does not exist
+func processDelete(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	deleted, err := selectElasticsearch(process).Delete(process.ArgsString(0), fmt.Sprintf("%v", process.Args[1]))
+	if err != nil {
+		throw(process, err)
+	}
+	return deleted
+}
+
+// processBulk connectors.<id>.Bulk(index, actions) the action is {"action": "index|create|update|delete", "id": "1", "doc": {...}}
+func processBulk(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	actions := []map[string]interface{}{}
+	switch values := process.Args[1].(type) {
+	case []map[string]interface{}:
+		actions = values
+	case []interface{}:
+		for i, value := range values {
+			action, ok := value.(map[string]interface{})
+			if !ok {
+				exception.New("actions.%d is not an object", 400, i).Throw()
+			}
+			actions = append(actions, action)
+		}
+	default:
+		exception.New("actions is not an array", 400).Throw()
+	}
+
+	res, err := selectElasticsearch(process).Bulk(process.ArgsString(0), actions)
+	if err != nil {
+		throw(process, err)
+	}
+	return res
+}
+
+// processSearch connectors.<id>.Search(index, query) search the documents by the query DSL
+func processSearch(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	var query map[string]interface{}
+	if process.NumOfArgs() > 1 && process.Args[1] != nil {
+		query = process.ArgsMap(1)
+	}
+
+	res, err := selectElasticsearch(process).Search(process.ArgsString(0), query)
+	if err != nil {
+		throw(process, err)
+	}
+	return res
+}
+
+func selectElasticsearch(process *process.Process) *elasticsearch.Connector {
+	c, err := Select(process.ID)
+	if err != nil {
+		exception.New("%s", 404, err.Error()).Throw()
+	}
+
+	es, ok := c.(*elasticsearch.Connector)
+	if !ok {
+		exception.New("connector %s is not an elasticsearch connector", 400, process.ID).Throw()
+	}
+	return es
+}
+
+// throw the error of the elasticsearch request, the status of the response is the code of the exception
+func throw(process *process.Process, err error) {
+	code := 500
+	if e, ok := err.(*elasticsearch.Error); ok {
+		code = e.Status
+	}
+	exception.New("%s %s", code, process.Name, err.Error()).Throw()
+}
@@ -58,6 +58,11 @@ type Connector interface {
 	Setting() map[string]interface{}
 }
 
+// Monitor the connector reports the pool statistics and the health metrics
+type Monitor interface {
+	Status() map[string]interface{}
+}
+
 // DSL the connector DSL
 type DSL struct {
 	ID      string                 `json:"-"`
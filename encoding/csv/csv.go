@@ -0,0 +1,153 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
+)
+
+// ProcessEncode csv Encode
+func ProcessEncode(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	res, err := Encode(process.Args[0])
+	if err != nil {
+		exception.New("CSV encode error: %s", 500, err).Throw()
+	}
+	return string(res)
+}
+
+// ProcessDecode csv Decode
+func ProcessDecode(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	res, err := Decode(process.ArgsString(0))
+	if err != nil {
+		exception.New("CSV decode error: %s", 500, err).Throw()
+	}
+	return res
+}
+
+// Encode encode the rows to CSV, the header is the sorted keys of the rows.
+// The value could be a list of rows, a row, or a map with the rows in the data field (the paginate result)
+func Encode(v interface{}) ([]byte, error) {
+
+	rows, err := toRows(v)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{}
+	has := map[string]bool{}
+	for _, row := range rows {
+		for key := range row {
+			if !has[key] {
+				has[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	err = writer.Write(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = cell(row[column])
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// Decode decode the CSV to rows, the first line is the header
+func Decode(data string) ([]map[string]interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]interface{}{}
+	if len(records) == 0 {
+		return rows, nil
+	}
+
+	columns := records[0]
+	for _, record := range records[1:] {
+		row := map[string]interface{}{}
+		for i, column := range columns {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func toRows(v interface{}) ([]map[string]interface{}, error) {
+
+	// normalize the value
+	bytes, err := jsoniter.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	err = jsoniter.Unmarshal(bytes, &value)
+	if err != nil {
+		return nil, err
+	}
+
+	switch data := value.(type) {
+	case []interface{}:
+		rows := []map[string]interface{}{}
+		for _, item := range data {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				row = map[string]interface{}{"value": item}
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+
+	case map[string]interface{}:
+		if items, ok := data["data"].([]interface{}); ok {
+			return toRows(items)
+		}
+		return []map[string]interface{}{data}, nil
+
+	case nil:
+		return []map[string]interface{}{}, nil
+	}
+
+	return []map[string]interface{}{{"value": value}}, nil
+}
+
+func cell(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case map[string]interface{}, []interface{}:
+		bytes, _ := jsoniter.Marshal(value)
+		return string(bytes)
+	}
+	return fmt.Sprintf("%v", v)
+}
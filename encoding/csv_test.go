@@ -0,0 +1,28 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/process"
+)
+
+func TestCSVEncode(t *testing.T) {
+	data := []interface{}{
+		map[string]interface{}{"id": 1, "name": "foo"},
+		map[string]interface{}{"id": 2, "name": "bar, baz"},
+	}
+	res, err := process.New("encoding.csv.Encode", data).Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "id,name\n1,foo\n2,\"bar, baz\"\n", res)
+}
+
+func TestCSVDecode(t *testing.T) {
+	res, err := process.New("encoding.csv.Decode", "id,name\n1,foo\n2,\"bar, baz\"\n").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []map[string]interface{}{{"id": "1", "name": "foo"}, {"id": "2", "name": "bar, baz"}}, res)
+}
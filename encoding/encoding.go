@@ -2,6 +2,7 @@ package encoding
 
 import (
 	"github.com/yaoapp/gou/encoding/base64"
+	"github.com/yaoapp/gou/encoding/csv"
 	"github.com/yaoapp/gou/encoding/hex"
 	"github.com/yaoapp/gou/encoding/json"
 	"github.com/yaoapp/gou/encoding/xml"
@@ -20,4 +21,6 @@ func init() {
 	process.Register("encoding.yaml.Decode", yaml.ProcessDecode)
 	process.Register("encoding.xml.Encode", xml.ProcessEncode)
 	process.Register("encoding.xml.Decode", xml.ProcessDecode)
+	process.Register("encoding.csv.Encode", csv.ProcessEncode)
+	process.Register("encoding.csv.Decode", csv.ProcessDecode)
 }
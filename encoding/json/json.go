@@ -27,3 +27,8 @@ func ProcessDecode(process *process.Process) interface{} {
 	}
 	return res
 }
+
+// Encode encode the value to JSON
+func Encode(v interface{}) ([]byte, error) {
+	return jsoniter.Marshal(v)
+}
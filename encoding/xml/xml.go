@@ -1,8 +1,10 @@
 package xml
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/yaoapp/gou/process"
@@ -57,3 +59,69 @@ func xmlToMap(data string) (map[string]interface{}, error) {
 	}
 	return result, nil
 }
+
+// Encode encode the value to XML, the maps are encoded as elements and the slices as repeated <item> elements
+func Encode(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := xml.NewEncoder(buf)
+	encoder.Indent("", "  ")
+	err := encodeElement(encoder, "xml", v)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeElement(encoder *xml.Encoder, name string, v interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	err := encoder.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			err = encodeElement(encoder, key, value[key])
+			if err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for _, item := range value {
+			err = encodeElement(encoder, "item", item)
+			if err != nil {
+				return err
+			}
+		}
+
+	case []map[string]interface{}:
+		for _, item := range value {
+			err = encodeElement(encoder, "item", item)
+			if err != nil {
+				return err
+			}
+		}
+
+	case nil:
+
+	default:
+		err = encoder.EncodeToken(xml.CharData(fmt.Sprintf("%v", value)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
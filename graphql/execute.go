@@ -0,0 +1,727 @@
+package graphql
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/model"
+	"github.com/yaoapp/kun/exception"
+	"github.com/yaoapp/kun/maps"
+)
+
+// MaxDepth the max depth of the selected fields, the root fields are at the depth 1
+var MaxDepth = 10
+
+// MaxFields the max number of the selected fields after the fragments are expanded
+var MaxFields = 1000
+
+// MaxPageSize the max pagesize of the paginate queries and the max (and the default) limit of the lists
+var MaxPageSize = 100
+
+type execution struct {
+	schema    *Schema
+	doc       *Document
+	variables map[string]interface{}
+	errors    []Error
+}
+
+// object the result object keeping the order of the selection
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// Execute execute the GraphQL request
+func (schema *Schema) Execute(req Request) *Response {
+
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	operation, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	err = doc.validate(operation)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	exec := &execution{schema: schema, doc: doc, variables: map[string]interface{}{}}
+	for _, variable := range operation.Variables {
+		value, has := req.Variables[variable.Name]
+		if !has && variable.HasDefault {
+			value, has = variable.Default, true
+		}
+		if (!has || value == nil) && strings.HasSuffix(variable.Type, "!") {
+			return &Response{Errors: []Error{{Message: fmt.Sprintf("variable $%s of required type %s was not provided", variable.Name, variable.Type)}}}
+		}
+		exec.variables[variable.Name] = value
+	}
+
+	roots := schema.Query
+	typename := "Query"
+	switch operation.Type {
+	case "mutation":
+		roots = schema.Mutation
+		typename = "Mutation"
+	case "subscription":
+		return &Response{Errors: []Error{{Message: "subscriptions are not supported"}}}
+	}
+
+	data := newObject()
+	fields, err := exec.collect(operation.Selections, typename)
+	if err != nil {
+		return &Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	for _, field := range fields {
+		key := field.key()
+		if field.Name == "__typename" {
+			data.set(key, typename)
+			continue
+		}
+
+		root, has := roots[field.Name]
+		if !has {
+			exec.errors = append(exec.errors, Error{Message: fmt.Sprintf("cannot query field %s on type %s", field.Name, typename), Path: []interface{}{key}})
+			data.set(key, nil)
+			continue
+		}
+
+		value, err := exec.resolve(root, field)
+		if err != nil {
+			exec.errors = append(exec.errors, exec.errorOf(err, key))
+			data.set(key, nil)
+			continue
+		}
+		data.set(key, value)
+	}
+
+	return &Response{Data: data, Errors: exec.errors}
+}
+
+// resolve resolve the root field
+func (exec *execution) resolve(root *Root, field *Selection) (value interface{}, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			switch ex := r.(type) {
+			case exception.Exception:
+				err = fieldError{Code: ex.Code, Message: ex.Message}
+			case *exception.Exception:
+				err = fieldError{Code: ex.Code, Message: ex.Message}
+			case error:
+				err = ex
+			default:
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	args := exec.arguments(field.Arguments)
+	typ := root.Type
+	mod := typ.Model
+
+	switch root.Action {
+	case "find":
+		param, err := exec.param(typ, field.Selections, args)
+		if err != nil {
+			return nil, err
+		}
+		row, err := mod.Find(args["id"], param)
+		if err != nil {
+			return nil, err
+		}
+		return exec.project(typ, field.Selections, helper.Normalize(row))
+
+	case "list":
+		param, err := exec.param(typ, field.Selections, args)
+		if err != nil {
+			return nil, err
+		}
+		if param.Limit == 0 {
+			param.Limit = MaxPageSize
+		}
+		rows, err := mod.Get(param)
+		if err != nil {
+			return nil, err
+		}
+		return exec.projectList(typ, field.Selections, helper.Normalize(rows))
+
+	case "paginate":
+		return exec.paginate(typ, field, args)
+
+	case "create", "update", "save":
+		row, ok := args["data"].(map[string]interface{})
+		if !ok {
+			return nil, fieldError{Code: 400, Message: fmt.Sprintf("the argument data of %s is required", root.Name)}
+		}
+
+		id := args["id"]
+		switch root.Action {
+		case "create":
+			id, err = mod.Create(maps.MapStrAny(row))
+		case "update":
+			err = mod.Update(id, maps.MapStrAny(row))
+		case "save":
+			id, err = mod.Save(maps.MapStrAny(row))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(field.Selections) == 0 {
+			return nil, fieldError{Code: 400, Message: fmt.Sprintf("the field %s of type %s must have a selection of subfields", root.Name, typ.Name)}
+		}
+
+		param, err := exec.param(typ, field.Selections, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+		saved, err := mod.Find(id, param)
+		if err != nil {
+			return nil, err
+		}
+		return exec.project(typ, field.Selections, helper.Normalize(saved))
+
+	case "delete":
+		err = mod.Delete(args["id"])
+		if err != nil {
+			return nil, err
+		}
+		return true, nil
+	}
+
+	return nil, fmt.Errorf("the action %s of %s is not supported", root.Action, root.Name)
+}
+
+func (exec *execution) paginate(typ *Type, field *Selection, args map[string]interface{}) (interface{}, error) {
+
+	fields, err := exec.collect(field.Selections, typ.Name+"Paginate")
+	if err != nil {
+		return nil, err
+	}
+
+	// the data selection drives the query
+	var data *Selection
+	for _, f := range fields {
+		if f.Name == "data" {
+			data = f
+			break
+		}
+	}
+
+	selections := []*Selection{}
+	if data != nil {
+		selections = data.Selections
+	}
+
+	param, err := exec.param(typ, selections, args)
+	if err != nil {
+		return nil, err
+	}
+
+	page := toInt(args["page"], 1)
+	pagesize := toInt(args["pagesize"], 20)
+	if pagesize > MaxPageSize {
+		return nil, fieldError{Code: 400, Message: fmt.Sprintf("the pagesize must be less than or equal to %d", MaxPageSize)}
+	}
+	res, err := typ.Model.Paginate(param, page, pagesize)
+	if err != nil {
+		return nil, err
+	}
+
+	result, _ := helper.Normalize(res).(map[string]interface{})
+	obj := newObject()
+	for _, f := range fields {
+		switch f.Name {
+		case "__typename":
+			obj.set(f.key(), typ.Name+"Paginate")
+		case "data":
+			rows, err := exec.projectList(typ, f.Selections, result["data"])
+			if err != nil {
+				return nil, err
+			}
+			obj.set(f.key(), rows)
+		case "total", "page", "pagesize", "pagecnt", "next", "prev":
+			obj.set(f.key(), result[f.Name])
+		default:
+			return nil, fmt.Errorf("cannot query field %s on type %sPaginate", f.Name, typ.Name)
+		}
+	}
+	return obj, nil
+}
+
+// param build the query param of the selection, the relation fields are queried as withs
+func (exec *execution) param(typ *Type, selections []*Selection, args map[string]interface{}) (model.QueryParam, error) {
+
+	param := model.QueryParam{Select: []interface{}{}}
+	fields, err := exec.collect(selections, typ.Name)
+	if err != nil {
+		return param, err
+	}
+
+	selected := map[string]bool{}
+	if pk := typ.Model.PrimaryKey; pk != "" {
+		param.Select = append(param.Select, pk)
+		selected[pk] = true
+	}
+
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			continue
+		}
+
+		field, has := typ.Fields[f.Name]
+		if !has {
+			return param, fmt.Errorf("cannot query field %s on type %s", f.Name, typ.Name)
+		}
+
+		if field.Relation == "" {
+			if !selected[field.Name] {
+				param.Select = append(param.Select, field.Name)
+				selected[field.Name] = true
+			}
+			continue
+		}
+
+		if len(f.Selections) == 0 {
+			return param, fmt.Errorf("the field %s of type %s must have a selection of subfields", f.Name, typ.Name)
+		}
+
+		if _, has := param.Withs[field.Relation]; has {
+			continue
+		}
+
+		sub, err := exec.param(exec.schema.Types[field.Type], f.Selections, exec.arguments(f.Arguments))
+		if err != nil {
+			return param, err
+		}
+
+		if param.Withs == nil {
+			param.Withs = map[string]model.With{}
+		}
+		param.Withs[field.Relation] = model.With{Name: field.Relation, Query: sub}
+	}
+
+	if wheres, has := args["wheres"]; has && wheres != nil {
+		if err := convert(wheres, &param.Wheres); err != nil {
+			return param, fmt.Errorf("invalid argument wheres: %s", err.Error())
+		}
+	}
+
+	if where, ok := args["where"].(map[string]interface{}); ok {
+		for _, column := range sortedKeys(where) {
+			param.Wheres = append(param.Wheres, model.QueryWhere{Column: column, Value: where[column]})
+		}
+	}
+
+	if err := exec.filterable(typ, param.Wheres); err != nil {
+		return param, err
+	}
+
+	if orders, has := args["orders"]; has && orders != nil {
+		if err := convert(orders, &param.Orders); err != nil {
+			return param, fmt.Errorf("invalid argument orders: %s", err.Error())
+		}
+	}
+
+	for _, order := range param.Orders {
+		if !exec.exposed(typ, order.Rel, order.Column) {
+			return param, fieldError{Code: 400, Message: fmt.Sprintf("cannot order by the field %s of type %s", order.Column, typ.Name)}
+		}
+	}
+
+	if limit := toInt(args["limit"], 0); limit > 0 {
+		if limit > MaxPageSize {
+			return param, fieldError{Code: 400, Message: fmt.Sprintf("the limit must be less than or equal to %d", MaxPageSize)}
+		}
+		param.Limit = limit
+	}
+
+	return param, nil
+}
+
+// filterable the conditions can only use the fields of the type (or the related types)
+func (exec *execution) filterable(typ *Type, wheres []model.QueryWhere) error {
+	for _, where := range wheres {
+		if where.Column == nil && len(where.Wheres) > 0 { // the group
+			if err := exec.filterable(typ, where.Wheres); err != nil {
+				return err
+			}
+			continue
+		}
+
+		column, _ := where.Column.(string)
+		if !exec.exposed(typ, where.Rel, column) {
+			return fieldError{Code: 400, Message: fmt.Sprintf("cannot filter by the field %v of type %s", where.Column, typ.Name)}
+		}
+
+		if err := exec.filterable(typ, where.Wheres); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exposed check if the column is a field of the type, or a field of the related type if the relation is given
+func (exec *execution) exposed(typ *Type, rel string, column string) bool {
+	if rel != "" {
+		field, has := typ.Fields[rel]
+		if !has || field.Relation == "" {
+			return false
+		}
+		typ = exec.schema.Types[field.Type]
+	}
+
+	field, has := typ.Fields[column]
+	return has && field.Relation == ""
+}
+
+// project pick the selected fields of the row
+func (exec *execution) project(typ *Type, selections []*Selection, value interface{}) (interface{}, error) {
+	row, ok := value.(map[string]interface{})
+	if !ok || row == nil {
+		return nil, nil
+	}
+
+	fields, err := exec.collect(selections, typ.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := newObject()
+	for _, f := range fields {
+		if f.Name == "__typename" {
+			obj.set(f.key(), typ.Name)
+			continue
+		}
+
+		field, has := typ.Fields[f.Name]
+		if !has {
+			return nil, fmt.Errorf("cannot query field %s on type %s", f.Name, typ.Name)
+		}
+
+		if field.Relation == "" {
+			obj.set(f.key(), row[field.Name])
+			continue
+		}
+
+		target := exec.schema.Types[field.Type]
+		var value interface{}
+		if field.List {
+			value, err = exec.projectList(target, f.Selections, row[field.Relation])
+		} else {
+			value, err = exec.project(target, f.Selections, row[field.Relation])
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj.set(f.key(), value)
+	}
+	return obj, nil
+}
+
+func (exec *execution) projectList(typ *Type, selections []*Selection, value interface{}) (interface{}, error) {
+	rows, _ := value.([]interface{})
+	list := []interface{}{}
+	for _, row := range rows {
+		item, err := exec.project(typ, selections, row)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// collect expand the fragments and apply the @include and @skip directives
+func (exec *execution) collect(selections []*Selection, typename string) ([]*Selection, error) {
+	fields := []*Selection{}
+	for _, selection := range selections {
+		include, err := exec.included(selection.Directives)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+
+		if selection.Fragment != "" {
+			fragment, has := exec.doc.Fragments[selection.Fragment]
+			if !has {
+				return nil, fmt.Errorf("unknown fragment %s", selection.Fragment)
+			}
+			if fragment.On != typename {
+				continue
+			}
+			sub, err := exec.collect(fragment.Selections, typename)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, sub...)
+			continue
+		}
+
+		if selection.Inline {
+			if selection.On != "" && selection.On != typename {
+				continue
+			}
+			sub, err := exec.collect(selection.Selections, typename)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, sub...)
+			continue
+		}
+
+		fields = append(fields, selection)
+	}
+	return fields, nil
+}
+
+func (exec *execution) included(directives []*Directive) (bool, error) {
+	for _, directive := range directives {
+		if directive.Name != "include" && directive.Name != "skip" {
+			continue
+		}
+
+		value, ok := exec.value(directive.Arguments["if"]).(bool)
+		if !ok {
+			return false, fmt.Errorf("the argument if of @%s must be a boolean", directive.Name)
+		}
+
+		if (directive.Name == "include" && !value) || (directive.Name == "skip" && value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// arguments resolve the variables of the arguments
+func (exec *execution) arguments(args map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{}
+	for name, arg := range args {
+		values[name] = exec.value(arg)
+	}
+	return values
+}
+
+func (exec *execution) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case VariableRef:
+		return exec.variables[string(v)]
+	case Enum:
+		return string(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = exec.value(item)
+		}
+		return list
+	case map[string]interface{}:
+		return exec.arguments(v)
+	}
+	return value
+}
+
+func (exec *execution) errorOf(err error, key string) Error {
+	e := Error{Message: err.Error(), Path: []interface{}{key}}
+	if ex, ok := err.(fieldError); ok {
+		e.Extensions = map[string]interface{}{"code": ex.Code}
+	}
+	return e
+}
+
+// validate reject the fragment cycles and the selections are too deep or too large before the operation is executed
+func (doc *Document) validate(operation *Operation) error {
+	checked := map[string]bool{}
+	for name := range doc.Fragments {
+		if err := doc.acyclic(name, map[string]bool{}, checked); err != nil {
+			return err
+		}
+	}
+
+	depth, size := doc.measure(operation.Selections, map[string][2]int{})
+	if depth > MaxDepth {
+		return fmt.Errorf("the selection is too deep (%d > %d)", depth, MaxDepth)
+	}
+
+	if size > MaxFields {
+		return fmt.Errorf("the selection has too many fields (%d > %d)", size, MaxFields)
+	}
+	return nil
+}
+
+// acyclic check the fragment does not spread itself directly or indirectly
+func (doc *Document) acyclic(name string, visiting map[string]bool, checked map[string]bool) error {
+	if checked[name] {
+		return nil
+	}
+
+	if visiting[name] {
+		return fmt.Errorf("the fragment %s spreads itself", name)
+	}
+
+	fragment, has := doc.Fragments[name]
+	if !has {
+		return fmt.Errorf("unknown fragment %s", name)
+	}
+
+	visiting[name] = true
+	for _, spread := range spreads(fragment.Selections) {
+		if err := doc.acyclic(spread, visiting, checked); err != nil {
+			return err
+		}
+	}
+	delete(visiting, name)
+	checked[name] = true
+	return nil
+}
+
+// measure the depth and the number of the fields of the selections, the fragments are expanded (measured once)
+func (doc *Document) measure(selections []*Selection, fragments map[string][2]int) (int, int) {
+	depth, size := 0, 0
+	for _, selection := range selections {
+		d, n := 0, 0
+		switch {
+		case selection.Fragment != "":
+			measured, has := fragments[selection.Fragment]
+			if !has {
+				if fragment, has := doc.Fragments[selection.Fragment]; has {
+					measured[0], measured[1] = doc.measure(fragment.Selections, fragments)
+				}
+				fragments[selection.Fragment] = measured
+			}
+			d, n = measured[0], measured[1]
+
+		case selection.Inline:
+			d, n = doc.measure(selection.Selections, fragments)
+
+		default:
+			d, n = doc.measure(selection.Selections, fragments)
+			d, n = d+1, n+1
+		}
+
+		if d > depth {
+			depth = d
+		}
+		size += n
+	}
+	return depth, size
+}
+
+// spreads the names of the fragments spread in the selections
+func spreads(selections []*Selection) []string {
+	names := []string{}
+	for _, selection := range selections {
+		if selection.Fragment != "" {
+			names = append(names, selection.Fragment)
+			continue
+		}
+		names = append(names, spreads(selection.Selections)...)
+	}
+	return names
+}
+
+// operation select the operation by the name
+func (doc *Document) operation(name string) (*Operation, error) {
+	if name == "" {
+		if len(doc.Operations) > 1 {
+			return nil, fmt.Errorf("the operation name is required when the document contains multiple operations")
+		}
+		return doc.Operations[0], nil
+	}
+
+	for _, operation := range doc.Operations {
+		if operation.Name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+func (selection *Selection) key() string {
+	if selection.Alias != "" {
+		return selection.Alias
+	}
+	return selection.Name
+}
+
+func newObject() *object {
+	return &object{keys: []string{}, values: map[string]interface{}{}}
+}
+
+func (obj *object) set(key string, value interface{}) {
+	if _, has := obj.values[key]; !has {
+		obj.keys = append(obj.keys, key)
+	}
+	obj.values[key] = value
+}
+
+// MarshalJSON encode the object in the order of the selection
+func (obj *object) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, key := range obj.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := jsoniter.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsoniter.Marshal(obj.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// normalize convert the query result to the plain maps and slices
+func convert(value interface{}, v interface{}) error {
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return jsoniter.Unmarshal(bytes, v)
+}
+
+// toInt the integer argument, the defaults if it is not given or not an integer
+func toInt(value interface{}, defaults int) int {
+	v, err := helper.ToInt(value)
+	if err != nil {
+		return defaults
+	}
+	return v
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldError the error of the field with the code, the exceptions are recovered as the field errors
+type fieldError struct {
+	Code    int
+	Message string
+}
+
+func (err fieldError) Error() string {
+	return err.Message
+}
@@ -0,0 +1,84 @@
+package graphql
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/api"
+	"github.com/yaoapp/kun/log"
+)
+
+// SetRoutes register the GraphQL endpoint of the models, GET ?sdl returns the schema definition
+func SetRoutes(router gin.IRoutes, option Option) (*Schema, error) {
+
+	if option.Guard == "" {
+		return nil, fmt.Errorf("the guard is required, \"-\" means no guard")
+	}
+
+	schema, err := NewSchema(option.Models, option.Readonly)
+	if err != nil {
+		log.Error("%s", err.Error())
+		return nil, err
+	}
+
+	path := option.Path
+	if path == "" {
+		path = "/graphql"
+	}
+
+	handlers := append(api.Guards(option.Guard), schema.Handler())
+	router.GET(path, handlers...)
+	router.POST(path, handlers...)
+	return schema, nil
+}
+
+// Handler the gin handler of the schema
+func (schema *Schema) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		if _, has := c.GetQuery("sdl"); has && c.Request.Method == http.MethodGet {
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(schema.SDL()))
+			return
+		}
+
+		req := Request{}
+		if c.Request.Method == http.MethodGet {
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if variables := c.Query("variables"); variables != "" {
+				if err := jsoniter.UnmarshalFromString(variables, &req.Variables); err != nil {
+					c.JSON(http.StatusBadRequest, Response{Errors: []Error{{Message: "invalid variables: " + err.Error()}}})
+					return
+				}
+			}
+		} else if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{Errors: []Error{{Message: "invalid request: " + err.Error()}}})
+			return
+		}
+
+		if req.Query == "" {
+			c.JSON(http.StatusBadRequest, Response{Errors: []Error{{Message: "the query is required"}}})
+			return
+		}
+
+		// mutations are not allowed over GET
+		if c.Request.Method == http.MethodGet {
+			if doc, err := Parse(req.Query); err == nil {
+				if operation, err := doc.operation(req.OperationName); err == nil && operation.Type == "mutation" {
+					c.JSON(http.StatusMethodNotAllowed, Response{Errors: []Error{{Message: "mutations are only allowed over POST"}}})
+					return
+				}
+			}
+		}
+
+		res := schema.Execute(req)
+
+		if res.Data == nil {
+			c.JSON(http.StatusBadRequest, res)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
@@ -0,0 +1,230 @@
+package graphql
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/model"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# list the users
+		query Users($status: String = "enabled", $withPets: Boolean!) {
+			users: userList(where: {status: $status}, orders: [{column: "id", option: desc}], limit: 10) {
+				...userFields
+				pets(limit: 2) @include(if: $withPets) { id name }
+				... on User { extra: name }
+			}
+		}
+		fragment userFields on User { id name }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, doc.Operations, 1)
+	operation := doc.Operations[0]
+	assert.Equal(t, "query", operation.Type)
+	assert.Equal(t, "Users", operation.Name)
+	assert.Equal(t, "String", operation.Variables[0].Type)
+	assert.Equal(t, "enabled", operation.Variables[0].Default)
+	assert.Equal(t, "Boolean!", operation.Variables[1].Type)
+
+	users := operation.Selections[0]
+	assert.Equal(t, "users", users.Alias)
+	assert.Equal(t, "userList", users.Name)
+	assert.Equal(t, map[string]interface{}{"status": VariableRef("status")}, users.Arguments["where"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"column": "id", "option": Enum("desc")}}, users.Arguments["orders"])
+	assert.Equal(t, 10, users.Arguments["limit"])
+	assert.Equal(t, "userFields", users.Selections[0].Fragment)
+	assert.Equal(t, "include", users.Selections[1].Directives[0].Name)
+	assert.True(t, users.Selections[2].Inline)
+	assert.Equal(t, "User", users.Selections[2].On)
+	assert.Equal(t, "User", doc.Fragments["userFields"].On)
+
+	for _, source := range []string{"", "{", "{ user(id: ) { id } }", `{ user(name: "foo) { id } }`, "query { }", "fragment on on User { id }"} {
+		_, err := Parse(source)
+		assert.NotNil(t, err, source)
+	}
+}
+
+func TestSDL(t *testing.T) {
+	prepare()
+	schema, err := NewSchema(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sdl := schema.SDL()
+	assert.Contains(t, sdl, "type User {\n  id: ID!\n  name: String!\n  score: Float\n  extra: JSON\n  pets(wheres: [Where!], where: JSON, orders: [Order!], limit: Int): [UserPet!]\n}")
+	assert.Contains(t, sdl, "type UserPet {\n  id: ID!\n  user_id: Int\n  name: String!\n  owner: User\n}")
+	assert.Contains(t, sdl, "  userPaginate(wheres: [Where!], where: JSON, orders: [Order!], page: Int, pagesize: Int): UserPaginate!")
+	assert.Contains(t, sdl, "  createUserPet(data: UserPetInput!): UserPet")
+
+	schema, err = NewSchema([]string{"user"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdl = schema.SDL()
+	assert.NotContains(t, sdl, "type Mutation")
+	assert.NotContains(t, sdl, "pets(")
+}
+
+func TestParam(t *testing.T) {
+	prepare()
+	schema, err := NewSchema(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Parse(`query ($limit: Int) { userList(where: {name: "foo"}, orders: [{column: "id", option: "desc"}]) { name pets(limit: $limit) { name __typename } } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := &execution{schema: schema, doc: doc, variables: map[string]interface{}{"limit": 2}}
+	field := doc.Operations[0].Selections[0]
+	param, err := exec.param(schema.Types["User"], field.Selections, exec.arguments(field.Arguments))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []interface{}{"id", "name"}, param.Select)
+	assert.Equal(t, []model.QueryWhere{{Column: "name", Value: "foo"}}, param.Wheres)
+	assert.Equal(t, []model.QueryOrder{{Column: "id", Option: "desc"}}, param.Orders)
+	assert.Equal(t, []interface{}{"id", "name"}, param.Withs["pets"].Query.Select)
+	assert.Equal(t, 2, param.Withs["pets"].Query.Limit)
+
+	// project
+	row := map[string]interface{}{"id": 1, "name": "foo", "status": "enabled", "pets": []interface{}{map[string]interface{}{"id": 2, "name": "bar"}}}
+	res, err := exec.project(schema.Types["User"], field.Selections, row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bytes, _ := jsoniter.Marshal(res)
+	assert.Equal(t, `{"name":"foo","pets":[{"name":"bar","__typename":"UserPet"}]}`, string(bytes))
+
+	// unknown field
+	doc, _ = Parse(`{ userList { password } }`)
+	exec.doc = doc
+	_, err = exec.param(schema.Types["User"], doc.Operations[0].Selections[0].Selections, map[string]interface{}{})
+	assert.NotNil(t, err)
+
+	// the conditions of the unexposed columns
+	for _, args := range []map[string]interface{}{
+		{"where": map[string]interface{}{"password": "foo"}},
+		{"wheres": []interface{}{map[string]interface{}{"wheres": []interface{}{map[string]interface{}{"column": "password", "value": "foo"}}}}},
+		{"wheres": []interface{}{map[string]interface{}{"rel": "pets", "column": "secret", "value": "foo"}}},
+		{"orders": []interface{}{map[string]interface{}{"column": "password"}}},
+		{"limit": MaxPageSize + 1},
+	} {
+		_, err = exec.param(schema.Types["User"], nil, args)
+		assert.NotNil(t, err)
+	}
+
+	_, err = exec.param(schema.Types["User"], nil, map[string]interface{}{"wheres": []interface{}{map[string]interface{}{"rel": "pets", "column": "name", "value": "foo"}}})
+	assert.Nil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	prepare()
+	schema, err := NewSchema(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{
+		`{ userList { ...A } } fragment A on User { ...A }`,
+		`{ userList { ...A } } fragment A on User { id pets { ...B } } fragment B on UserPet { owner { ...A } }`,
+		`{ userList { ...A } } fragment A on User { ... on User { ...A } }`,
+		`{ userPaginate(pagesize: 1000) { total } }`,
+		`{ user(id: 1) { pets { owner { pets { owner { pets { owner { pets { owner { pets { owner { id } } } } } } } } } } }`,
+		`{ userList { ...A } } fragment A on User { ...B ...B ...B ...B } fragment B on User { ...C ...C ...C ...C } fragment C on User { ...D ...D ...D ...D } fragment D on User { ...E ...E ...E ...E } fragment E on User { id name id name }`,
+	} {
+		res := schema.Execute(Request{Query: query})
+		assert.NotEmpty(t, res.Errors, query)
+	}
+
+	_, err = Parse(strings.Repeat("{ user ", maxNesting+1) + strings.Repeat("}", maxNesting+1))
+	assert.NotNil(t, err)
+}
+
+func TestHandler(t *testing.T) {
+	prepare()
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	_, err := SetRoutes(router, Option{})
+	assert.NotNil(t, err)
+
+	_, err = SetRoutes(router, Option{Guard: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/graphql?sdl", nil)
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), "type Query {")
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/graphql", bytes.NewBufferString(`{"query":"{ __typename unknown { id } }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, `{"data":{"__typename":"Query","unknown":null},"errors":[{"message":"cannot query field unknown on type Query","path":["unknown"]}]}`, response.Body.String())
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/graphql?query=mutation{deleteUser(id:1)}", nil)
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 405, response.Code)
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/graphql", bytes.NewBufferString(`{"query":"{ user(id: 1) { id "}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 400, response.Code)
+}
+
+func prepare() {
+	user := &model.Model{
+		ID:         "user",
+		PrimaryKey: "id",
+		MetaData: model.MetaData{
+			Columns: []model.Column{
+				{Name: "id", Type: "ID", Primary: true},
+				{Name: "name", Type: "string"},
+				{Name: "score", Type: "decimal", Nullable: true},
+				{Name: "extra", Type: "json", Nullable: true},
+				{Name: "password", Type: "string", Crypt: "PASSWORD", Nullable: true},
+			},
+			Relations: map[string]model.Relation{
+				"pets": {Type: "hasMany", Model: "user.pet", Key: "user_id", Foreign: "id"},
+			},
+		},
+	}
+
+	pet := &model.Model{
+		ID:         "user.pet",
+		PrimaryKey: "id",
+		MetaData: model.MetaData{
+			Columns: []model.Column{
+				{Name: "id", Type: "ID", Primary: true},
+				{Name: "user_id", Type: "bigInteger", Nullable: true},
+				{Name: "name", Type: "string"},
+			},
+			Relations: map[string]model.Relation{
+				"owner": {Type: "hasOne", Model: "user", Key: "id", Foreign: "user_id"},
+			},
+		},
+	}
+
+	model.Models = map[string]*model.Model{"user": user, "user.pet": pet}
+}
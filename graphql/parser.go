@@ -0,0 +1,689 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+// the max nesting of the selection sets and the values, the deeper documents are rejected before they are executed
+const maxNesting = 64
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse parse the GraphQL document
+func Parse(source string) (*Document, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	doc := &Document{Fragments: map[string]*Fragment{}}
+	for p.peek().kind != tokEOF {
+		tok := p.peek()
+		switch {
+		case tok.kind == tokName && tok.value == "fragment":
+			fragment, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, has := doc.Fragments[fragment.Name]; has {
+				return nil, fmt.Errorf("there can be only one fragment named %s", fragment.Name)
+			}
+			doc.Fragments[fragment.Name] = fragment
+
+		case tok.kind == tokName && (tok.value == "query" || tok.value == "mutation" || tok.value == "subscription"),
+			tok.kind == tokPunct && tok.value == "{":
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.Operations = append(doc.Operations, operation)
+
+		default:
+			return nil, p.unexpected(tok)
+		}
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, fmt.Errorf("the document does not contain any operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) operation() (*Operation, error) {
+	operation := &Operation{Type: "query"}
+	if p.is(tokPunct, "{") {
+		selections, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		operation.Selections = selections
+		return operation, nil
+	}
+
+	operation.Type = p.next().value
+	if p.peek().kind == tokName {
+		operation.Name = p.next().value
+	}
+
+	if p.is(tokPunct, "(") {
+		p.next()
+		for !p.is(tokPunct, ")") {
+			variable, err := p.variable()
+			if err != nil {
+				return nil, err
+			}
+			operation.Variables = append(operation.Variables, variable)
+		}
+		p.next()
+	}
+
+	directives, err := p.directives()
+	if err != nil {
+		return nil, err
+	}
+	operation.Directives = directives
+
+	operation.Selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+func (p *parser) variable() (*Variable, error) {
+	if err := p.expect(tokPunct, "$"); err != nil {
+		return nil, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(tokPunct, ":"); err != nil {
+		return nil, err
+	}
+
+	typ, err := p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+
+	variable := &Variable{Name: name, Type: typ}
+	if p.is(tokPunct, "=") {
+		p.next()
+		variable.Default, err = p.value(true)
+		if err != nil {
+			return nil, err
+		}
+		variable.HasDefault = true
+	}
+	return variable, nil
+}
+
+func (p *parser) typeRef() (string, error) {
+	typ := ""
+	if p.is(tokPunct, "[") {
+		p.next()
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(tokPunct, "]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name
+	}
+
+	if p.is(tokPunct, "!") {
+		p.next()
+		typ = typ + "!"
+	}
+	return typ, nil
+}
+
+func (p *parser) fragment() (*Fragment, error) {
+	p.next()
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "on" {
+		return nil, fmt.Errorf("the fragment name can not be on")
+	}
+
+	if err := p.expect(tokName, "on"); err != nil {
+		return nil, err
+	}
+
+	on, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	fragment := &Fragment{Name: name, On: on}
+	fragment.Directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	fragment.Selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return fragment, nil
+}
+
+func (p *parser) selectionSet() ([]*Selection, error) {
+	if err := p.expect(tokPunct, "{"); err != nil {
+		return nil, err
+	}
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	selections := []*Selection{}
+	for !p.is(tokPunct, "}") {
+		if p.peek().kind == tokEOF {
+			return nil, p.unexpected(p.peek())
+		}
+
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	p.next()
+
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("the selection set can not be empty")
+	}
+	return selections, nil
+}
+
+func (p *parser) selection() (*Selection, error) {
+	var err error
+	if p.is(tokPunct, "...") {
+		p.next()
+
+		// fragment spread
+		if p.peek().kind == tokName && p.peek().value != "on" {
+			selection := &Selection{Fragment: p.next().value}
+			selection.Directives, err = p.directives()
+			if err != nil {
+				return nil, err
+			}
+			return selection, nil
+		}
+
+		// inline fragment
+		selection := &Selection{Inline: true}
+		if p.is(tokName, "on") {
+			p.next()
+			selection.On, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		selection.Directives, err = p.directives()
+		if err != nil {
+			return nil, err
+		}
+
+		selection.Selections, err = p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		return selection, nil
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	selection := &Selection{Name: name}
+	if p.is(tokPunct, ":") {
+		p.next()
+		selection.Alias = name
+		selection.Name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	selection.Arguments, err = p.arguments(false)
+	if err != nil {
+		return nil, err
+	}
+
+	selection.Directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.is(tokPunct, "{") {
+		selection.Selections, err = p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return selection, nil
+}
+
+func (p *parser) arguments(constant bool) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	if !p.is(tokPunct, "(") {
+		return args, nil
+	}
+
+	p.next()
+	for !p.is(tokPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(tokPunct, ":"); err != nil {
+			return nil, err
+		}
+
+		args[name], err = p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *parser) directives() ([]*Directive, error) {
+	directives := []*Directive{}
+	for p.is(tokPunct, "@") {
+		p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		args, err := p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &Directive{Name: name, Arguments: args})
+	}
+	return directives, nil
+}
+
+func (p *parser) value(constant bool) (interface{}, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokInt:
+		value, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %s at %d", tok.value, tok.pos)
+		}
+		return int(value), nil
+
+	case tokFloat:
+		value, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at %d", tok.value, tok.pos)
+		}
+		return value, nil
+
+	case tokString:
+		return tok.value, nil
+
+	case tokName:
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return Enum(tok.value), nil
+
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, fmt.Errorf("unexpected variable at %d", tok.pos)
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return VariableRef(name), nil
+
+		case "[":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			defer p.leave()
+
+			list := []interface{}{}
+			for !p.is(tokPunct, "]") {
+				if p.peek().kind == tokEOF {
+					return nil, p.unexpected(p.peek())
+				}
+				value, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			p.next()
+			return list, nil
+
+		case "{":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+			defer p.leave()
+
+			object := map[string]interface{}{}
+			for !p.is(tokPunct, "}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(tokPunct, ":"); err != nil {
+					return nil, err
+				}
+				object[name], err = p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+			return object, nil
+		}
+	}
+	return nil, p.unexpected(tok)
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxNesting {
+		return fmt.Errorf("the document is nested too deeply (max %d)", maxNesting)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) name() (string, error) {
+	tok := p.next()
+	if tok.kind != tokName {
+		return "", p.unexpected(tok)
+	}
+	return tok.value, nil
+}
+
+func (p *parser) expect(kind int, value string) error {
+	tok := p.next()
+	if tok.kind != kind || tok.value != value {
+		return fmt.Errorf("expected %s, found %s at %d", value, describe(tok), tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) is(kind int, value string) bool {
+	tok := p.peek()
+	return tok.kind == kind && tok.value == value
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) unexpected(tok token) error {
+	return fmt.Errorf("unexpected %s at %d", describe(tok), tok.pos)
+}
+
+func describe(tok token) string {
+	switch tok.kind {
+	case tokEOF:
+		return "<EOF>"
+	case tokString:
+		return strconv.Quote(tok.value)
+	}
+	return tok.value
+}
+
+// lex split the source into tokens, the commas and the comments are ignored
+func lex(source string) ([]token, error) {
+	tokens := []token{}
+	i := 0
+	for i < len(source) {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+
+		case c == 0xEF && strings.HasPrefix(source[i:], "\uFEFF"):
+			i += 3
+
+		case c == '#':
+			for i < len(source) && source[i] != '\n' && source[i] != '\r' {
+				i++
+			}
+
+		case c == '.':
+			if !strings.HasPrefix(source[i:], "...") {
+				return nil, fmt.Errorf("unexpected . at %d", i)
+			}
+			tokens = append(tokens, token{kind: tokPunct, value: "...", pos: i})
+			i += 3
+
+		case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+			tokens = append(tokens, token{kind: tokPunct, value: string(c), pos: i})
+			i++
+
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(source) && isNameChar(source[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokName, value: source[start:i], pos: start})
+
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			kind := tokInt
+			if c == '-' {
+				i++
+			}
+			for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+				i++
+			}
+			if i < len(source) && source[i] == '.' {
+				kind = tokFloat
+				i++
+				for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+					i++
+				}
+			}
+			if i < len(source) && (source[i] == 'e' || source[i] == 'E') {
+				kind = tokFloat
+				i++
+				if i < len(source) && (source[i] == '+' || source[i] == '-') {
+					i++
+				}
+				for i < len(source) && source[i] >= '0' && source[i] <= '9' {
+					i++
+				}
+			}
+			if i < len(source) && isNameChar(source[i]) {
+				return nil, fmt.Errorf("invalid number at %d", start)
+			}
+			tokens = append(tokens, token{kind: kind, value: source[start:i], pos: start})
+
+		case c == '"':
+			start := i
+			if strings.HasPrefix(source[i:], `"""`) {
+				end := strings.Index(source[i+3:], `"""`)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated string at %d", start)
+				}
+				value := strings.ReplaceAll(source[i+3:i+3+end], `\"""`, `"""`)
+				tokens = append(tokens, token{kind: tokString, value: blockString(value), pos: start})
+				i = i + 3 + end + 3
+				continue
+			}
+
+			value, size, err := quotedString(source[i:])
+			if err != nil {
+				return nil, fmt.Errorf("%s at %d", err.Error(), start)
+			}
+			tokens = append(tokens, token{kind: tokString, value: value, pos: start})
+			i += size
+
+		default:
+			r, _ := utf8.DecodeRuneInString(source[i:])
+			return nil, fmt.Errorf("unexpected character %q at %d", r, i)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(source)})
+	return tokens, nil
+}
+
+// quotedString read the quoted string, return the value and the size of the source
+func quotedString(source string) (string, int, error) {
+	var builder strings.Builder
+	i := 1
+	for i < len(source) {
+		c := source[i]
+		switch c {
+		case '"':
+			return builder.String(), i + 1, nil
+
+		case '\n', '\r':
+			return "", 0, fmt.Errorf("unterminated string")
+
+		case '\\':
+			if i+1 >= len(source) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch source[i+1] {
+			case '"', '\\', '/':
+				builder.WriteByte(source[i+1])
+			case 'b':
+				builder.WriteByte('\b')
+			case 'f':
+				builder.WriteByte('\f')
+			case 'n':
+				builder.WriteByte('\n')
+			case 'r':
+				builder.WriteByte('\r')
+			case 't':
+				builder.WriteByte('\t')
+			case 'u':
+				if i+6 > len(source) {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(source[i+2:i+6], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid unicode escape")
+				}
+				builder.WriteRune(rune(code))
+				i += 4
+			default:
+				return "", 0, fmt.Errorf("invalid escape \\%c", source[i+1])
+			}
+			i += 2
+
+		default:
+			builder.WriteByte(c)
+			i++
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// blockString remove the common indentation and the leading and trailing blank lines of the block string
+func blockString(value string) string {
+	lines := strings.Split(strings.ReplaceAll(value, "\r\n", "\n"), "\n")
+	indent := -1
+	for i, line := range lines {
+		if i == 0 {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if size := len(line) - len(trimmed); indent < 0 || size < indent {
+			indent = size
+		}
+	}
+
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,212 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yaoapp/gou/model"
+)
+
+// NewSchema generate the schema of the models, all of the loaded models if the ids are empty, the encrypted columns are not exposed
+func NewSchema(ids []string, readonly bool) (*Schema, error) {
+
+	if len(ids) == 0 {
+		for id := range model.Models {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	schema := &Schema{Types: map[string]*Type{}, Query: map[string]*Root{}, Mutation: map[string]*Root{}}
+	for _, id := range ids {
+		mod, has := model.Models[id]
+		if !has {
+			return nil, fmt.Errorf("[GraphQL] model %s not loaded", id)
+		}
+
+		name := typeName(id)
+		if _, has := schema.Types[name]; has {
+			return nil, fmt.Errorf("[GraphQL] the type name %s of the model %s is duplicated", name, id)
+		}
+		schema.Types[name] = &Type{Name: name, Model: mod, Fields: map[string]*Field{}}
+		schema.names = append(schema.names, name)
+	}
+
+	for _, name := range schema.names {
+		typ := schema.Types[name]
+		for i := range typ.Model.MetaData.Columns {
+			column := &typ.Model.MetaData.Columns[i]
+			if column.Crypt != "" {
+				continue // the encrypted columns (e.g. the passwords) are not exposed
+			}
+			typ.add(&Field{
+				Name:    column.Name,
+				Type:    scalar(column),
+				NonNull: column.Primary || (!column.Nullable && column.Default == nil && column.DefaultRaw == ""),
+				Column:  column,
+			})
+		}
+
+		relations := []string{}
+		for rel := range typ.Model.MetaData.Relations {
+			relations = append(relations, rel)
+		}
+		sort.Strings(relations)
+
+		for _, rel := range relations {
+			relation := typ.Model.MetaData.Relations[rel]
+			target := typeName(relation.Model)
+			if _, has := schema.Types[target]; !has {
+				continue // the related model is not exposed
+			}
+			typ.add(&Field{Name: rel, Type: target, List: relation.Type == model.RelHasMany, Relation: rel})
+		}
+
+		field := lowerFirst(name)
+		schema.Query[field] = &Root{Name: field, Action: "find", Type: typ}
+		schema.Query[field+"List"] = &Root{Name: field + "List", Action: "list", Type: typ}
+		schema.Query[field+"Paginate"] = &Root{Name: field + "Paginate", Action: "paginate", Type: typ}
+		if readonly {
+			continue
+		}
+
+		for _, action := range []string{"create", "update", "save", "delete"} {
+			schema.Mutation[action+name] = &Root{Name: action + name, Action: action, Type: typ}
+		}
+	}
+
+	return schema, nil
+}
+
+// SDL the schema definition of the schema
+func (schema *Schema) SDL() string {
+	lines := []string{
+		"scalar JSON",
+		"",
+		"input Where {",
+		"  column: String",
+		"  value: JSON",
+		"  method: String",
+		"  op: String",
+		"  wheres: [Where!]",
+		"}",
+		"",
+		"input Order {",
+		"  column: String!",
+		"  option: String",
+		"}",
+		"",
+	}
+
+	filters := "wheres: [Where!], where: JSON, orders: [Order!]"
+	query := []string{}
+	mutation := []string{}
+	for _, name := range schema.names {
+		typ := schema.Types[name]
+		field := lowerFirst(name)
+
+		lines = append(lines, fmt.Sprintf("type %s {", name))
+		input := []string{}
+		for _, fieldName := range typ.names {
+			f := typ.Fields[fieldName]
+			if f.Relation != "" {
+				if f.List {
+					lines = append(lines, fmt.Sprintf("  %s(%s, limit: Int): [%s!]", f.Name, filters, f.Type))
+					continue
+				}
+				lines = append(lines, fmt.Sprintf("  %s: %s", f.Name, f.Type))
+				continue
+			}
+
+			nonNull := ""
+			if f.NonNull {
+				nonNull = "!"
+			}
+			lines = append(lines, fmt.Sprintf("  %s: %s%s", f.Name, f.Type, nonNull))
+			input = append(input, fmt.Sprintf("  %s: %s", f.Name, f.Type))
+		}
+		lines = append(lines, "}", "")
+
+		lines = append(lines, fmt.Sprintf("type %sPaginate {", name),
+			fmt.Sprintf("  data: [%s!]!", name),
+			"  total: Int!", "  page: Int!", "  pagesize: Int!", "  pagecnt: Int!", "  next: Int!", "  prev: Int!",
+			"}", "")
+
+		query = append(query,
+			fmt.Sprintf("  %s(id: ID!): %s", field, name),
+			fmt.Sprintf("  %sList(%s, limit: Int): [%s!]!", field, filters, name),
+			fmt.Sprintf("  %sPaginate(%s, page: Int, pagesize: Int): %sPaginate!", field, filters, name),
+		)
+
+		if _, has := schema.Mutation["create"+name]; !has {
+			continue
+		}
+
+		lines = append(lines, fmt.Sprintf("input %sInput {", name))
+		lines = append(lines, input...)
+		lines = append(lines, "}", "")
+		mutation = append(mutation,
+			fmt.Sprintf("  create%s(data: %sInput!): %s", name, name, name),
+			fmt.Sprintf("  update%s(id: ID!, data: %sInput!): %s", name, name, name),
+			fmt.Sprintf("  save%s(data: %sInput!): %s", name, name, name),
+			fmt.Sprintf("  delete%s(id: ID!): Boolean!", name),
+		)
+	}
+
+	lines = append(lines, "type Query {")
+	lines = append(lines, query...)
+	lines = append(lines, "}")
+	if len(mutation) > 0 {
+		lines = append(lines, "", "type Mutation {")
+		lines = append(lines, mutation...)
+		lines = append(lines, "}")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (typ *Type) add(field *Field) {
+	if _, has := typ.Fields[field.Name]; has {
+		return
+	}
+	typ.Fields[field.Name] = field
+	typ.names = append(typ.names, field.Name)
+}
+
+// scalar the GraphQL scalar type of the column
+func scalar(column *model.Column) string {
+	if column.Primary {
+		return "ID"
+	}
+
+	typ := strings.ToLower(column.Type)
+	switch {
+	case typ == "id" || strings.Contains(typ, "increments"):
+		return "ID"
+	case strings.Contains(typ, "integer"):
+		return "Int"
+	case typ == "float" || typ == "double" || typ == "decimal" || strings.HasPrefix(typ, "unsigned"):
+		return "Float"
+	case typ == "boolean":
+		return "Boolean"
+	case strings.HasPrefix(typ, "json"):
+		return "JSON"
+	}
+	return "String"
+}
+
+// typeName the type name of the model id, user.pet => UserPet
+func typeName(id string) string {
+	name := ""
+	for _, part := range strings.FieldsFunc(id, func(r rune) bool { return r == '.' || r == '_' || r == '-' || r == '/' }) {
+		name = name + strings.ToUpper(part[:1]) + part[1:]
+	}
+	return name
+}
+
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
@@ -0,0 +1,121 @@
+package graphql
+
+import (
+	"github.com/yaoapp/gou/model"
+)
+
+// Option the GraphQL endpoint option
+type Option struct {
+	Path     string   `json:"path,omitempty"`     // the endpoint path, default is /graphql
+	Guard    string   `json:"guard,omitempty"`    // the comma separated guards (required), "-" means no guard
+	Models   []string `json:"models,omitempty"`   // the exposed model ids, all of the loaded models if empty
+	Readonly bool     `json:"readonly,omitempty"` // disable the mutations
+}
+
+// Request the GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response the GraphQL response
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Error the GraphQL error
+type Error struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// Schema the GraphQL schema generated from the models
+type Schema struct {
+	Types    map[string]*Type
+	Query    map[string]*Root
+	Mutation map[string]*Root
+	names    []string
+}
+
+// Type the object type of a model
+type Type struct {
+	Name   string
+	Model  *model.Model
+	Fields map[string]*Field
+	names  []string
+}
+
+// Field the field of an object type, a column or a relation
+type Field struct {
+	Name     string
+	Type     string // ID, Int, Float, Boolean, String, JSON or the type name of the relation
+	List     bool
+	NonNull  bool
+	Column   *model.Column
+	Relation string
+}
+
+// Root the root field of the query or the mutation
+type Root struct {
+	Name   string
+	Action string // find, list, paginate, create, update, save, delete
+	Type   *Type
+}
+
+// Document the parsed GraphQL document
+type Document struct {
+	Operations []*Operation
+	Fragments  map[string]*Fragment
+}
+
+// Operation the query or the mutation operation
+type Operation struct {
+	Type       string // query, mutation, subscription
+	Name       string
+	Variables  []*Variable
+	Directives []*Directive
+	Selections []*Selection
+}
+
+// Variable the variable definition of the operation
+type Variable struct {
+	Name       string
+	Type       string
+	Default    interface{}
+	HasDefault bool
+}
+
+// Fragment the named fragment
+type Fragment struct {
+	Name       string
+	On         string
+	Directives []*Directive
+	Selections []*Selection
+}
+
+// Selection a field, a fragment spread (Fragment) or an inline fragment (Inline)
+type Selection struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]interface{}
+	Directives []*Directive
+	Selections []*Selection
+	Fragment   string
+	Inline     bool
+	On         string
+}
+
+// Directive the directive, @include and @skip are supported
+type Directive struct {
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// VariableRef the variable reference in the arguments
+type VariableRef string
+
+// Enum the enum value in the arguments
+type Enum string
@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"regexp"
+	"strings"
+)
+
+// GlobRegexp convert the glob pattern to the regular expression. * matches any sequence of characters, ? matches any single character, [abc] matches a character in the set
+func GlobRegexp(pattern string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				builder.WriteString(`\[`)
+				continue
+			}
+			set := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(set, "!") {
+				set = "^" + set[1:]
+			}
+			builder.WriteString("[" + strings.ReplaceAll(set, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				builder.WriteString(regexp.QuoteMeta(string(pattern[i])))
+				continue
+			}
+			builder.WriteString(`\\`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
+
+// GlobMatch check if the name matches the glob pattern, the empty pattern matches everything
+func GlobMatch(pattern string, name string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	matched, err := regexp.MatchString(GlobRegexp(pattern), name)
+	return err == nil && matched
+}
@@ -0,0 +1,71 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Normalize copy the value as the plain maps, slices, strings, float64 and bool via JSON, the value is returned if it can not be encoded
+func Normalize(value interface{}) interface{} {
+	switch value.(type) {
+	case string, nil:
+		return value
+	}
+
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var res interface{}
+	if err := jsoniter.Unmarshal(bytes, &res); err != nil {
+		return value
+	}
+	return res
+}
+
+// Equal compare the values in the JSON form
+func Equal(a interface{}, b interface{}) bool {
+	return reflect.DeepEqual(Normalize(a), Normalize(b))
+}
+
+// ToInt convert the integer, the float without the fraction or the numeric string to int
+func ToInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return int(v), nil
+	case uint:
+		return int(v), nil
+	case uint8:
+		return int(v), nil
+	case uint16:
+		return int(v), nil
+	case uint32:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float32:
+		if float32(math.Trunc(float64(v))) == v {
+			return int(v), nil
+		}
+	case float64:
+		if math.Trunc(v) == v {
+			return int(v), nil
+		}
+	case string:
+		return strconv.Atoi(v)
+	}
+	return 0, fmt.Errorf("%v is not an integer", value)
+}
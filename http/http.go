@@ -18,6 +18,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/cast"
 	"github.com/yaoapp/gou/dns"
+	"github.com/yaoapp/gou/observe"
 )
 
 // New make a new  http Request
@@ -90,6 +91,12 @@ func (r *Request) WithHeader(headers http.Header) *Request {
 	return r
 }
 
+// WithContext set the context of the request, the trace context is propagated
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
 // WithQuery set the request query params
 func (r *Request) WithQuery(values neturl.Values) *Request {
 	r.query = values
@@ -187,6 +194,10 @@ func (r *Request) Send(method string, data interface{}) *Response {
 
 	// Request Header
 	req.Header = r.headers
+	if _, ok := observe.TraceFrom(r.ctx); ok {
+		req.Header = r.headers.Clone()
+		observe.Inject(r.ctx, req.Header)
+	}
 
 	// Force using system DSN resolver
 	// var dialer = &net.Dialer{Resolver: &net.Resolver{PreferGo: false}}
@@ -336,6 +347,10 @@ func (r *Request) Stream(ctx context.Context, method string, data interface{}, h
 
 	// Request Header
 	req.Header = r.headers
+	if _, ok := observe.TraceFrom(r.ctx); ok {
+		req.Header = r.headers.Clone()
+		observe.Inject(r.ctx, req.Header)
+	}
 
 	// Force using system DSN resolver
 	// var dialer = &net.Dialer{Resolver: &net.Resolver{PreferGo: false}}
@@ -18,6 +18,7 @@ import (
 	"github.com/gin-gonic/gin"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/observe"
 	"github.com/yaoapp/kun/any"
 )
 
@@ -66,6 +67,30 @@ func TestGet(t *testing.T) {
 	assert.Equal(t, "It works", fmt.Sprintf("%s", res.Data))
 }
 
+func TestTraceContext(t *testing.T) {
+
+	shutdown, ready, host := setup()
+	go start(t, &host, shutdown, ready)
+	defer stop(shutdown, ready)
+	<-ready
+
+	trace := observe.NewTrace()
+	ctx := observe.WithTrace(context.Background(), trace)
+	res := New(fmt.Sprintf("%s/get", host)).WithContext(ctx).Get()
+	data := any.Of(res.Data).MapStr().Dot()
+	assert.Equal(t, 200, res.Code)
+
+	parent, ok := observe.ParseTraceparent(fmt.Sprintf("%v", data.Get("headers.Traceparent[0]")))
+	assert.True(t, ok)
+	assert.Equal(t, trace.TraceID, parent.TraceID)
+	assert.NotEqual(t, trace.SpanID, parent.SpanID)
+
+	// without the trace
+	res = New(fmt.Sprintf("%s/get", host)).Get()
+	data = any.Of(res.Data).MapStr().Dot()
+	assert.Nil(t, data.Get("headers.Traceparent"))
+}
+
 func TestPost(t *testing.T) {
 	shutdown, ready, host := setup()
 	go start(t, &host, shutdown, ready)
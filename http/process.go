@@ -309,7 +309,7 @@ func processHTTPStream(p *process.Process) interface{} {
 		payload = p.Args[3]
 	}
 
-	req := New(url)
+	req := New(url).WithContext(p.Context)
 	if p.NumOfArgs() > 4 {
 		values, err := cast.AnyToURLValues(p.Args[4])
 		if err != nil {
@@ -366,7 +366,7 @@ func processHTTPStream(p *process.Process) interface{} {
 // make a *Request
 func processHTTPNew(process *process.Process, from int) (*Request, *Response) {
 
-	req := New(process.ArgsString(0))
+	req := New(process.ArgsString(0)).WithContext(process.Context)
 
 	if process.NumOfArgs() > from {
 		values, err := cast.AnyToURLValues(process.Args[from])
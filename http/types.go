@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 )
@@ -25,6 +26,7 @@ type Request struct {
 	files     []File
 	fileBytes []File
 	data      interface{}
+	ctx       context.Context // the trace context is propagated to the request
 }
 
 // Response HTTP Response
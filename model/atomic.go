@@ -100,6 +100,7 @@ func (mod *Model) Create(row maps.MapStrAny) (int, error) {
 		return 0, err
 	}
 
+	mod.synced(int(id))
 	return int(id), err
 }
 
@@ -141,6 +142,9 @@ func (mod *Model) Update(id interface{}, row maps.MapStrAny) error {
 		return fmt.Errorf("没有数据被更新")
 	}
 
+	if err == nil {
+		mod.synced(id)
+	}
 	return err
 }
 
@@ -187,6 +191,7 @@ func (mod *Model) Save(row maps.MapStrAny) (interface{}, error) {
 			return 0, err
 		}
 
+		mod.synced(id)
 		return id, nil
 	}
 
@@ -205,6 +210,7 @@ func (mod *Model) Save(row maps.MapStrAny) (interface{}, error) {
 		return 0, err
 	}
 
+	mod.synced(id)
 	return id, err
 }
 
@@ -228,6 +234,10 @@ func (mod *Model) Delete(id interface{}) error {
 		},
 		Limit: 1,
 	})
+
+	if err == nil {
+		mod.synced(id)
+	}
 	return err
 }
 
@@ -242,6 +252,9 @@ func (mod *Model) MustDelete(id interface{}) {
 // Destroy 真删除单条记录
 func (mod *Model) Destroy(id interface{}) error {
 	_, err := capsule.Query().Table(mod.MetaData.Table.Name).Where(mod.PrimaryKey, id).Limit(1).Delete()
+	if err == nil {
+		mod.synced(id)
+	}
 	return err
 }
 
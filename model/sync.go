@@ -0,0 +1,127 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/kun/maps"
+)
+
+// Syncer sync the written rows of the model to the other storage, e.g. a search index
+type Syncer interface {
+	Save(mod *Model, id interface{}, row maps.MapStr) error
+	Remove(mod *Model, id interface{}) error
+}
+
+// SyncQueueSize the size of the sync queue, the writes are not synced if the queue is full
+var SyncQueueSize = 4096
+
+// the syncers of the models  model id -> name -> syncer
+var syncers = map[string]map[string]Syncer{}
+var syncersMutex sync.RWMutex
+
+// the written rows are synced by a worker in the written order
+var syncQueue chan syncJob
+var syncOnce sync.Once
+
+type syncJob struct {
+	mod *Model
+	id  interface{}
+}
+
+// AddSyncer sync the single row writes of the model (Create, Update, Save, EachSave, Delete, Destroy) by the syncer in the background,
+// the syncer of the same name is replaced. The batch writes (Insert, UpdateWhere, DeleteWhere ...) are not synced.
+func AddSyncer(id string, name string, syncer Syncer) {
+	syncersMutex.Lock()
+	defer syncersMutex.Unlock()
+	if _, has := syncers[id]; !has {
+		syncers[id] = map[string]Syncer{}
+	}
+	syncers[id][name] = syncer
+}
+
+// RemoveSyncer remove the syncer of the model
+func RemoveSyncer(id string, name string) {
+	syncersMutex.Lock()
+	defer syncersMutex.Unlock()
+	delete(syncers[id], name)
+}
+
+// synced queue the written row to be synced, the write is not synced (and logged) if the queue is full
+func (mod *Model) synced(id interface{}) {
+	if len(mod.syncers()) == 0 {
+		return
+	}
+
+	syncOnce.Do(func() {
+		syncQueue = make(chan syncJob, SyncQueueSize)
+		go syncWorker(syncQueue)
+	})
+
+	select {
+	case syncQueue <- syncJob{mod: mod, id: id}:
+	default:
+		log.Error("[Model] %s sync %v the sync queue is full, dropped", mod.ID, id)
+	}
+}
+
+// syncWorker sync the queued rows
+func syncWorker(queue chan syncJob) {
+	for job := range queue {
+		job.mod.syncRow(job.id)
+	}
+}
+
+// syncRow sync the row, the row is removed if it does not exist (e.g. soft deleted).
+// the errors are logged, the write is not rolled back. the row is not synced if it can not be read.
+func (mod *Model) syncRow(id interface{}) {
+	list := mod.syncers()
+	if len(list) == 0 {
+		return
+	}
+
+	row, err := mod.syncedRow(id)
+	if err != nil {
+		log.Error("[Model] %s sync %v %s", mod.ID, id, err.Error())
+		return
+	}
+
+	for name, syncer := range list {
+		var err error
+		if row == nil {
+			err = syncer.Remove(mod, id)
+		} else {
+			err = syncer.Save(mod, id, row)
+		}
+
+		if err != nil {
+			log.Error("[Model] %s sync %v to %s %s", mod.ID, id, name, err.Error())
+		}
+	}
+}
+
+// syncedRow the written row, nil if the row does not exist
+func (mod *Model) syncedRow(id interface{}) (row maps.MapStr, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	rows, err := mod.Get(QueryParam{Wheres: []QueryWhere{{Column: mod.PrimaryKey, Value: id}}, Limit: 1})
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0], nil
+}
+
+func (mod *Model) syncers() map[string]Syncer {
+	syncersMutex.RLock()
+	defer syncersMutex.RUnlock()
+	list := map[string]Syncer{}
+	for name, syncer := range syncers[mod.ID] {
+		list[name] = syncer
+	}
+	return list
+}
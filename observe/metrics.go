@@ -0,0 +1,149 @@
+package observe
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultBuckets the default latency histogram buckets (seconds)
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics the global metrics registry
+var Metrics = NewRegistry()
+
+// NewRegistry create a metrics registry
+func NewRegistry() *Registry {
+	return &Registry{counters: map[string]*counter{}, histograms: map[string]*histogram{}}
+}
+
+// Add add the value to the counter
+func (registry *Registry) Add(name string, labels map[string]string, value float64) {
+	key := metricKey(name, labels)
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	c, has := registry.counters[key]
+	if !has {
+		c = &counter{name: name, labels: labels}
+		registry.counters[key] = c
+	}
+	c.value += value
+}
+
+// Observe add the value to the histogram
+func (registry *Registry) Observe(name string, labels map[string]string, buckets []float64, value float64) {
+	key := metricKey(name, labels)
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	h, has := registry.histograms[key]
+	if !has {
+		h = &histogram{name: name, labels: labels, buckets: buckets, counts: make([]uint64, len(buckets))}
+		registry.histograms[key] = h
+	}
+
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Reset remove all of the metrics
+func (registry *Registry) Reset() {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.counters = map[string]*counter{}
+	registry.histograms = map[string]*histogram{}
+}
+
+// Write write the metrics in the Prometheus text format
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	types := map[string]string{}
+	groups := map[string][]string{}
+
+	keys := []string{}
+	for key := range registry.counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c := registry.counters[key]
+		types[c.name] = "counter"
+		groups[c.name] = append(groups[c.name], fmt.Sprintf("%s%s %s", c.name, formatLabels(c.labels, "", ""), formatFloat(c.value)))
+	}
+
+	keys = []string{}
+	for key := range registry.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		h := registry.histograms[key]
+		types[h.name] = "histogram"
+		for i, bucket := range h.buckets {
+			groups[h.name] = append(groups[h.name], fmt.Sprintf("%s_bucket%s %d", h.name, formatLabels(h.labels, "le", formatFloat(bucket)), h.counts[i]))
+		}
+		groups[h.name] = append(groups[h.name],
+			fmt.Sprintf("%s_bucket%s %d", h.name, formatLabels(h.labels, "le", "+Inf"), h.count),
+			fmt.Sprintf("%s_sum%s %s", h.name, formatLabels(h.labels, "", ""), formatFloat(h.sum)),
+			fmt.Sprintf("%s_count%s %d", h.name, formatLabels(h.labels, "", ""), h.count),
+		)
+	}
+
+	names := []string{}
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, err := fmt.Fprintf(w, "# TYPE %s %s\n%s\n", name, types[name], strings.Join(groups[name], "\n"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func metricKey(name string, labels map[string]string) string {
+	return name + formatLabels(labels, "", "")
+}
+
+func formatLabels(labels map[string]string, extra string, extraValue string) string {
+	names := []string{}
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []string{}
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, strconv.Quote(labels[name])))
+	}
+
+	if extra != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", extra, strconv.Quote(extraValue)))
+	}
+
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,213 @@
+package observe
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/kun/log"
+)
+
+type recordKey struct{}
+
+// Middleware the gin middleware of the access logs, metrics and trace context
+func Middleware(option Option) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// the request has been observed by the Handler
+		if _, ok := RecordFrom(c.Request.Context()); ok {
+			c.Next()
+			return
+		}
+
+		record, ctx := option.begin(c.Request)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if record.Route == "" {
+			record.Route = c.FullPath()
+		}
+		record.Status = c.Writer.Status()
+		if size := c.Writer.Size(); size > 0 {
+			record.RespSize = int64(size)
+		}
+		option.end(record)
+	}
+}
+
+// Handler wrap the http handler with the access logs, metrics and trace context, the metrics are served at the option.Metrics path
+func Handler(next http.Handler, option Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if option.Metrics != "" && r.Method == "GET" && r.URL.Path == option.Metrics {
+			writeMetrics(w)
+			return
+		}
+
+		record, ctx := option.begin(r)
+		writer := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(writer, r.WithContext(ctx))
+
+		record.Status = writer.status
+		record.RespSize = writer.size
+		option.end(record)
+	})
+}
+
+// MetricsHandler serve the metrics in the Prometheus text format
+func MetricsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writeMetrics(c.Writer)
+	}
+}
+
+// Annotate set the route, the process and the session id of the observed request
+func Annotate(c *gin.Context, process string) {
+	record, ok := RecordFrom(c.Request.Context())
+	if !ok {
+		return
+	}
+
+	if route := c.FullPath(); route != "" {
+		record.Route = route
+	}
+
+	if process != "" {
+		record.Process = process
+	}
+
+	if sid := c.GetString("__sid"); sid != "" {
+		record.Sid = sid
+	}
+}
+
+// RecordFrom get the observed request record of the context
+func RecordFrom(ctx context.Context) (*Record, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	record, ok := ctx.Value(recordKey{}).(*Record)
+	return record, ok && record != nil
+}
+
+// begin start observing the request
+func (option Option) begin(r *http.Request) (*Record, context.Context) {
+	record := &Record{Method: r.Method, Start: time.Now()}
+	if r.ContentLength > 0 {
+		record.ReqSize = r.ContentLength
+	}
+
+	ctx := r.Context()
+	if option.Trace {
+		record.Trace = FromHeader(r.Header)
+		ctx = WithTrace(ctx, record.Trace)
+	}
+
+	return record, context.WithValue(ctx, recordKey{}, record)
+}
+
+// end record the metrics and write the access log
+func (option Option) end(record *Record) {
+
+	record.Latency = time.Since(record.Start)
+	if record.Route == "" {
+		record.Route = "other"
+	}
+
+	buckets := option.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	status := fmt.Sprintf("%d", record.Status)
+	Metrics.Add(option.name("http_requests_total"), map[string]string{"method": record.Method, "route": record.Route, "status": status}, 1)
+	Metrics.Observe(option.name("http_request_duration_seconds"), map[string]string{"method": record.Method, "route": record.Route}, buckets, record.Latency.Seconds())
+	Metrics.Add(option.name("http_request_size_bytes_total"), map[string]string{"method": record.Method, "route": record.Route}, float64(record.ReqSize))
+	Metrics.Add(option.name("http_response_size_bytes_total"), map[string]string{"method": record.Method, "route": record.Route}, float64(record.RespSize))
+
+	if record.Process != "" {
+		result := "ok"
+		if record.Status >= 500 {
+			result = "error"
+		}
+		Metrics.Add(option.name("process_calls_total"), map[string]string{"process": record.Process, "result": result}, 1)
+		Metrics.Observe(option.name("process_duration_seconds"), map[string]string{"process": record.Process}, buckets, record.Latency.Seconds())
+	}
+
+	if !option.AccessLog {
+		return
+	}
+
+	fields := log.F{
+		"method":   record.Method,
+		"route":    record.Route,
+		"process":  record.Process,
+		"sid":      record.Sid,
+		"status":   record.Status,
+		"latency":  record.Latency.Milliseconds(),
+		"req_size": record.ReqSize,
+		"res_size": record.RespSize,
+	}
+
+	if record.Trace != nil {
+		fields["trace_id"] = record.Trace.TraceID
+		fields["span_id"] = record.Trace.SpanID
+	}
+
+	log.With(fields).Info("[HTTP] %s %s %d %s", record.Method, record.Route, record.Status, record.Latency)
+}
+
+func (option Option) name(name string) string {
+	if option.Namespace == "" {
+		return name
+	}
+	return option.Namespace + "_" + name
+}
+
+func writeMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	Metrics.Write(w)
+}
+
+// responseWriter record the status and the size of the response
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, fmt.Errorf("the response writer does not support hijacking")
+}
+
+func (w *responseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
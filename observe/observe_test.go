@@ -0,0 +1,104 @@
+package observe
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	trace, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", trace.SpanID)
+	assert.True(t, trace.Sampled)
+
+	child := trace.Child()
+	assert.Equal(t, trace.TraceID, child.TraceID)
+	assert.Equal(t, trace.SpanID, child.ParentID)
+	assert.Regexp(t, `^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$`, child.Traceparent())
+
+	for _, value := range []string{"", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "00-xyz-00f067aa0ba902b7-01"} {
+		_, ok = ParseTraceparent(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add("requests_total", map[string]string{"route": "/a"}, 1)
+	registry.Add("requests_total", map[string]string{"route": "/a"}, 2)
+	registry.Observe("duration_seconds", map[string]string{"route": "/a"}, []float64{0.1, 1}, 0.5)
+
+	buf := &bytes.Buffer{}
+	err := registry.Write(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, strings.Join([]string{
+		`# TYPE duration_seconds histogram`,
+		`duration_seconds_bucket{route="/a",le="0.1"} 0`,
+		`duration_seconds_bucket{route="/a",le="1"} 1`,
+		`duration_seconds_bucket{route="/a",le="+Inf"} 1`,
+		`duration_seconds_sum{route="/a"} 0.5`,
+		`duration_seconds_count{route="/a"} 1`,
+		`# TYPE requests_total counter`,
+		`requests_total{route="/a"} 3`,
+		``,
+	}, "\n"), buf.String())
+}
+
+func TestMiddleware(t *testing.T) {
+	Metrics.Reset()
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(Middleware(Option{Trace: true, AccessLog: true, Metrics: "/metrics"}))
+	router.GET("/metrics", MetricsHandler())
+	router.GET("/users/:id", func(c *gin.Context) {
+		Annotate(c, "unit.observe.user")
+		trace, _ := TraceFrom(c.Request.Context())
+		c.JSON(200, gin.H{"trace_id": trace.TraceID, "parent_id": trace.ParentID})
+	})
+
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(response, req)
+	assert.Equal(t, 200, response.Code)
+	assert.Contains(t, response.Body.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, response.Body.String(), `"parent_id":"00f067aa0ba902b7"`)
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	router.ServeHTTP(response, req)
+	assert.Contains(t, response.Body.String(), `http_requests_total{method="GET",route="/users/:id",status="200"} 1`)
+	assert.Contains(t, response.Body.String(), `process_calls_total{process="unit.observe.user",result="ok"} 1`)
+}
+
+func TestHandler(t *testing.T) {
+	Metrics.Reset()
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.GET("/users/:id", func(c *gin.Context) {
+		Annotate(c, "")
+		c.String(404, "not found")
+	})
+
+	handler := Handler(router, Option{Metrics: "/metrics", Namespace: "unit"})
+	response := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	handler.ServeHTTP(response, req)
+	assert.Equal(t, 404, response.Code)
+
+	response = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	handler.ServeHTTP(response, req)
+	assert.Contains(t, response.Body.String(), `unit_http_requests_total{method="GET",route="/users/:id",status="404"} 1`)
+	assert.Contains(t, response.Body.String(), `unit_http_response_size_bytes_total{method="GET",route="/users/:id"} 9`)
+}
@@ -0,0 +1,110 @@
+package observe
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+type traceKey struct{}
+
+var reTraceparent = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// NewTrace create a new sampled trace
+func NewTrace() *Trace {
+	return &Trace{TraceID: randomHex(16), SpanID: randomHex(8), Sampled: true}
+}
+
+// ParseTraceparent parse the traceparent header, version-traceid-parentid-flags
+func ParseTraceparent(value string) (*Trace, bool) {
+	matches := reTraceparent.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if matches == nil || matches[1] == "ff" {
+		return nil, false
+	}
+
+	if strings.Trim(matches[2], "0") == "" || strings.Trim(matches[3], "0") == "" {
+		return nil, false
+	}
+
+	flags, _ := hex.DecodeString(matches[4])
+	return &Trace{TraceID: matches[2], SpanID: matches[3], Sampled: flags[0]&0x01 == 0x01}, true
+}
+
+// FromHeader the child span of the trace in the header, a new trace is created if the header is missing or invalid
+func FromHeader(header http.Header) *Trace {
+	parent, ok := ParseTraceparent(header.Get("traceparent"))
+	if !ok {
+		return NewTrace()
+	}
+	parent.State = header.Get("tracestate")
+	return parent.Child()
+}
+
+// Child create a child span of the trace
+func (trace *Trace) Child() *Trace {
+	return &Trace{
+		TraceID:  trace.TraceID,
+		SpanID:   randomHex(8),
+		ParentID: trace.SpanID,
+		Sampled:  trace.Sampled,
+		State:    trace.State,
+	}
+}
+
+// Traceparent the traceparent header value
+func (trace *Trace) Traceparent() string {
+	flags := "00"
+	if trace.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", trace.TraceID, trace.SpanID, flags)
+}
+
+// WithTrace return a copy of the context with the trace
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFrom get the trace of the context
+func TraceFrom(ctx context.Context) (*Trace, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	trace, ok := ctx.Value(traceKey{}).(*Trace)
+	return trace, ok && trace != nil
+}
+
+// Propagate copy the trace of the source context to the context
+func Propagate(ctx context.Context, source context.Context) context.Context {
+	trace, ok := TraceFrom(source)
+	if !ok {
+		return ctx
+	}
+	return WithTrace(ctx, trace)
+}
+
+// Inject set the traceparent (a child span) and tracestate headers of an outbound request
+func Inject(ctx context.Context, header http.Header) {
+	trace, ok := TraceFrom(ctx)
+	if !ok || header.Get("traceparent") != "" {
+		return
+	}
+
+	header.Set("traceparent", trace.Child().Traceparent())
+	if trace.State != "" {
+		header.Set("tracestate", trace.State)
+	}
+}
+
+func randomHex(size int) string {
+	bytes := make([]byte, size)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
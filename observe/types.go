@@ -0,0 +1,60 @@
+package observe
+
+import (
+	"sync"
+	"time"
+)
+
+// Option the observability option
+type Option struct {
+	AccessLog bool      `json:"accessLog,omitempty"` // emit the structured access logs
+	Metrics   string    `json:"metrics,omitempty"`   // the path of the Prometheus metrics, empty means do not serve the metrics
+	Namespace string    `json:"namespace,omitempty"` // the prefix of the metric names
+	Buckets   []float64 `json:"buckets,omitempty"`   // the latency histogram buckets (seconds)
+	Trace     bool      `json:"trace,omitempty"`     // propagate the W3C trace context
+}
+
+// Trace the W3C trace context
+type Trace struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Sampled  bool
+	State    string // the tracestate header
+}
+
+// Record the observed request
+type Record struct {
+	Method   string
+	Route    string
+	Process  string
+	Sid      string
+	Status   int
+	ReqSize  int64
+	RespSize int64
+	Start    time.Time
+	Latency  time.Duration
+	Trace    *Trace
+}
+
+// Registry the metrics registry
+type Registry struct {
+	counters   map[string]*counter
+	histograms map[string]*histogram
+	mutex      sync.Mutex
+}
+
+type counter struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+type histogram struct {
+	name    string
+	labels  map[string]string
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
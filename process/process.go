@@ -104,6 +104,25 @@ func (process *Process) WithRuntime(runtime Runtime) *Process {
 	return process
 }
 
+// WithEmitter set the stream event emitter
+func (process *Process) WithEmitter(emitter Emitter) *Process {
+	process.Emitter = emitter
+	return process
+}
+
+// Streaming check if the process is called in a stream
+func (process *Process) Streaming() bool {
+	return process.Emitter != nil
+}
+
+// Emit send an event to the stream
+func (process *Process) Emit(name string, message interface{}) error {
+	if process.Emitter == nil {
+		return fmt.Errorf("%s is not streaming", process.Name)
+	}
+	return process.Emitter.Emit(name, message)
+}
+
 // Dispose the process after run success
 func (process *Process) Dispose() {
 	if process.Runtime != nil {
@@ -114,6 +133,7 @@ func (process *Process) Dispose() {
 	process.Global = nil
 	process.Context = nil
 	process.Runtime = nil
+	process.Emitter = nil
 	process = nil
 }
 
@@ -135,7 +155,7 @@ func (process *Process) make() error {
 	process.Group = fields[0]
 	switch process.Group {
 
-	case "models", "schemas", "stores", "fs", "tasks", "schedules":
+	case "models", "schemas", "stores", "fs", "tasks", "schedules", "connectors":
 		// models.user.pet.Find
 		process.Method = fields[len(fields)-1]
 		process.ID = strings.ToLower(strings.Join(fields[1:len(fields)-1], "."))
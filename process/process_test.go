@@ -240,6 +240,24 @@ func TestWithGlobal(t *testing.T) {
 	assert.Equal(t, map[string]interface{}{"hello": "world"}, data["global"])
 }
 
+func TestWithEmitter(t *testing.T) {
+	prepare(t)
+
+	p := New("unit.test.prepare", "foo", "bar")
+	assert.False(t, p.Streaming())
+	assert.Error(t, p.Emit("message", "foo"))
+
+	events := []string{}
+	p.WithEmitter(EmitterFunc(func(name string, message interface{}) error {
+		events = append(events, name+":"+message.(string))
+		return nil
+	}))
+	assert.True(t, p.Streaming())
+	assert.Nil(t, p.Emit("message", "foo"))
+	assert.Nil(t, p.Emit("done", "bar"))
+	assert.Equal(t, []string{"message:foo", "done:bar"}, events)
+}
+
 func prepare(t *testing.T) {
 	Register("unit.test.prepare", processTest)
 	Register("flows", processTest)
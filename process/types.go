@@ -16,6 +16,7 @@ type Process struct {
 	Sid     string                 // Session ID
 	Context context.Context        // Context
 	Runtime Runtime                // Runtime
+	Emitter Emitter                // Stream event emitter, nil if the process is not streaming
 }
 
 // Runtime interface
@@ -23,5 +24,18 @@ type Runtime interface {
 	Dispose()
 }
 
+// Emitter the stream event emitter interface
+type Emitter interface {
+	Emit(name string, message interface{}) error
+}
+
+// EmitterFunc the function adapter of the Emitter
+type EmitterFunc func(name string, message interface{}) error
+
+// Emit the event
+func (fn EmitterFunc) Emit(name string, message interface{}) error {
+	return fn(name, message)
+}
+
 // Handler the process handler
 type Handler func(process *Process) interface{}
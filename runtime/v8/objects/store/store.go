@@ -2,6 +2,7 @@ package store
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/yaoapp/gou/runtime/v8/bridge"
@@ -30,6 +31,12 @@ func (store *Store) ExportObject(iso *v8go.Isolate) *v8go.ObjectTemplate {
 	tmpl.Set("Keys", store.keys(iso))
 	tmpl.Set("Len", store.len(iso))
 	tmpl.Set("Clear", store.clear(iso))
+	tmpl.Set("Incr", store.incr(iso, "Incr", 1))
+	tmpl.Set("Decr", store.incr(iso, "Decr", -1))
+	tmpl.Set("SetNX", store.setNX(iso))
+	tmpl.Set("CAS", store.cas(iso))
+	tmpl.Set("TTL", store.ttl(iso))
+	tmpl.Set("Expire", store.expire(iso))
 	return tmpl
 }
 
@@ -299,7 +306,12 @@ func (store *Store) keys(iso *v8go.Isolate) *v8go.FunctionTemplate {
 			return bridge.JsException(info.Context(), msg)
 		}
 
-		keys := c.Keys()
+		pattern := []string{}
+		if args := info.Args(); len(args) > 0 && !args[0].IsNullOrUndefined() {
+			pattern = append(pattern, args[0].String())
+		}
+
+		keys := c.Keys(pattern...)
 		res, err := bridge.JsValue(info.Context(), keys)
 		if err != nil {
 			msg := fmt.Sprintf("Cache Keys: %s", err.Error())
@@ -323,6 +335,212 @@ func (store *Store) clear(iso *v8go.Isolate) *v8go.FunctionTemplate {
 	})
 }
 
+func (store *Store) incr(iso *v8go.Isolate, method string, sign int) *v8go.FunctionTemplate {
+	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
+		c, err := store.getLRU(info)
+		if err != nil {
+			msg := fmt.Sprintf("Cache %s: %s", method, err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		args := info.Args()
+		if len(args) < 1 {
+			msg := fmt.Sprintf("Cache %s: %s", method, "Missing parameters")
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		delta := 1
+		if len(args) > 1 {
+			delta = int(args[1].Integer())
+		}
+
+		value, err := c.Incr(args[0].String(), sign*delta)
+		if err != nil {
+			msg := fmt.Sprintf("Cache %s: %s", method, err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		res, err := v8go.NewValue(info.Context().Isolate(), int64(value))
+		if err != nil {
+			msg := fmt.Sprintf("Cache %s: %s", method, err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+		return res
+	})
+}
+
+func (store *Store) setNX(iso *v8go.Isolate) *v8go.FunctionTemplate {
+	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
+		c, err := store.getLRU(info)
+		if err != nil {
+			msg := fmt.Sprintf("Cache SetNX: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		args := info.Args()
+		if len(args) < 2 {
+			msg := fmt.Sprintf("Cache SetNX: %s", "Missing parameters")
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		v, err := bridge.GoValue(args[1], info.Context())
+		if err != nil {
+			msg := fmt.Sprintf("Cache SetNX: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		ttl := 0 * time.Second
+		if len(args) > 2 {
+			ttl = time.Duration(args[2].Integer()) * time.Second
+		}
+
+		ok, err := c.SetNX(args[0].String(), v, ttl)
+		if err != nil {
+			msg := fmt.Sprintf("Cache SetNX: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		res, err := v8go.NewValue(info.Context().Isolate(), ok)
+		if err != nil {
+			msg := fmt.Sprintf("Cache SetNX: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+		return res
+	})
+}
+
+func (store *Store) cas(iso *v8go.Isolate) *v8go.FunctionTemplate {
+	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
+		c, err := store.getLRU(info)
+		if err != nil {
+			msg := fmt.Sprintf("Cache CAS: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		args := info.Args()
+		if len(args) < 3 {
+			msg := fmt.Sprintf("Cache CAS: %s", "Missing parameters")
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		var old interface{}
+		if !args[1].IsNullOrUndefined() {
+			old, err = bridge.GoValue(args[1], info.Context())
+			if err != nil {
+				msg := fmt.Sprintf("Cache CAS: %s", err.Error())
+				log.Error(msg)
+				return bridge.JsException(info.Context(), msg)
+			}
+		}
+
+		v, err := bridge.GoValue(args[2], info.Context())
+		if err != nil {
+			msg := fmt.Sprintf("Cache CAS: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		ttl := 0 * time.Second
+		if len(args) > 3 {
+			ttl = time.Duration(args[3].Integer()) * time.Second
+		}
+
+		ok, err := c.CAS(args[0].String(), old, v, ttl)
+		if err != nil {
+			msg := fmt.Sprintf("Cache CAS: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		res, err := v8go.NewValue(info.Context().Isolate(), ok)
+		if err != nil {
+			msg := fmt.Sprintf("Cache CAS: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+		return res
+	})
+}
+
+func (store *Store) ttl(iso *v8go.Isolate) *v8go.FunctionTemplate {
+	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
+		c, err := store.getLRU(info)
+		if err != nil {
+			msg := fmt.Sprintf("Cache TTL: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		args := info.Args()
+		if len(args) < 1 {
+			msg := fmt.Sprintf("Cache TTL: %s", "Missing parameters")
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		// -1 the key never expires, -2 the key does not exist
+		seconds := int32(-2)
+		ttl, ok := c.TTL(args[0].String())
+		if ok && ttl < 0 {
+			seconds = -1
+		} else if ok {
+			seconds = int32(math.Ceil(ttl.Seconds()))
+		}
+
+		res, err := v8go.NewValue(info.Context().Isolate(), seconds)
+		if err != nil {
+			msg := fmt.Sprintf("Cache TTL: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+		return res
+	})
+}
+
+func (store *Store) expire(iso *v8go.Isolate) *v8go.FunctionTemplate {
+	return v8go.NewFunctionTemplate(iso, func(info *v8go.FunctionCallbackInfo) *v8go.Value {
+		c, err := store.getLRU(info)
+		if err != nil {
+			msg := fmt.Sprintf("Cache Expire: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		args := info.Args()
+		if len(args) < 2 {
+			msg := fmt.Sprintf("Cache Expire: %s", "Missing parameters")
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		ok, err := c.Expire(args[0].String(), time.Duration(args[1].Integer())*time.Second)
+		if err != nil {
+			msg := fmt.Sprintf("Cache Expire: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+
+		res, err := v8go.NewValue(info.Context().Isolate(), ok)
+		if err != nil {
+			msg := fmt.Sprintf("Cache Expire: %s", err.Error())
+			log.Error(msg)
+			return bridge.JsException(info.Context(), msg)
+		}
+		return res
+	})
+}
+
 func (store *Store) getLRU(info *v8go.FunctionCallbackInfo) (kv.Store, error) {
 	name, err := info.This().Get("name")
 	if err != nil {
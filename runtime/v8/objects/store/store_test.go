@@ -224,6 +224,43 @@ func testStoreObject(t *testing.T, c store.Store) {
 	flat = res.Dot()
 	assert.Equal(t, float64(0), res.Get("len"))
 	assert.Equal(t, []interface{}{}, res.Get("keys"))
+
+	// Atomic
+	v, err = ctx.RunScript(`
+		function atomic(){
+			var basic = new Store("basic")
+			basic.Incr("counter", 5)
+			var locked = basic.SetNX("lock", "foo", 60)
+			return {
+				"counter": basic.Decr("counter"),
+				"locked": locked,
+				"relocked": basic.SetNX("lock", "bar"),
+				"swapped": basic.CAS("lock", "foo", "bar"),
+				"ttl": basic.TTL("lock"),
+				"missing": basic.TTL("missing"),
+				"keys": basic.Keys("c*")
+			}
+		}
+		atomic()
+		`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err = bridge.GoValue(v, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res = any.Of(value).Map().MapStrAny
+	assert.Equal(t, float64(4), res.Get("counter"))
+	assert.True(t, res.Get("locked").(bool))
+	assert.False(t, res.Get("relocked").(bool))
+	assert.True(t, res.Get("swapped").(bool))
+	assert.Equal(t, float64(-1), res.Get("ttl"))
+	assert.Equal(t, float64(-2), res.Get("missing"))
+	assert.Equal(t, []interface{}{"counter"}, res.Get("keys"))
+	c.Clear()
 }
 
 func newStore(t *testing.T, c connector.Connector) store.Store {
@@ -0,0 +1,69 @@
+package schedule
+
+import (
+	"time"
+)
+
+// Run the run of the schedule
+type Run struct {
+	Start    time.Time   `json:"start"`
+	End      time.Time   `json:"end"`
+	Duration int64       `json:"duration"` // milliseconds
+	Result   interface{} `json:"result,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// Status the status of the schedule, the next and the previous run times are of the cron entry
+func (sch *Schedule) Status() map[string]interface{} {
+	res := map[string]interface{}{
+		"name":     sch.name,
+		"schedule": sch.Schedule,
+		"enabled":  sch.Enabled,
+		"next":     nil,
+		"prev":     nil,
+		"last":     nil,
+	}
+
+	if sch.cron != nil {
+		entry := sch.cron.Entry(sch.id)
+		if sch.Enabled && entry.Valid() {
+			next := entry.Next
+			if next.IsZero() { // the entry is scheduled after the cron was started
+				next = entry.Schedule.Next(time.Now())
+			}
+			res["next"] = next
+		}
+
+		if !entry.Prev.IsZero() {
+			res["prev"] = entry.Prev
+		}
+	}
+
+	if sch.leader != nil {
+		res["leader"] = sch.leader.IsLeader()
+	}
+
+	if sch.Timezone != "" {
+		res["timezone"] = sch.Timezone
+	}
+
+	sch.mutex.Lock()
+	defer sch.mutex.Unlock()
+	if len(sch.runs) > 0 {
+		res["last"] = sch.runs[0]
+	}
+	res["runs"] = sch.total
+	res["failures"] = sch.failures
+	res["skipped"] = sch.skipped
+	res["running"] = sch.active
+	return res
+}
+
+// Runs the recent runs of the schedule, the newest first
+func (sch *Schedule) Runs() []Run {
+	sch.mutex.Lock()
+	defer sch.mutex.Unlock()
+	runs := make([]Run, len(sch.runs))
+	copy(runs, sch.runs)
+	return runs
+}
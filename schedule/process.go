@@ -1,12 +1,16 @@
 package schedule
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/store"
 	"github.com/yaoapp/gou/task"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
@@ -17,8 +21,11 @@ var Schedules = map[string]*Schedule{}
 
 // ScheduleHandlers chedule process handlers
 var ScheduleHandlers = map[string]process.Handler{
-	"start": processScheduleStart,
-	"stop":  processScheduleStop,
+	"start":   processScheduleStart,
+	"stop":    processScheduleStop,
+	"status":  processScheduleStatus,
+	"history": processScheduleHistory,
+	"run":     processScheduleRun,
 }
 
 func init() {
@@ -33,9 +40,26 @@ type Schedule struct {
 	Schedule string        `json:"schedule"`
 	TaskName string        `json:"task,omitempty"`
 	Args     []interface{} `json:"args,omitempty"`
+	Leader   bool          `json:"leader,omitempty"`   // run on the leader instance only, the leader is elected via the store
+	Store    string        `json:"store,omitempty"`    // the store name for the leader election
+	History  int           `json:"history,omitempty"`  // the number of the recent runs kept in the history, default is 10
+	Timezone string        `json:"timezone,omitempty"` // the timezone of the cron expression, e.g. Asia/Shanghai. default is the local timezone
+	Overlap  string        `json:"overlap,omitempty"`  // the policy when the previous run is still running: allow (default), skip or queue
+	Jitter   int           `json:"jitter,omitempty"`   // the maximum random delay before each scheduled run (seconds)
+	Timeout  int           `json:"timeout,omitempty"`  // the max execution time of a run (seconds), the run fails and its context is canceled after the timeout, the process is not killed. 0 means no limit
 	id       cron.EntryID
 	Enabled  bool
 	cron     *cron.Cron
+	leader   *store.Leader
+	exec     func(ctx context.Context) (interface{}, error)
+	runs     []Run           // the recent runs, the newest first
+	total    int             // the number of the runs
+	failures int             // the number of the failed runs
+	skipped  int             // the number of the runs skipped by the overlap policy
+	active   int             // the number of the running runs
+	mutex    sync.Mutex      // guards the history and the counters
+	running  sync.Mutex      // held by the running run if the overlap policy is skip or queue
+	overtime <-chan struct{} // closed when the process exceeded the max execution time returns
 }
 
 // Load load schedule
@@ -52,13 +76,36 @@ func Load(file string, name string) (*Schedule, error) {
 		return nil, err
 	}
 
-	handler, err := sch.handler()
+	switch sch.Overlap {
+	case "", "allow", "skip", "queue":
+	default:
+		return nil, fmt.Errorf("Schedule %s overlap %s does not support (allow, skip or queue)", name, sch.Overlap)
+	}
+
+	options := []cron.Option{}
+	if sch.Timezone != "" {
+		location, err := time.LoadLocation(sch.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("Schedule %s timezone %s", name, err.Error())
+		}
+		options = append(options, cron.WithLocation(location))
+	}
+
+	sch.exec, err = sch.handler()
 	if err != nil {
 		return nil, err
 	}
 
-	c := cron.New()
-	id, err := c.AddFunc(sch.Schedule, handler)
+	run := sch.scheduled
+	if sch.Leader {
+		run, err = sch.leaderOnly(run)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := cron.New(options...)
+	id, err := c.AddFunc(sch.Schedule, sch.delay(run))
 
 	if err != nil {
 		return nil, err
@@ -94,8 +141,8 @@ func (sch *Schedule) parseArgs() {
 	sch.Args = args
 }
 
-// handler task or process
-func (sch *Schedule) handler() (func(), error) {
+// handler task or process, the result is the job id of the task or the result of the process
+func (sch *Schedule) handler() (func(ctx context.Context) (interface{}, error), error) {
 	sch.parseArgs()
 
 	if sch.TaskName != "" {
@@ -103,27 +150,67 @@ func (sch *Schedule) handler() (func(), error) {
 		if !has {
 			return nil, fmt.Errorf("%s was not loaded", sch.TaskName)
 		}
-		return func() { task.Tasks[sch.TaskName].Add(sch.Args...) }, nil
+		return func(ctx context.Context) (interface{}, error) {
+			id, err := task.Tasks[sch.TaskName].Add(sch.Args...)
+			if err != nil {
+				log.Error("[Schedule] %s %s %s", sch.name, sch.TaskName, err)
+				return nil, err
+			}
+			return id, nil
+		}, nil
 	} else if sch.Process != "" {
-		return func() {
+		return func(ctx context.Context) (interface{}, error) {
 			p, err := process.Of(sch.Process, sch.Args...)
 			if err != nil {
 				log.Error("[Schedule] %s %s %s", sch.name, sch.Process, err)
+				return nil, err
+			}
+
+			res, overtime, err := wait(ctx, p.WithContext(ctx))
+			if overtime != nil {
+				sch.mutex.Lock()
+				sch.overtime = overtime
+				sch.mutex.Unlock()
 			}
 
-			_, err = p.Exec()
 			if err != nil {
 				log.Error("[Schedule] %s %s %s", sch.name, sch.Process, err)
+				return nil, err
 			}
+			return res, nil
 		}, nil
 	}
 
 	return nil, fmt.Errorf("process or task is required")
 }
 
+// leaderOnly skip the handler if the instance is not the leader
+func (sch *Schedule) leaderOnly(handler func()) (func(), error) {
+	if sch.Store == "" {
+		return nil, fmt.Errorf("store is required for the leader only schedule")
+	}
+
+	stor, has := store.Pools[sch.Store]
+	if !has {
+		return nil, fmt.Errorf("store %s was not loaded", sch.Store)
+	}
+
+	sch.leader = store.NewLeader(stor, fmt.Sprintf("schedule:%s", sch.name), 0)
+	return func() {
+		if !sch.leader.IsLeader() {
+			log.Trace("[Schedule] %s skipped, the instance is not the leader", sch.name)
+			return
+		}
+		handler()
+	}, nil
+}
+
 // Start start the schedule
 func (sch *Schedule) Start() {
 	sch.Enabled = true
+	if sch.leader != nil {
+		sch.leader.Start()
+	}
 	sch.cron.Start()
 }
 
@@ -131,6 +218,9 @@ func (sch *Schedule) Start() {
 func (sch *Schedule) Stop() {
 	sch.Enabled = false
 	sch.cron.Stop()
+	if sch.leader != nil {
+		sch.leader.Stop()
+	}
 }
 
 // processScheduleStart
@@ -146,3 +236,25 @@ func processScheduleStop(process *process.Process) interface{} {
 	sch.Stop()
 	return map[string]interface{}{"enabled": sch.Enabled}
 }
+
+// processScheduleStatus
+func processScheduleStatus(process *process.Process) interface{} {
+	sch := Select(process.ID)
+	return sch.Status()
+}
+
+// processScheduleHistory
+func processScheduleHistory(process *process.Process) interface{} {
+	sch := Select(process.ID)
+	return sch.Runs()
+}
+
+// processScheduleRun schedules.<name>.Run() run the schedule immediately, returns the run
+func processScheduleRun(process *process.Process) interface{} {
+	sch := Select(process.ID)
+	run, err := sch.Run()
+	if err == ErrSkipped {
+		exception.New("Schedule %s Run: %s", 409, process.ID, err).Throw()
+	}
+	return run
+}
@@ -1,16 +1,20 @@
 package schedule
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/process"
 	v8 "github.com/yaoapp/gou/runtime/v8"
+	"github.com/yaoapp/gou/store"
 	"github.com/yaoapp/gou/task"
 )
 
@@ -60,6 +64,183 @@ func TestScheduleProcesses(t *testing.T) {
 	}
 
 	assert.False(t, res.(map[string]interface{})["enabled"].(bool))
+
+	res, err = process.New("schedules.mail.Status").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "mail", res.(map[string]interface{})["name"])
+	assert.Nil(t, res.(map[string]interface{})["next"])
+
+	res, err = process.New("schedules.mail.History").Exec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, res, 0)
+}
+
+func TestScheduleStatus(t *testing.T) {
+	sch := &Schedule{name: "unit", Schedule: "* * * * *", History: 2}
+	count := 0
+	sch.exec = func(ctx context.Context) (interface{}, error) {
+		count++
+		if count == 2 {
+			return nil, fmt.Errorf("failed")
+		}
+		return count, nil
+	}
+
+	sch.cron = cron.New()
+	id, err := sch.cron.AddFunc(sch.Schedule, sch.scheduled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch.id = id
+
+	sch.scheduled()
+	sch.scheduled()
+	run, err := sch.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, run.Result)
+	status := sch.Status()
+	assert.Equal(t, 3, status["runs"])
+	assert.Equal(t, 1, status["failures"])
+	assert.Equal(t, 3, status["last"].(Run).Result)
+	assert.Nil(t, status["next"])
+	assert.Nil(t, status["prev"])
+
+	// the recent runs are kept
+	runs := sch.Runs()
+	assert.Len(t, runs, 2)
+	assert.Equal(t, 3, runs[0].Result)
+	assert.Equal(t, "failed", runs[1].Error)
+
+	sch.Start()
+	defer sch.Stop()
+	next, ok := sch.Status()["next"].(time.Time)
+	assert.True(t, ok)
+	assert.True(t, next.After(time.Now()))
+	assert.True(t, next.Before(time.Now().Add(time.Minute+time.Second)))
+}
+
+func TestScheduleRun(t *testing.T) {
+	process.Register("unit.schedule.Sleep", func(process *process.Process) interface{} {
+		time.Sleep(time.Duration(process.ArgsInt(0)) * time.Millisecond)
+		return process.ArgsInt(0)
+	})
+
+	// skip the run if the previous run is still running
+	sch := &Schedule{name: "unit", Process: "unit.schedule.Sleep", Args: []interface{}{100}, Overlap: "skip"}
+	exec, err := sch.handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sch.exec = exec
+
+	go sch.Run()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, sch.Status()["running"])
+	_, err = sch.Run()
+	assert.Equal(t, ErrSkipped, err)
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, 1, sch.Status()["runs"])
+	assert.Equal(t, 1, sch.Status()["skipped"])
+
+	// queue the run until the previous run is completed
+	sch.Overlap = "queue"
+	go sch.Run()
+	time.Sleep(20 * time.Millisecond)
+	run, err := sch.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 100, run.Result)
+	assert.Equal(t, 3, sch.Status()["runs"])
+
+	// the run is canceled after the max execution time
+	sch.Overlap = ""
+	sch.Timeout = 1
+	sch.Args = []interface{}{1500}
+	sch.parseArgs()
+	run, err = sch.Run()
+	assert.NotNil(t, err)
+	assert.Contains(t, run.Error, "max execution time")
+	assert.Less(t, run.Duration, int64(1400))
+
+	// the overlap lock is held until the process exceeded the max execution time returns
+	sch.Overlap = "skip"
+	run, err = sch.Run()
+	assert.Contains(t, run.Error, "max execution time")
+	_, err = sch.Run()
+	assert.Equal(t, ErrSkipped, err)
+	time.Sleep(700 * time.Millisecond)
+	sch.Timeout = 0
+	sch.Args = []interface{}{10}
+	sch.parseArgs()
+	run, err = sch.Run()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, run.Result)
+
+	// the invalid process is failed without panic
+	invalid := &Schedule{name: "unit", Process: "invalid"}
+	exec, err = invalid.handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid.exec = exec
+	assert.NotPanics(t, func() {
+		run, err = invalid.Run()
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, invalid.Status()["failures"])
+
+	_, err = (&Schedule{name: "unit"}).Run()
+	assert.NotNil(t, err)
+}
+
+func TestScheduleJitter(t *testing.T) {
+	sch := &Schedule{name: "unit"}
+	count := 0
+	sch.delay(func() { count++ })()
+	assert.Equal(t, 1, count)
+
+	sch.Jitter = 1
+	start := time.Now()
+	sch.delay(func() { count++ })()
+	assert.Equal(t, 2, count)
+	assert.Less(t, time.Since(start), 1100*time.Millisecond)
+}
+
+func TestScheduleLeader(t *testing.T) {
+	kv, err := store.New(nil, store.Option{"size": 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Pools["unit.leader"] = kv
+	defer delete(store.Pools, "unit.leader")
+
+	count := 0
+	handlers := []func(){}
+	instances := []*Schedule{}
+	for i := 0; i < 2; i++ {
+		sch := &Schedule{name: "unit", Leader: true, Store: "unit.leader"}
+		handler, err := sch.leaderOnly(func() { count++ })
+		if err != nil {
+			t.Fatal(err)
+		}
+		sch.leader.Start()
+		defer sch.leader.Stop()
+		handlers = append(handlers, handler)
+		instances = append(instances, sch)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	for _, handler := range handlers {
+		handler()
+	}
+	assert.Equal(t, 1, count)
+	assert.NotEqual(t, instances[0].leader.IsLeader(), instances[1].leader.IsLeader())
+
+	_, err = (&Schedule{name: "unit", Leader: true, Store: "unit.missing"}).leaderOnly(func() {})
+	assert.NotNil(t, err)
 }
 
 func scheduleLoad(t *testing.T) {
@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/log"
+)
+
+// ErrSkipped the run was skipped, the previous run is still running
+var ErrSkipped = errors.New("the previous run is still running")
+
+// Run run the schedule immediately, the overlap policy is applied. returns the run
+// ErrSkipped is returned if the overlap policy is skip and the previous run is still running
+func (sch *Schedule) Run() (Run, error) {
+	if sch.exec == nil {
+		return Run{}, fmt.Errorf("schedule %s was not loaded", sch.name)
+	}
+
+	switch sch.Overlap {
+	case "skip":
+		if !sch.running.TryLock() {
+			sch.mutex.Lock()
+			sch.skipped++
+			sch.mutex.Unlock()
+			return Run{}, ErrSkipped
+		}
+		defer sch.unlock()
+
+	case "queue":
+		sch.running.Lock()
+		defer sch.unlock()
+	}
+
+	return sch.execute()
+}
+
+// unlock release the overlap lock, the lock is held until the process exceeded the max execution time returns
+func (sch *Schedule) unlock() {
+	sch.mutex.Lock()
+	overtime := sch.overtime
+	sch.overtime = nil
+	sch.mutex.Unlock()
+
+	if overtime == nil {
+		sch.running.Unlock()
+		return
+	}
+
+	go func() {
+		<-overtime
+		sch.running.Unlock()
+	}()
+}
+
+// scheduled the run triggered by the cron
+func (sch *Schedule) scheduled() {
+	_, err := sch.Run()
+	if err == ErrSkipped {
+		log.Warn("[Schedule] %s skipped, %s", sch.name, err.Error())
+	}
+}
+
+// delay delay the start of the scheduled run by the random jitter
+func (sch *Schedule) delay(run func()) func() {
+	if sch.Jitter <= 0 {
+		return run
+	}
+
+	return func() {
+		time.Sleep(time.Duration(rand.Int63n(int64(sch.Jitter) * int64(time.Second))))
+		run()
+	}
+}
+
+// execute execute the handler and record the run to the history
+func (sch *Schedule) execute() (Run, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if sch.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(sch.Timeout)*time.Second)
+	}
+	defer cancel()
+
+	sch.mutex.Lock()
+	sch.active++
+	sch.mutex.Unlock()
+
+	run := Run{Start: time.Now()}
+	res, err := sch.exec(ctx)
+	run.End = time.Now()
+	run.Duration = run.End.Sub(run.Start).Milliseconds()
+	run.Result = res
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	size := sch.History
+	if size <= 0 {
+		size = 10
+	}
+
+	sch.mutex.Lock()
+	defer sch.mutex.Unlock()
+	sch.active--
+	sch.total++
+	if err != nil {
+		sch.failures++
+	}
+
+	sch.runs = append([]Run{run}, sch.runs...)
+	if len(sch.runs) > size {
+		sch.runs = sch.runs[:size]
+	}
+	return run, err
+}
+
+// wait execute the process, the run is failed if the context is done before the process is completed.
+// the process keeps running after the context is done, the overtime channel is closed when it returns.
+func wait(ctx context.Context, p *process.Process) (value interface{}, overtime <-chan struct{}, err error) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+
+	ch := make(chan result, 1)
+	go func() {
+		value, err := p.Exec()
+		ch <- result{value: value, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		done := make(chan struct{})
+		go func() {
+			<-ch
+			close(done)
+		}()
+
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, done, fmt.Errorf("the run exceeded the max execution time (%s)", ctx.Err().Error())
+		}
+		return nil, done, ctx.Err()
+
+	case res := <-ch:
+		return res.value, nil, res.err
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/observe"
 	"github.com/yaoapp/gou/websocket"
 	"github.com/yaoapp/kun/log"
 )
@@ -84,7 +85,11 @@ func (server *Server) Start() error {
 
 	// network preparing
 	server.addr = listener.Addr()
-	srv := &http.Server{Addr: server.addr.String(), Handler: server.router}
+	var handler http.Handler = server.router
+	if server.option.Observe != nil {
+		handler = observe.Handler(server.router, *server.option.Observe)
+	}
+	srv := &http.Server{Addr: server.addr.String(), Handler: handler}
 
 	// close server
 	defer func() {
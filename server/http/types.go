@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/yaoapp/gou/observe"
 	"github.com/yaoapp/gou/websocket"
 )
 
@@ -32,11 +33,12 @@ const (
 
 // Option the http server opiton
 type Option struct {
-	Port    int           `json:"port,omitempty"`
-	Host    string        `json:"host,omitempty"`
-	Timeout time.Duration `json:"timeout,omitempty"`
-	Root    string        `json:"root,omitempty"`   // API Root
-	Allows  []string      `json:"allows,omitempty"` // CORS Domains
+	Port    int             `json:"port,omitempty"`
+	Host    string          `json:"host,omitempty"`
+	Timeout time.Duration   `json:"timeout,omitempty"`
+	Root    string          `json:"root,omitempty"`    // API Root
+	Allows  []string        `json:"allows,omitempty"`  // CORS Domains
+	Observe *observe.Option `json:"observe,omitempty"` // Access logs, metrics and trace context
 }
 
 // Server the http server opiton
@@ -141,3 +141,33 @@ func (bunt *BuntDB) Dump(id string) (map[string]interface{}, error) {
 	})
 	return res, err
 }
+
+// SetFlash set the one-shot value
+func (bunt *BuntDB) SetFlash(id string, key string, value interface{}, timeout time.Duration) error {
+	return bunt.Set(id, flashKey(key), value, timeout)
+}
+
+// GetFlash get the one-shot value and delete it
+func (bunt *BuntDB) GetFlash(id string, key string) (interface{}, error) {
+	skey := fmt.Sprintf("%s:%s:%s", "yao:session", id, flashKey(key))
+	var value interface{} = nil
+	err := bunt.db.Update(func(tx *buntdb.Tx) error {
+		val, err := tx.Delete(skey)
+		if err != nil {
+			if err == buntdb.ErrNotFound {
+				return nil
+			}
+			log.Error("Session buntdb GetFlash: %s ERROR:%s", skey, err.Error())
+			return err
+		}
+
+		err = jsoniter.Unmarshal([]byte(val), &value)
+		if err != nil {
+			log.Error("Session buntdb GetFlash JSON: %s val: %s ERROR:%s", skey, val, err.Error())
+			return err
+		}
+		return nil
+	})
+
+	return value, err
+}
@@ -76,3 +76,28 @@ func TestBuntDBMustDump(t *testing.T) {
 	data = ss.MustDump()
 	assert.Equal(t, map[string]interface{}{}, data)
 }
+
+func TestBuntDBFlash(t *testing.T) {
+	ss := Use("buntdb").ID(ID()).Expire(200 * time.Millisecond)
+	ss.MustSetFlash("notice", map[string]interface{}{"message": "saved"})
+	assert.Equal(t, map[string]interface{}{"message": "saved"}, ss.MustGetFlash("notice"))
+	assert.Nil(t, ss.MustGetFlash("notice"))
+
+	ss.MustSetFlash("notice", "expired")
+	time.Sleep(300 * time.Millisecond)
+	assert.Nil(t, ss.MustGetFlash("notice"))
+}
+
+func TestBuntDBCSRFToken(t *testing.T) {
+	ss := Use("buntdb").ID(ID())
+	token, err := ss.CSRFToken()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, token)
+
+	again, _ := ss.CSRFToken()
+	assert.Equal(t, token, again)
+	assert.True(t, ss.VerifyCSRF(token))
+	assert.False(t, ss.VerifyCSRF("invalid"))
+	assert.False(t, ss.VerifyCSRF(""))
+	assert.False(t, Use("buntdb").ID(ID()).VerifyCSRF(token))
+}
@@ -5,19 +5,29 @@ import (
 	"time"
 
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
 )
 
 // SessionHandlers 模型运行器
 var SessionHandlers = map[string]process.Handler{
-	"id":      processID,
-	"get":     processGet,
-	"del":     processDel,
-	"set":     processSet,
-	"dump":    processDump,
-	"getmany": processGetMany,
-	"setmany": processSetMany,
-	"delmany": processDelMany,
+	"id":        processID,
+	"get":       processGet,
+	"del":       processDel,
+	"set":       processSet,
+	"dump":      processDump,
+	"getmany":   processGetMany,
+	"setmany":   processSetMany,
+	"delmany":   processDelMany,
+	"touch":     processTouch,
+	"destroy":   processDestroy,
+	"rotate":    processRotate,
+	"bind":      processBind,
+	"sessions":  processSessions,
+	"revoke":    processRevoke,
+	"setflash":  processSetFlash,
+	"getflash":  processGetFlash,
+	"csrftoken": processCSRFToken,
 }
 
 func init() {
@@ -164,6 +174,137 @@ func processSetMany(process *process.Process) interface{} {
 	return nil
 }
 
+// processTouch session.Touch(timeout, sid) extend the expiration of the session, the timeout is in seconds
+func processTouch(process *process.Process) interface{} {
+	ss := setSession(process)
+	if process.NumOfArgs() == 2 {
+		ss = Global().ID(process.ArgsString(1))
+	}
+
+	var err error
+	if process.NumOfArgs() > 0 && process.Args[0] != nil {
+		err = ss.Touch(time.Duration(process.ArgsInt(0)) * time.Second)
+	} else {
+		err = ss.Touch()
+	}
+
+	if err != nil {
+		exception.New("session.Touch: %s", 500, err.Error()).Throw()
+	}
+	return nil
+}
+
+// processDestroy session.Destroy(sid) remove all the data of the session
+func processDestroy(process *process.Process) interface{} {
+	ss := setSession(process)
+	if process.NumOfArgs() == 1 {
+		ss = Global().ID(process.ArgsString(0))
+	}
+
+	err := ss.Destroy()
+	if err != nil {
+		exception.New("session.Destroy: %s", 500, err.Error()).Throw()
+	}
+	return nil
+}
+
+// processRotate session.Rotate(sid) regenerate the session id, returns the new session id
+func processRotate(process *process.Process) interface{} {
+	ss := setSession(process)
+	if process.NumOfArgs() == 1 {
+		ss = Global().ID(process.ArgsString(0))
+	}
+
+	err := ss.Rotate()
+	if err != nil {
+		exception.New("session.Rotate: %s", 500, err.Error()).Throw()
+	}
+	return ss.GetID()
+}
+
+// processBind session.Bind(user, sid) index the session to the user
+func processBind(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	ss := setSession(process)
+	if process.NumOfArgs() == 2 {
+		ss = Global().ID(process.ArgsString(1))
+	}
+
+	err := ss.Bind(fmt.Sprintf("%v", process.Args[0]))
+	if err != nil {
+		exception.New("session.Bind: %s", 500, err.Error()).Throw()
+	}
+	return nil
+}
+
+// processSessions session.Sessions(user) the session ids of the user
+func processSessions(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	ids, err := Global().Sessions(fmt.Sprintf("%v", process.Args[0]))
+	if err != nil {
+		exception.New("session.Sessions: %s", 500, err.Error()).Throw()
+	}
+	return ids
+}
+
+// processRevoke session.Revoke(user) destroy all the sessions of the user, returns the number of the destroyed sessions
+func processRevoke(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	revoked, err := Global().Revoke(fmt.Sprintf("%v", process.Args[0]))
+	if err != nil {
+		exception.New("session.Revoke: %s", 500, err.Error()).Throw()
+	}
+	return revoked
+}
+
+// processSetFlash session.SetFlash(key, value, sid) set the one-shot value
+func processSetFlash(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	ss := setSession(process)
+	if process.NumOfArgs() == 3 {
+		ss = Global().ID(process.ArgsString(2))
+	}
+
+	err := ss.SetFlash(process.ArgsString(0), process.Args[1])
+	if err != nil {
+		exception.New("session.SetFlash: %s", 500, err.Error()).Throw()
+	}
+	return nil
+}
+
+// processGetFlash session.GetFlash(key, sid) get the one-shot value and delete it
+func processGetFlash(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	ss := setSession(process)
+	if process.NumOfArgs() == 2 {
+		ss = Global().ID(process.ArgsString(1))
+	}
+
+	value, err := ss.GetFlash(process.ArgsString(0))
+	if err != nil {
+		exception.New("session.GetFlash: %s", 500, err.Error()).Throw()
+	}
+	return value
+}
+
+// processCSRFToken session.CSRFToken(sid) the CSRF token of the session
+func processCSRFToken(process *process.Process) interface{} {
+	ss := setSession(process)
+	if process.NumOfArgs() == 1 {
+		ss = Global().ID(process.ArgsString(0))
+	}
+
+	if ss.GetID() == "" {
+		exception.New("session.CSRFToken: the session id is required", 400).Throw()
+	}
+
+	token, err := ss.CSRFToken()
+	if err != nil {
+		exception.New("session.CSRFToken: %s", 500, err.Error()).Throw()
+	}
+	return token
+}
+
 func setSession(process *process.Process) *Session {
 	ss := Global()
 	if process.Sid != "" {
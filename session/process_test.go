@@ -113,6 +113,45 @@ func TestDump(t *testing.T) {
 	assert.Equal(t, "UID-05-DATA", r.Get("user_data"))
 }
 
+func TestTouchDestroy(t *testing.T) {
+	prepare(t)
+	execOf(t, "session.Set", "user_id", "UID-11", 1)
+	assert.NotPanics(t, func() {
+		execOf(t, "session.Touch", 3)
+	})
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, "UID-11", execOf(t, "session.Get", "user_id"))
+
+	assert.NotPanics(t, func() {
+		execOf(t, "session.Destroy")
+	})
+	assert.Equal(t, nil, execOf(t, "session.Get", "user_id"))
+
+	execOfSID(t, "SID-UNIT-TEST-3", "session.Set", "user_id", "UID-12")
+	sid := execOfSID(t, "SID-UNIT-TEST-3", "session.Rotate")
+	assert.NotEqual(t, "SID-UNIT-TEST-3", sid)
+	assert.Equal(t, "UID-12", execOf(t, "session.Get", "user_id", sid))
+	assert.Equal(t, nil, execOf(t, "session.Get", "user_id", "SID-UNIT-TEST-3"))
+}
+
+func TestFlashCSRFToken(t *testing.T) {
+	prepare(t)
+	execOf(t, "session.SetFlash", "notice", "saved")
+	assert.Equal(t, "saved", execOf(t, "session.GetFlash", "notice"))
+	assert.Equal(t, nil, execOf(t, "session.GetFlash", "notice"))
+
+	execOf(t, "session.SetFlash", "notice", "saved", "SID-UNIT-TEST-4")
+	assert.Equal(t, nil, execOf(t, "session.GetFlash", "notice"))
+	assert.Equal(t, "saved", execOfSID(t, "SID-UNIT-TEST-4", "session.GetFlash", "notice"))
+
+	token := execOf(t, "session.CSRFToken")
+	assert.NotEmpty(t, token)
+	assert.Equal(t, token, execOf(t, "session.CSRFToken"))
+	assert.NotEqual(t, token, execOf(t, "session.CSRFToken", "SID-UNIT-TEST-4"))
+	assert.True(t, Global().ID("SID-UNIT-TEST").VerifyCSRF(token.(string)))
+}
+
 func TestLang(t *testing.T) {
 	prepare(t)
 	p := makeP(t, "session.Get")
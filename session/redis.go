@@ -12,6 +12,15 @@ import (
 	"github.com/yaoapp/kun/log"
 )
 
+// flashScript get the value and delete it atomically
+var flashScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value then
+	redis.call("DEL", KEYS[1])
+end
+return value
+`)
+
 // Redis session store
 type Redis struct {
 	timeout time.Duration
@@ -141,3 +150,30 @@ func (redis *Redis) Dump(id string) (map[string]interface{}, error) {
 	}
 	return res, nil
 }
+
+// SetFlash set the one-shot value
+func (redis *Redis) SetFlash(id string, key string, value interface{}, timeout time.Duration) error {
+	return redis.Set(id, flashKey(key), value, timeout)
+}
+
+// GetFlash get the one-shot value and delete it
+func (redis *Redis) GetFlash(id string, key string) (interface{}, error) {
+	skey := fmt.Sprintf("%s:%s:%s", "yao:session", id, flashKey(key))
+	val, err := flashScript.Run(context.Background(), redis.rdb, []string{skey}).Text()
+	if err != nil {
+		if "redis: nil" == err.Error() {
+			return nil, nil
+		}
+		log.Error("Session redis GetFlash: %s ERROR:%s", skey, err.Error())
+		return nil, err
+	}
+
+	var value interface{}
+	err = jsoniter.Unmarshal([]byte(val), &value)
+	if err != nil {
+		log.Error("Session redis GetFlash JSON: %s val: %s ERROR:%s", skey, val, err.Error())
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -96,3 +96,14 @@ func TestRedisMustDump(t *testing.T) {
 	data = ss.MustDump()
 	assert.Equal(t, map[string]interface{}{}, data)
 }
+
+func TestRedisFlash(t *testing.T) {
+	ss := Use("redis").ID(ID()).Expire(200 * time.Millisecond)
+	ss.MustSetFlash("notice", map[string]interface{}{"message": "saved"})
+	assert.Equal(t, map[string]interface{}{"message": "saved"}, ss.MustGetFlash("notice"))
+	assert.Nil(t, ss.MustGetFlash("notice"))
+
+	ss.MustSetFlash("notice", "expired")
+	time.Sleep(300 * time.Millisecond)
+	assert.Nil(t, ss.MustGetFlash("notice"))
+}
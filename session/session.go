@@ -2,7 +2,9 @@ package session
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
+	"fmt"
 	"io"
 	"time"
 
@@ -16,6 +18,9 @@ var Managers = map[string]Manager{}
 // Timeout 默认有效时间
 var Timeout time.Duration = 3600 * time.Second
 
+// CSRFKey the session key of the CSRF token
+const CSRFKey = "__csrf_token"
+
 // Name 默认为会话管理器
 var Name string = "buntdb"
 
@@ -187,6 +192,175 @@ func (session *Session) MustDump() map[string]interface{} {
 	return value
 }
 
+// Touch extend the expiration of the session by the timeout, the timeout of the session is used if it is not given
+func (session *Session) Touch(timeout ...time.Duration) error {
+	ttl := session.timeout
+	if len(timeout) > 0 {
+		ttl = timeout[0]
+	}
+
+	if manager, ok := session.Manager.(Lifecycle); ok {
+		return manager.Touch(session.id, ttl)
+	}
+
+	data, err := session.Dump()
+	if err != nil {
+		return err
+	}
+	return session.SetManyWithEx(data, ttl)
+}
+
+// Destroy remove all the data of the session
+func (session *Session) Destroy() error {
+	if manager, ok := session.Manager.(Lifecycle); ok {
+		return manager.Destroy(session.id)
+	}
+
+	data, err := session.Dump()
+	if err != nil {
+		return err
+	}
+
+	for key := range data {
+		if err := session.Del(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rotate regenerate the session id and move the data to the new id, call it when the privilege is changed (e.g. login)
+func (session *Session) Rotate() error {
+	id := ID()
+	if manager, ok := session.Manager.(Lifecycle); ok {
+		if err := manager.Rotate(session.id, id); err != nil {
+			return err
+		}
+		session.id = id
+		return nil
+	}
+
+	data, err := session.Dump()
+	if err != nil {
+		return err
+	}
+
+	err = session.Destroy()
+	if err != nil {
+		return err
+	}
+
+	session.id = id
+	return session.SetMany(data)
+}
+
+// Bind index the session to the user, the sessions of the user could be listed and revoked
+func (session *Session) Bind(user string) error {
+	manager, err := session.lifecycle()
+	if err != nil {
+		return err
+	}
+	return manager.Bind(session.id, user)
+}
+
+// Sessions the session ids of the user
+func (session *Session) Sessions(user string) ([]string, error) {
+	manager, err := session.lifecycle()
+	if err != nil {
+		return nil, err
+	}
+	return manager.Sessions(user)
+}
+
+// Revoke destroy all the sessions of the user, returns the number of the destroyed sessions
+func (session *Session) Revoke(user string) (int, error) {
+	manager, err := session.lifecycle()
+	if err != nil {
+		return 0, err
+	}
+	return manager.Revoke(user)
+}
+
+// SetFlash set the one-shot value, it is deleted after it is read by GetFlash
+func (session *Session) SetFlash(key string, value interface{}) error {
+	if manager, ok := session.Manager.(Flash); ok {
+		return manager.SetFlash(session.id, key, value, session.timeout)
+	}
+	return session.Set(flashKey(key), value)
+}
+
+// MustSetFlash set the one-shot value
+func (session *Session) MustSetFlash(key string, value interface{}) {
+	err := session.SetFlash(key, value)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+}
+
+// GetFlash get the one-shot value and delete it
+func (session *Session) GetFlash(key string) (interface{}, error) {
+	if manager, ok := session.Manager.(Flash); ok {
+		return manager.GetFlash(session.id, key)
+	}
+
+	value, err := session.Get(flashKey(key))
+	if err != nil || value == nil {
+		return value, err
+	}
+	return value, session.Del(flashKey(key))
+}
+
+// MustGetFlash get the one-shot value and delete it
+func (session *Session) MustGetFlash(key string) interface{} {
+	value, err := session.GetFlash(key)
+	if err != nil {
+		exception.Err(err, 500).Throw()
+	}
+	return value
+}
+
+// CSRFToken the CSRF token of the session, the token is generated on the first call and expires with the session
+func (session *Session) CSRFToken() (string, error) {
+	value, err := session.Get(CSRFKey)
+	if err != nil {
+		return "", err
+	}
+
+	if token, ok := value.(string); ok && token != "" {
+		return token, nil
+	}
+
+	token := ID()
+	return token, session.Set(CSRFKey, token)
+}
+
+// VerifyCSRF check if the token is the CSRF token of the session
+func (session *Session) VerifyCSRF(token string) bool {
+	if token == "" {
+		return false
+	}
+
+	value, err := session.Get(CSRFKey)
+	if err != nil {
+		return false
+	}
+
+	expected, ok := value.(string)
+	return ok && expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+func flashKey(key string) string {
+	return "__flash:" + key
+}
+
+func (session *Session) lifecycle() (Lifecycle, error) {
+	manager, ok := session.Manager.(Lifecycle)
+	if !ok {
+		return nil, fmt.Errorf("the session manager %s does not support the user index", session.name)
+	}
+	return manager, nil
+}
+
 // // Cookie 从Cookie中读取 Session ID
 // func (session *Session) Cookie(name string) {}
 
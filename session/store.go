@@ -0,0 +1,346 @@
+package session
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/store"
+	"github.com/yaoapp/kun/log"
+)
+
+// casRetries the number of the retries when the session was modified by the others at the same time
+const casRetries = 10
+
+// Store the session manager on the key-value store of store.Pools (redis, mongo, lru...)
+// the data of a session is saved in one key <prefix><id>, the sessions of a user are indexed by the keys <prefix>user:<hex(user)>:<id>
+type Store struct {
+	store  store.Store
+	option StoreOption
+}
+
+// StoreOption the option of the store session manager
+type StoreOption struct {
+	Prefix  string // the key prefix, default is "session:"
+	Sliding bool   // the sliding expiration, the expiration of the session is extended on every access
+}
+
+// record the session data saved in the store
+type record struct {
+	User   string            `json:"user,omitempty"`
+	Fields map[string]*field `json:"fields"`
+}
+
+// field the session value
+type field struct {
+	Value     interface{} `json:"value"`
+	TTL       int64       `json:"ttl,omitempty"`        // milliseconds, 0 means the value never expires
+	ExpiredAt int64       `json:"expired_at,omitempty"` // unix milliseconds
+}
+
+// NewStore create a session manager on the store of store.Pools
+func NewStore(name string, option StoreOption) (*Store, error) {
+	stor, has := store.Pools[name]
+	if !has {
+		return nil, fmt.Errorf("store %s was not loaded", name)
+	}
+
+	if option.Prefix == "" {
+		option.Prefix = "session:"
+	}
+	return &Store{store: stor, option: option}, nil
+}
+
+// Init initialization
+func (s *Store) Init() {}
+
+// Set session value
+func (s *Store) Set(id string, key string, value interface{}, timeout time.Duration) error {
+	return s.update(id, func(rec *record) {
+		rec.Fields[key] = &field{Value: value, TTL: timeout.Milliseconds()}
+		rec.Fields[key].touch(time.Now(), 0)
+	})
+}
+
+// Get session value
+func (s *Store) Get(id string, key string) (interface{}, error) {
+	rec, err := s.alive(id)
+	if err != nil || rec == nil {
+		return nil, err
+	}
+
+	if f, has := rec.Fields[key]; has {
+		return f.Value, nil
+	}
+	return nil, nil
+}
+
+// Del session value
+func (s *Store) Del(id string, key string) error {
+	return s.update(id, func(rec *record) {
+		delete(rec.Fields, key)
+	})
+}
+
+// Dump session data
+func (s *Store) Dump(id string) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	rec, err := s.alive(id)
+	if err != nil || rec == nil {
+		return res, err
+	}
+
+	for key, f := range rec.Fields {
+		res[key] = f.Value
+	}
+	return res, nil
+}
+
+// Touch extend the expiration of the session, each value is extended by its own ttl if the timeout is 0
+func (s *Store) Touch(id string, timeout time.Duration) error {
+	return s.update(id, func(rec *record) {
+		now := time.Now()
+		for _, f := range rec.Fields {
+			f.touch(now, timeout)
+		}
+	})
+}
+
+// Destroy remove the session and its user index
+func (s *Store) Destroy(id string) error {
+	rec, _, err := s.load(id)
+	if err != nil {
+		return err
+	}
+
+	if rec != nil && rec.User != "" {
+		s.store.Del(s.index(rec.User, id))
+	}
+	return s.store.Del(s.key(id))
+}
+
+// Rotate move the session data to the new id, the old id is invalid after the rotation
+func (s *Store) Rotate(id string, newID string) error {
+	rec, _, err := s.load(id)
+	if err != nil || rec == nil {
+		return err
+	}
+
+	err = s.save(newID, nil, rec)
+	if err != nil {
+		return err
+	}
+	return s.Destroy(id)
+}
+
+// Bind index the session to the user
+func (s *Store) Bind(id string, user string) error {
+	if user == "" {
+		return fmt.Errorf("the user is required")
+	}
+
+	rec, _, err := s.load(id)
+	if err != nil {
+		return err
+	}
+
+	if rec == nil {
+		return fmt.Errorf("session %s does not exist", id)
+	}
+
+	previous := ""
+	err = s.update(id, func(rec *record) {
+		previous = rec.User
+		rec.User = user
+	})
+	if err != nil {
+		return err
+	}
+
+	if previous != "" && previous != user {
+		s.store.Del(s.index(previous, id))
+	}
+	return nil
+}
+
+// Sessions the session ids of the user
+func (s *Store) Sessions(user string) ([]string, error) {
+	prefix := s.index(user, "")
+	ids := []string{}
+	for _, key := range s.store.Keys(prefix + "*") {
+		id := strings.TrimPrefix(key, prefix)
+		if !s.store.Has(s.key(id)) { // the session was expired
+			s.store.Del(key)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Revoke destroy all the sessions of the user, returns the number of the destroyed sessions
+func (s *Store) Revoke(user string) (int, error) {
+	ids, err := s.Sessions(user)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := s.Destroy(id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// alive the session of the id, the expiration is extended if the sliding expiration is enabled. nil if the session does not exist
+func (s *Store) alive(id string) (*record, error) {
+	if s.option.Sliding {
+		if err := s.Touch(id, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	rec, _, err := s.load(id)
+	return rec, err
+}
+
+// update update the session atomically, the session is saved only if it was not modified by the others
+func (s *Store) update(id string, change func(rec *record)) error {
+	for i := 0; i < casRetries; i++ {
+		rec, raw, err := s.load(id)
+		if err != nil {
+			return err
+		}
+
+		if rec == nil {
+			rec = &record{Fields: map[string]*field{}}
+		}
+
+		change(rec)
+		saved, err := s.cas(id, raw, rec)
+		if err != nil {
+			return err
+		}
+
+		if saved {
+			return nil
+		}
+	}
+	return fmt.Errorf("session %s was modified by the others, try again later", id)
+}
+
+// cas save the session if the saved data equals to the old one, nil old means the session does not exist
+func (s *Store) cas(id string, old interface{}, rec *record) (bool, error) {
+	rec.prune(time.Now())
+	if len(rec.Fields) == 0 {
+		if old == nil {
+			return true, nil
+		}
+		return true, s.Destroy(id)
+	}
+
+	value, err := jsoniter.MarshalToString(rec)
+	if err != nil {
+		return false, err
+	}
+
+	ttl := rec.ttl(time.Now())
+	saved, err := s.store.CAS(s.key(id), old, value, ttl)
+	if err != nil || !saved {
+		return saved, err
+	}
+
+	if rec.User != "" {
+		err = s.store.Set(s.index(rec.User, id), id, ttl)
+	}
+	return true, err
+}
+
+// save save the session
+func (s *Store) save(id string, old interface{}, rec *record) error {
+	saved, err := s.cas(id, old, rec)
+	if err != nil {
+		return err
+	}
+
+	if !saved {
+		return fmt.Errorf("session %s exists", id)
+	}
+	return nil
+}
+
+// load the session of the id and the raw data saved in the store, nil if the session does not exist
+func (s *Store) load(id string) (*record, interface{}, error) {
+	raw, has := s.store.Get(s.key(id))
+	if !has || raw == nil {
+		return nil, nil, nil
+	}
+
+	text, ok := raw.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("session %s the data is not a string", id)
+	}
+
+	rec := &record{}
+	err := jsoniter.UnmarshalFromString(text, rec)
+	if err != nil {
+		log.Error("Session store load %s: %s", id, err.Error())
+		return nil, nil, err
+	}
+
+	if rec.Fields == nil {
+		rec.Fields = map[string]*field{}
+	}
+	rec.prune(time.Now())
+	return rec, raw, nil
+}
+
+func (s *Store) key(id string) string {
+	return s.option.Prefix + id
+}
+
+// index the key of the user index, the user is hex encoded, the key pattern of a user does not match the others and has no glob metacharacters
+func (s *Store) index(user string, id string) string {
+	return fmt.Sprintf("%suser:%s:%s", s.option.Prefix, hex.EncodeToString([]byte(user)), id)
+}
+
+// touch extend the expiration of the value by the timeout, or by its own ttl if the timeout is 0
+func (f *field) touch(now time.Time, timeout time.Duration) {
+	if timeout > 0 {
+		f.TTL = timeout.Milliseconds()
+	}
+
+	f.ExpiredAt = 0
+	if f.TTL > 0 {
+		f.ExpiredAt = now.UnixMilli() + f.TTL
+	}
+}
+
+// prune remove the expired values
+func (rec *record) prune(now time.Time) {
+	for key, f := range rec.Fields {
+		if f.ExpiredAt > 0 && f.ExpiredAt <= now.UnixMilli() {
+			delete(rec.Fields, key)
+		}
+	}
+}
+
+// ttl the time to live of the session, it is the longest one of the values. 0 means the session never expires
+func (rec *record) ttl(now time.Time) time.Duration {
+	var ttl int64 = 0
+	for _, f := range rec.Fields {
+		if f.ExpiredAt == 0 {
+			return 0
+		}
+
+		if remain := f.ExpiredAt - now.UnixMilli(); remain > ttl {
+			ttl = remain
+		}
+	}
+	return time.Duration(ttl) * time.Millisecond
+}
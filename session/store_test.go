@@ -0,0 +1,172 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/store"
+)
+
+func init() {
+	kv, err := store.New(nil, store.Option{"size": 10240})
+	if err != nil {
+		panic(err)
+	}
+	store.Pools["unit.session"] = kv
+
+	manager, err := NewStore("unit.session", StoreOption{})
+	if err != nil {
+		panic(err)
+	}
+	Register("store", manager)
+
+	sliding, err := NewStore("unit.session", StoreOption{Prefix: "sliding:", Sliding: true})
+	if err != nil {
+		panic(err)
+	}
+	Register("store.sliding", sliding)
+}
+
+func TestStoreMustSetGetDel(t *testing.T) {
+	id := ID()
+	s := Use("store").ID(id).Expire(200 * time.Millisecond)
+	s.MustSet("foo", "bar")
+	s.MustSetWithEx("forever", map[string]interface{}{"hello": "world"}, 0)
+	assert.Equal(t, "bar", s.MustGet("foo"))
+	assert.Equal(t, map[string]interface{}{"hello": "world"}, s.MustGet("forever"))
+
+	s.MustSetMany(map[string]interface{}{"hello": "world", "hi": "gou"})
+	assert.Equal(t, "world", s.MustGet("hello"))
+	assert.Equal(t, "gou", s.MustGet("hi"))
+
+	s.MustDel("hi")
+	assert.Nil(t, s.MustGet("hi"))
+
+	data := s.MustDump()
+	assert.Len(t, data, 3)
+
+	time.Sleep(201 * time.Millisecond)
+	assert.Nil(t, s.MustGet("foo"))
+	assert.Nil(t, s.MustGet("hello"))
+	assert.Equal(t, map[string]interface{}{"forever": map[string]interface{}{"hello": "world"}}, s.MustDump())
+
+	_, err := NewStore("unit.missing", StoreOption{})
+	assert.NotNil(t, err)
+}
+
+func TestStoreSliding(t *testing.T) {
+	id := ID()
+	s := Use("store.sliding").ID(id).Expire(200 * time.Millisecond)
+	s.MustSet("foo", "bar")
+
+	// the session is alive while it is accessed
+	for i := 0; i < 3; i++ {
+		time.Sleep(120 * time.Millisecond)
+		assert.Equal(t, "bar", s.MustGet("foo"))
+	}
+
+	time.Sleep(210 * time.Millisecond)
+	assert.Nil(t, s.MustGet("foo"))
+
+	// touch the session of the fixed expiration
+	fixed := Use("store").ID(ID()).Expire(200 * time.Millisecond)
+	fixed.MustSet("foo", "bar")
+	time.Sleep(120 * time.Millisecond)
+	assert.Nil(t, fixed.Touch())
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, "bar", fixed.MustGet("foo"))
+
+	assert.Nil(t, fixed.Touch(50*time.Millisecond))
+	time.Sleep(60 * time.Millisecond)
+	assert.Nil(t, fixed.MustGet("foo"))
+}
+
+func TestStoreRotateRevoke(t *testing.T) {
+	user := ID()
+	first := Use("store").Make()
+	first.MustSet("user_id", user)
+	assert.Nil(t, first.Bind(user))
+
+	second := Use("store").Make()
+	second.MustSet("user_id", user)
+	assert.Nil(t, second.Bind(user))
+
+	ids, err := first.Sessions(user)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{first.GetID(), second.GetID()}, ids)
+
+	// rotate the session id
+	old := first.GetID()
+	assert.Nil(t, first.Rotate())
+	assert.NotEqual(t, old, first.GetID())
+	assert.Equal(t, user, first.MustGet("user_id"))
+	assert.Nil(t, Use("store").ID(old).MustGet("user_id"))
+
+	ids, _ = first.Sessions(user)
+	assert.ElementsMatch(t, []string{first.GetID(), second.GetID()}, ids)
+
+	// revoke all the sessions of the user
+	revoked, err := first.Revoke(user)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, revoked)
+	assert.Nil(t, first.MustGet("user_id"))
+	assert.Nil(t, second.MustGet("user_id"))
+
+	ids, _ = first.Sessions(user)
+	assert.Len(t, ids, 0)
+
+	// the session does not exist
+	assert.NotNil(t, Use("store").Make().Bind(user))
+}
+
+func TestStoreDestroy(t *testing.T) {
+	s := Use("store").Make()
+	s.MustSet("foo", "bar")
+	assert.Nil(t, s.Bind("unit-user"))
+	assert.Nil(t, s.Destroy())
+	assert.Nil(t, s.MustGet("foo"))
+
+	ids, _ := s.Sessions("unit-user")
+	assert.Len(t, ids, 0)
+
+	// the managers without the user index
+	bunt := Use("buntdb").Make()
+	bunt.MustSet("foo", "bar")
+	assert.Nil(t, bunt.Rotate())
+	assert.Equal(t, "bar", bunt.MustGet("foo"))
+	assert.Nil(t, bunt.Destroy())
+	assert.Nil(t, bunt.MustGet("foo"))
+	assert.NotNil(t, bunt.Bind("unit-user"))
+}
+
+func TestStoreSessionsOfUser(t *testing.T) {
+	users := []string{"unit-alice", "unit-alice:bob", "unit-*", "unit-[a]"}
+	sessions := map[string]string{}
+	for _, user := range users {
+		s := Use("store").Make()
+		s.MustSet("user_id", user)
+		assert.Nil(t, s.Bind(user))
+		sessions[user] = s.GetID()
+	}
+
+	// the user is not matched by the other users or the glob metacharacters
+	s := Use("store").Make()
+	for _, user := range users {
+		ids, err := s.Sessions(user)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{sessions[user]}, ids)
+	}
+
+	revoked, err := s.Revoke("unit-alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, revoked)
+	assert.Equal(t, "unit-alice:bob", Use("store").ID(sessions["unit-alice:bob"]).MustGet("user_id"))
+}
+
+func TestStoreFlash(t *testing.T) {
+	s := Use("store").Make()
+	s.MustSetFlash("notice", "saved")
+	assert.Equal(t, "saved", s.MustGetFlash("notice"))
+	assert.Nil(t, s.MustGetFlash("notice"))
+}
@@ -11,6 +11,22 @@ type Manager interface {
 	Dump(id string) (map[string]interface{}, error)
 }
 
+// Lifecycle the manager supports the sliding expiration, the session id rotation and the user index
+type Lifecycle interface {
+	Touch(id string, timeout time.Duration) error // extend the expiration of the session
+	Destroy(id string) error
+	Rotate(id string, newID string) error // move the session data to the new id
+	Bind(id string, user string) error    // index the session to the user
+	Sessions(user string) ([]string, error)
+	Revoke(user string) (int, error) // destroy all the sessions of the user
+}
+
+// Flash the manager supports the one-shot values, the value is deleted atomically after it is read
+type Flash interface {
+	SetFlash(id string, key string, value interface{}, timeout time.Duration) error
+	GetFlash(id string, key string) (interface{}, error)
+}
+
 // Session 数据结构
 type Session struct {
 	id      string
@@ -1,6 +1,8 @@
 package ssl
 
 import (
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -63,3 +65,12 @@ func NewCertificate(data []byte) (*Certificate, error) {
 	return nil, fmt.Errorf("the kind of PEM should be CERTIFICATE")
 
 }
+
+// NewPublicKey create a new PUBLIC KEY certificate from the given RSA or ECDSA public key
+func NewPublicKey(key any) (*Certificate, error) {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return &Certificate{pub: key, Type: "PUBLIC KEY"}, nil
+	}
+	return nil, fmt.Errorf("the public key should be a RSA or ECDSA PUBLIC KEY")
+}
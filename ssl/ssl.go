@@ -2,6 +2,7 @@ package ssl
 
 import (
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
@@ -102,11 +103,6 @@ func Verify(data []byte, signature []byte, cert *Certificate, algorithm string)
 		return false, fmt.Errorf("The algorithm type %s does not support", algorithm)
 	}
 
-	publicKey, ok := cert.pub.(*rsa.PublicKey)
-	if !ok {
-		return false, fmt.Errorf("The PUBLIC KEY should be a RSA PUBLIC KEY")
-	}
-
 	h := hash.New()
 	_, err := h.Write(data)
 	if err != nil {
@@ -114,12 +110,22 @@ func Verify(data []byte, signature []byte, cert *Certificate, algorithm string)
 	}
 	hashed := h.Sum(nil)
 
-	err = rsa.VerifyPKCS1v15(publicKey, hash, hashed, signature)
-	if err != nil {
-		return false, err
+	switch publicKey := cert.pub.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(publicKey, hash, hashed, signature)
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+
+	case *ecdsa.PublicKey: // the signature should be ASN.1 encoded
+		if !ecdsa.VerifyASN1(publicKey, hashed, signature) {
+			return false, fmt.Errorf("ecdsa: verification error")
+		}
+		return true, nil
 	}
 
-	return true, err
+	return false, fmt.Errorf("The PUBLIC KEY should be a RSA or ECDSA PUBLIC KEY")
 }
 
 // VerifyBase64 Verify signature
@@ -1,6 +1,10 @@
 package ssl
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"testing"
 
@@ -66,3 +70,29 @@ func TestCertVerifyStrBase64Public(t *testing.T) {
 
 	assert.True(t, res)
 }
+
+func TestVerifyECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashed := sha256.Sum256([]byte("hello world"))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Verify([]byte("hello world"), signature, cert, "SHA256")
+	assert.Nil(t, err)
+	assert.True(t, res)
+
+	res, err = Verify([]byte("hello world!"), signature, cert, "SHA256")
+	assert.NotNil(t, err)
+	assert.False(t, res)
+}
@@ -0,0 +1,420 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tidwall/buntdb"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/kun/log"
+)
+
+// New create a new file store, the data file is created if it does not exist
+func New(path string, option Option) (*Store, error) {
+	if path == "" {
+		return nil, fmt.Errorf("the path of the file store is required")
+	}
+
+	if path != ":memory:" {
+		err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := buntdb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config buntdb.Config
+	err = db.ReadConfig(&config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	switch strings.ToLower(option.Sync) {
+	case "always":
+		config.SyncPolicy = buntdb.Always
+	case "never":
+		config.SyncPolicy = buntdb.Never
+	case "", "everysecond":
+		config.SyncPolicy = buntdb.EverySecond
+	default:
+		db.Close()
+		return nil, fmt.Errorf("the sync policy %s does not support", option.Sync)
+	}
+
+	if option.Shrink < 0 {
+		config.AutoShrinkDisabled = true
+	} else if option.Shrink > 0 {
+		config.AutoShrinkPercentage = option.Shrink
+	}
+
+	if option.ShrinkSize > 0 {
+		config.AutoShrinkMinSize = option.ShrinkSize
+	}
+
+	err = db.SetConfig(config)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db, Path: path, Option: option}, nil
+}
+
+// Close close the data file
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// Shrink compact the data file, the expired and the overwritten entries are removed
+func (store *Store) Shrink() error {
+	return store.db.Shrink()
+}
+
+// Get looks up a key's value from the store.
+func (store *Store) Get(key string) (value interface{}, ok bool) {
+	err := store.db.View(func(tx *buntdb.Tx) error {
+		value, ok = get(tx, key)
+		return nil
+	})
+	if err != nil {
+		log.Error("Store file Get %s: %s", key, err.Error())
+		return nil, false
+	}
+	return value, ok
+}
+
+// Set adds a value to the store.
+func (store *Store) Set(key string, value interface{}, ttl time.Duration) error {
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		return set(tx, key, value, ttl)
+	})
+	if err != nil {
+		log.Error("Store file Set %s: %s", key, err.Error())
+		return err
+	}
+	return nil
+}
+
+// Del remove is used to purge a key from the store
+func (store *Store) Del(key string) error {
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		_, err := tx.Delete(key)
+		if err == buntdb.ErrNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		log.Error("Store file Del %s: %s", key, err.Error())
+		return err
+	}
+	return nil
+}
+
+// Has check if the store is exist ( without updating recency or frequency )
+func (store *Store) Has(key string) bool {
+	has := false
+	store.db.View(func(tx *buntdb.Tx) error {
+		has = exists(tx, key)
+		return nil
+	})
+	return has
+}
+
+// Len returns the number of stored entries (**not O(1)**)
+func (store *Store) Len() int {
+	return len(store.Keys())
+}
+
+// Keys returns the stored keys match the pattern
+func (store *Store) Keys(pattern ...string) []string {
+	match := ""
+	if len(pattern) > 0 {
+		match = pattern[0]
+	}
+
+	keys := []string{}
+	err := store.db.View(func(tx *buntdb.Tx) error {
+		return tx.AscendKeys("*", func(key, value string) bool {
+			if exists(tx, key) && helper.GlobMatch(match, key) {
+				keys = append(keys, key)
+			}
+			return true
+		})
+	})
+
+	if err != nil {
+		log.Error("Store file Keys: %s", err.Error())
+		return []string{}
+	}
+	return keys
+}
+
+// Clear is used to clear the store
+func (store *Store) Clear() {
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		return tx.DeleteAll()
+	})
+	if err != nil {
+		log.Error("Store file Clear: %s", err.Error())
+	}
+}
+
+// GetSet looks up a key's value from the store. if does not exist add to the store
+func (store *Store) GetSet(key string, ttl time.Duration, getValue func(key string) (interface{}, error)) (interface{}, error) {
+	value, ok := store.Get(key)
+	if !ok {
+		var err error
+		value, err = getValue(key)
+		if err != nil {
+			return nil, err
+		}
+		store.Set(key, value, ttl)
+	}
+	return value, nil
+}
+
+// GetDel looks up a key's value from the store, then remove it.
+func (store *Store) GetDel(key string) (value interface{}, ok bool) {
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		value, ok = get(tx, key)
+		if !ok {
+			return nil
+		}
+		_, err := tx.Delete(key)
+		return err
+	})
+	if err != nil {
+		log.Error("Store file GetDel %s: %s", key, err.Error())
+		return nil, false
+	}
+	return value, ok
+}
+
+// GetMulti mulit get values
+func (store *Store) GetMulti(keys []string) map[string]interface{} {
+	values := map[string]interface{}{}
+	store.db.View(func(tx *buntdb.Tx) error {
+		for _, key := range keys {
+			value, _ := get(tx, key)
+			values[key] = value
+		}
+		return nil
+	})
+	return values
+}
+
+// SetMulti mulit set values
+func (store *Store) SetMulti(values map[string]interface{}, ttl time.Duration) {
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		for key, value := range values {
+			if err := set(tx, key, value, ttl); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("Store file SetMulti: %s", err.Error())
+	}
+}
+
+// DelMulti mulit remove values
+func (store *Store) DelMulti(keys []string) {
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		for _, key := range keys {
+			if _, err := tx.Delete(key); err != nil && err != buntdb.ErrNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error("Store file DelMulti: %s", err.Error())
+	}
+}
+
+// GetSetMulti mulit get values, if does not exist add to the store
+func (store *Store) GetSetMulti(keys []string, ttl time.Duration, getValue func(key string) (interface{}, error)) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		value, err := store.GetSet(key, ttl, getValue)
+		if err != nil {
+			log.Error("GetSetMulti Set %s: %s", key, err.Error())
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// Incr increase the integer value atomically, the missing key is set to the delta
+func (store *Store) Incr(key string, delta int) (int, error) {
+	current := 0
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		ttl := time.Duration(0)
+		if value, ok := get(tx, key); ok {
+			var err error
+			current, err = helper.ToInt(value)
+			if err != nil {
+				return fmt.Errorf("the value of %s is not an integer", key)
+			}
+
+			// keep the expiration of the key
+			if ttl, err = tx.TTL(key); err != nil || ttl < 0 {
+				ttl = 0
+			}
+		}
+
+		current = current + delta
+		return set(tx, key, current, ttl)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return current, nil
+}
+
+// Decr decrease the integer value atomically
+func (store *Store) Decr(key string, delta int) (int, error) {
+	return store.Incr(key, -delta)
+}
+
+// SetNX set the value if the key does not exist
+func (store *Store) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	ok := false
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		if exists(tx, key) {
+			return nil
+		}
+		ok = true
+		return set(tx, key, value, ttl)
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// CAS set the value if the current value equals to the old one, nil old means the key does not exist
+func (store *Store) CAS(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	ok := false
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		current, has := get(tx, key)
+		if old == nil && has {
+			return nil
+		}
+
+		if old != nil && (!has || !helper.Equal(current, old)) {
+			return nil
+		}
+
+		ok = true
+		return set(tx, key, value, ttl)
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// CAD delete the key if the current value equals to the value
+func (store *Store) CAD(key string, value interface{}) (bool, error) {
+	ok := false
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		current, has := get(tx, key)
+		if !has || !helper.Equal(current, value) {
+			return nil
+		}
+
+		ok = true
+		_, err := tx.Delete(key)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// TTL the remaining time to live of the key, -1 if the key never expires
+func (store *Store) TTL(key string) (ttl time.Duration, ok bool) {
+	store.db.View(func(tx *buntdb.Tx) error {
+		var err error
+		ttl, err = tx.TTL(key)
+		ok = err == nil
+		return nil
+	})
+	return ttl, ok
+}
+
+// Expire set the time to live of the key, ttl <= 0 removes the expiration
+func (store *Store) Expire(key string, ttl time.Duration) (bool, error) {
+	ok := false
+	err := store.db.Update(func(tx *buntdb.Tx) error {
+		value, err := tx.Get(key)
+		if err == buntdb.ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		ok = true
+		_, _, err = tx.Set(key, value, options(ttl))
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// get the value of the key in the transaction, the value is json encoded
+func get(tx *buntdb.Tx, key string) (interface{}, bool) {
+	val, err := tx.Get(key)
+	if err != nil {
+		if err != buntdb.ErrNotFound {
+			log.Error("Store file Get %s: %s", key, err.Error())
+		}
+		return nil, false
+	}
+
+	var value interface{}
+	err = jsoniter.Unmarshal([]byte(val), &value)
+	if err != nil {
+		log.Error("Store file Get %s: %s val: %s", key, err.Error(), val)
+		return nil, false
+	}
+	return value, true
+}
+
+// set the value of the key in the transaction
+func set(tx *buntdb.Tx, key string, value interface{}, ttl time.Duration) error {
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, _, err = tx.Set(key, string(bytes), options(ttl))
+	return err
+}
+
+// exists check if the key exists and not expired, the expired keys are still in the file until the background sweep
+func exists(tx *buntdb.Tx, key string) bool {
+	_, err := tx.TTL(key)
+	return err == nil
+}
+
+func options(ttl time.Duration) *buntdb.SetOptions {
+	if ttl <= 0 {
+		return nil
+	}
+	return &buntdb.SetOptions{Expires: true, TTL: ttl}
+}
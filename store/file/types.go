@@ -0,0 +1,19 @@
+package file
+
+import (
+	"github.com/tidwall/buntdb"
+)
+
+// Store the file store, the data is persisted in the append-only file via buntdb
+type Store struct {
+	db     *buntdb.DB
+	Path   string
+	Option Option
+}
+
+// Option the file store option
+type Option struct {
+	Sync       string // the sync policy: always, everysecond (default) and never
+	Shrink     int    // compact the file when it grows more than the percentage of the last compacted size, -1 disables auto compaction, default is 100
+	ShrinkSize int    // the minimum size in bytes before the auto compaction is triggered
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/kun/log"
+)
+
+// lockRetryInterval the interval of retrying to acquire the lock
+var lockRetryInterval = 50 * time.Millisecond
+
+// NewLock create a lock on the store, the lock key is expired after the ttl if the holder crashes
+func NewLock(stor Store, key string, ttl time.Duration) *Lock {
+	return &Lock{store: stor, Key: key, Token: uuid.NewString(), TTL: ttl}
+}
+
+// TryAcquire try to acquire the lock once (SET NX PX)
+func (lock *Lock) TryAcquire() (bool, error) {
+	return lock.store.SetNX(lock.Key, lock.Token, lock.TTL)
+}
+
+// Acquire acquire the lock, wait until the timeout if the lock is held by others
+func (lock *Lock) Acquire(timeout time.Duration) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := lock.TryAcquire()
+		if err != nil || ok {
+			return ok, err
+		}
+
+		if !time.Now().Before(deadline) {
+			return false, nil
+		}
+
+		wait := lockRetryInterval
+		if left := time.Until(deadline); left < wait {
+			wait = left
+		}
+		time.Sleep(wait)
+	}
+}
+
+// Renew extend the ttl of the lock, false if the lock is no longer held by the holder
+func (lock *Lock) Renew(ttl time.Duration) (bool, error) {
+	if ttl > 0 {
+		lock.TTL = ttl
+	}
+	return lock.store.CAS(lock.Key, lock.Token, lock.Token, lock.TTL)
+}
+
+// Release release the lock, false if the lock is no longer held by the holder
+func (lock *Lock) Release() (bool, error) {
+	return Unlock(lock.store, lock.Key, lock.Token)
+}
+
+// Unlock release the lock held by the token, the key is deleted only if the token matches
+func Unlock(stor Store, key string, token string) (bool, error) {
+	return stor.CAD(key, token)
+}
+
+// NewLeader create a leader election on the store, only one instance of the same name is the leader at a time
+func NewLeader(stor Store, name string, ttl time.Duration) *Leader {
+	if ttl <= 0 {
+		ttl = 10 * time.Second
+	}
+	return &Leader{
+		lock:     NewLock(stor, fmt.Sprintf("leader:%s", name), ttl),
+		interval: ttl / 3,
+	}
+}
+
+// Start campaign for the leadership in the background
+func (leader *Leader) Start() {
+	leader.mutex.Lock()
+	defer leader.mutex.Unlock()
+	if leader.stop != nil {
+		return
+	}
+
+	leader.stop = make(chan struct{})
+	leader.done = make(chan struct{})
+	go leader.campaign(leader.stop, leader.done)
+}
+
+// Stop stop the campaign and resign the leadership
+func (leader *Leader) Stop() {
+	leader.mutex.Lock()
+	stop, done := leader.stop, leader.done
+	leader.stop = nil
+	leader.mutex.Unlock()
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	leader.resign()
+}
+
+// IsLeader check if the instance is the leader, the leadership is lost if it was not renewed within the ttl
+func (leader *Leader) IsLeader() bool {
+	leader.mutex.Lock()
+	defer leader.mutex.Unlock()
+	return leader.leading && time.Now().Before(leader.until)
+}
+
+// ID the token of the instance
+func (leader *Leader) ID() string {
+	return leader.lock.Token
+}
+
+func (leader *Leader) campaign(stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(leader.interval)
+	defer ticker.Stop()
+	for {
+		leader.elect()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (leader *Leader) elect() {
+	start := time.Now()
+
+	leader.mutex.Lock()
+	leading := leader.leading
+	leader.mutex.Unlock()
+
+	// renew the lease first, the key may still be held by the instance even if the lease was expired locally
+	var ok bool
+	var err error
+	if leading {
+		ok, err = leader.lock.Renew(0)
+	}
+
+	if !ok && err == nil {
+		ok, err = leader.lock.TryAcquire()
+	}
+
+	if err != nil {
+		log.Error("[Leader] %s: %s", leader.lock.Key, err.Error())
+	}
+
+	leader.mutex.Lock()
+	leader.leading = ok
+	if ok {
+		leader.until = start.Add(leader.lock.TTL)
+	}
+	leader.mutex.Unlock()
+}
+
+func (leader *Leader) resign() {
+	leader.mutex.Lock()
+	leading := leader.leading
+	leader.leading = false
+	leader.mutex.Unlock()
+
+	if leading {
+		if _, err := leader.lock.Release(); err != nil {
+			log.Error("[Leader] %s resign: %s", leader.lock.Key, err.Error())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	kv := newStore(t, nil)
+	lock := NewLock(kv, "lock:unit", time.Second)
+	other := NewLock(kv, "lock:unit", time.Second)
+
+	ok, err := lock.TryAcquire()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	start := time.Now()
+	ok, err = other.Acquire(100 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	// the other one can not renew or release the lock
+	ok, _ = other.Renew(0)
+	assert.False(t, ok)
+	ok, _ = other.Release()
+	assert.False(t, ok)
+
+	ok, _ = lock.Renew(2 * time.Second)
+	assert.True(t, ok)
+	ttl, _ := kv.TTL("lock:unit")
+	assert.True(t, ttl > time.Second)
+
+	ok, _ = lock.Release()
+	assert.True(t, ok)
+	ok, err = other.Acquire(100 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// the lock is expired if the holder crashes
+	ok, err = NewLock(kv, "lock:crash", 100*time.Millisecond).TryAcquire()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = NewLock(kv, "lock:crash", time.Second).Acquire(time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
+func TestLeader(t *testing.T) {
+	kv := newStore(t, nil)
+	first := NewLeader(kv, "unit", 300*time.Millisecond)
+	second := NewLeader(kv, "unit", 300*time.Millisecond)
+
+	first.Start()
+	time.Sleep(50 * time.Millisecond)
+	second.Start()
+	defer second.Stop()
+
+	time.Sleep(400 * time.Millisecond)
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+
+	// the second one takes over after the first one resigns
+	first.Stop()
+	assert.False(t, first.IsLeader())
+	time.Sleep(200 * time.Millisecond)
+	assert.True(t, second.IsLeader())
+}
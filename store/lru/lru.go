@@ -5,12 +5,13 @@ import (
 	"time"
 
 	lru "github.com/hashicorp/golang-lru"
+	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/kun/log"
 )
 
 // New create a new LRU cache
 func New(size int) (*Cache, error) {
-	cache := &Cache{}
+	cache := &Cache{size: size, expires: map[string]time.Time{}}
 	lru, err := lru.NewARC(size)
 	if err != nil {
 		return nil, err
@@ -21,34 +22,58 @@ func New(size int) (*Cache, error) {
 
 // Get looks up a key's value from the cache.
 func (cache *Cache) Get(key string) (value interface{}, ok bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !cache.alive(key) {
+		return nil, false
+	}
 	return cache.lru.Get(key)
 }
 
 // Set adds a value to the cache.
 func (cache *Cache) Set(key string, value interface{}, ttl time.Duration) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
 	cache.lru.Add(key, value)
+	cache.setTTL(key, ttl)
 	return nil
 }
 
 // Del remove is used to purge a key from the cache
 func (cache *Cache) Del(key string) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
 	cache.lru.Remove(key)
+	delete(cache.expires, key)
 	return nil
 }
 
 // Has check if the cache is exist ( without updating recency or frequency )
 func (cache *Cache) Has(key string) bool {
-	_, has := cache.lru.Peek(key)
-	return has
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.exists(key)
 }
 
 // Len returns the number of cached entries
 func (cache *Cache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.purge()
 	return cache.lru.Len()
 }
 
-// Keys returns all the cached keys
-func (cache *Cache) Keys() []string {
+// Keys returns the cached keys match the pattern
+func (cache *Cache) Keys(pattern ...string) []string {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.purge()
+
+	match := ""
+	if len(pattern) > 0 {
+		match = pattern[0]
+	}
+
 	keys := cache.lru.Keys()
 	res := []string{}
 	for _, key := range keys {
@@ -56,19 +81,24 @@ func (cache *Cache) Keys() []string {
 		if !ok {
 			keystr = fmt.Sprintf("%v", key)
 		}
-		res = append(res, keystr)
+		if helper.GlobMatch(match, keystr) {
+			res = append(res, keystr)
+		}
 	}
 	return res
 }
 
 // Clear is used to clear the cache
 func (cache *Cache) Clear() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
 	cache.lru.Purge()
+	cache.expires = map[string]time.Time{}
 }
 
 // GetSet looks up a key's value from the cache. if does not exist add to the cache
 func (cache *Cache) GetSet(key string, ttl time.Duration, getValue func(key string) (interface{}, error)) (interface{}, error) {
-	value, ok := cache.lru.Get(key)
+	value, ok := cache.Get(key)
 	if !ok {
 		var err error
 		value, err = getValue(key)
@@ -82,11 +112,18 @@ func (cache *Cache) GetSet(key string, ttl time.Duration, getValue func(key stri
 
 // GetDel looks up a key's value from the cache, then remove it.
 func (cache *Cache) GetDel(key string) (value interface{}, ok bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !cache.alive(key) {
+		return nil, false
+	}
+
 	value, ok = cache.lru.Get(key)
 	if !ok {
 		return nil, false
 	}
 	cache.lru.Remove(key)
+	delete(cache.expires, key)
 	return value, true
 }
 
@@ -94,7 +131,7 @@ func (cache *Cache) GetDel(key string) (value interface{}, ok bool) {
 func (cache *Cache) GetMulti(keys []string) map[string]interface{} {
 	values := map[string]interface{}{}
 	for _, key := range keys {
-		value, _ := cache.lru.Get(key)
+		value, _ := cache.Get(key)
 		values[key] = value
 	}
 	return values
@@ -103,14 +140,14 @@ func (cache *Cache) GetMulti(keys []string) map[string]interface{} {
 // SetMulti mulit set values
 func (cache *Cache) SetMulti(values map[string]interface{}, ttl time.Duration) {
 	for key, value := range values {
-		cache.lru.Add(key, value)
+		cache.Set(key, value, ttl)
 	}
 }
 
 // DelMulti mulit remove values
 func (cache *Cache) DelMulti(keys []string) {
 	for _, key := range keys {
-		cache.lru.Remove(key)
+		cache.Del(key)
 	}
 }
 
@@ -118,7 +155,7 @@ func (cache *Cache) DelMulti(keys []string) {
 func (cache *Cache) GetSetMulti(keys []string, ttl time.Duration, getValue func(key string) (interface{}, error)) map[string]interface{} {
 	values := map[string]interface{}{}
 	for _, key := range keys {
-		value, ok := cache.lru.Get(key)
+		value, ok := cache.Get(key)
 		if !ok {
 			var err error
 			value, err = getValue(key)
@@ -130,3 +167,158 @@ func (cache *Cache) GetSetMulti(keys []string, ttl time.Duration, getValue func(
 	}
 	return values
 }
+
+// Incr increase the integer value atomically, the missing key is set to the delta
+func (cache *Cache) Incr(key string, delta int) (int, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	current := 0
+	if cache.alive(key) {
+		if value, ok := cache.lru.Peek(key); ok {
+			var err error
+			current, err = helper.ToInt(value)
+			if err != nil {
+				return 0, fmt.Errorf("the value of %s is not an integer", key)
+			}
+		}
+	}
+
+	current = current + delta
+	cache.lru.Add(key, current)
+	return current, nil
+}
+
+// Decr decrease the integer value atomically
+func (cache *Cache) Decr(key string, delta int) (int, error) {
+	return cache.Incr(key, -delta)
+}
+
+// SetNX set the value if the key does not exist
+func (cache *Cache) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.exists(key) {
+		return false, nil
+	}
+	cache.lru.Add(key, value)
+	cache.setTTL(key, ttl)
+	return true, nil
+}
+
+// CAS set the value if the current value equals to the old one, nil old means the key does not exist
+func (cache *Cache) CAS(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	var current interface{}
+	has := cache.alive(key)
+	if has {
+		current, has = cache.lru.Peek(key)
+	}
+
+	if old == nil && has {
+		return false, nil
+	}
+
+	if old != nil && (!has || !helper.Equal(current, old)) {
+		return false, nil
+	}
+
+	cache.lru.Add(key, value)
+	cache.setTTL(key, ttl)
+	return true, nil
+}
+
+// CAD delete the key if the current value equals to the value
+func (cache *Cache) CAD(key string, value interface{}) (bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !cache.exists(key) {
+		return false, nil
+	}
+
+	current, has := cache.lru.Peek(key)
+	if !has || !helper.Equal(current, value) {
+		return false, nil
+	}
+
+	cache.lru.Remove(key)
+	delete(cache.expires, key)
+	return true, nil
+}
+
+// TTL the remaining time to live of the key, -1 if the key never expires
+func (cache *Cache) TTL(key string) (time.Duration, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !cache.exists(key) {
+		return 0, false
+	}
+
+	expiredAt, has := cache.expires[key]
+	if !has {
+		return -1, true
+	}
+	return time.Until(expiredAt), true
+}
+
+// Expire set the time to live of the key, ttl <= 0 removes the expiration
+func (cache *Cache) Expire(key string, ttl time.Duration) (bool, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !cache.exists(key) {
+		return false, nil
+	}
+	cache.setTTL(key, ttl)
+	return true, nil
+}
+
+// exists check if the key exists and not expired (the lock must be held)
+func (cache *Cache) exists(key string) bool {
+	if !cache.alive(key) {
+		return false
+	}
+	return cache.lru.Contains(key)
+}
+
+// alive check if the key is not expired, the expired key is removed (the lock must be held)
+func (cache *Cache) alive(key string) bool {
+	expiredAt, has := cache.expires[key]
+	if !has || time.Now().Before(expiredAt) {
+		return true
+	}
+	delete(cache.expires, key)
+	cache.lru.Remove(key)
+	return false
+}
+
+// setTTL set the expiration time of the key (the lock must be held)
+func (cache *Cache) setTTL(key string, ttl time.Duration) {
+	if ttl <= 0 {
+		delete(cache.expires, key)
+		return
+	}
+
+	cache.expires[key] = time.Now().Add(ttl)
+
+	// the evicted keys are still in the expires
+	if len(cache.expires) > cache.size {
+		for key := range cache.expires {
+			if !cache.lru.Contains(key) {
+				delete(cache.expires, key)
+			}
+		}
+	}
+}
+
+// purge remove the expired keys (the lock must be held)
+func (cache *Cache) purge() {
+	now := time.Now()
+	for key, expiredAt := range cache.expires {
+		if !now.Before(expiredAt) {
+			delete(cache.expires, key)
+			cache.lru.Remove(key)
+		}
+	}
+}
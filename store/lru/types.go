@@ -1,9 +1,16 @@
 package lru
 
-import lru "github.com/hashicorp/golang-lru"
+import (
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+)
 
 // Cache lru cache
 type Cache struct {
-	size int
-	lru  *lru.ARCCache
+	size    int
+	lru     *lru.ARCCache
+	expires map[string]time.Time // the expiration time of the keys which have ttl
+	mutex   sync.Mutex
 }
@@ -8,8 +8,10 @@ import (
 
 	"github.com/yaoapp/gou/connector"
 	mongodb "github.com/yaoapp/gou/connector/mongo"
+	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/kun/log"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -35,13 +37,24 @@ func New(c connector.Connector) (*Store, error) {
 		return nil, err
 	}
 
+	// Remove the expired documents in background
+	ttlIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expired_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err = coll.Indexes().CreateOne(context.TODO(), ttlIndexModel)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Store{Database: mongodb.Database, Collection: coll}, nil
 }
 
 // Get looks up a key's value from the store.
 func (store *Store) Get(key string) (value interface{}, ok bool) {
 	var result bson.M
-	err := store.Collection.FindOne(context.TODO(), bson.D{{Key: "key", Value: key}}).Decode(&result)
+	err := store.Collection.FindOne(context.TODO(), alive(key)).Decode(&result)
 	if err != nil {
 		if !strings.Contains(err.Error(), "no documents in result") {
 			log.Error("Store mongo Get %s: %s", key, err.Error())
@@ -56,6 +69,9 @@ func (store *Store) Get(key string) (value interface{}, ok bool) {
 func (store *Store) Set(key string, value interface{}, ttl time.Duration) error {
 	filter := bson.D{{Key: "key", Value: key}}
 	doc := bson.D{{Key: "key", Value: key}, {Key: "value", Value: value}}
+	if ttl > 0 {
+		doc = append(doc, bson.E{Key: "expired_at", Value: time.Now().Add(ttl)})
+	}
 	opts := options.FindOneAndReplace().SetUpsert(true)
 	res := store.Collection.FindOneAndReplace(context.TODO(), filter, doc, opts)
 	err := res.Err()
@@ -80,7 +96,7 @@ func (store *Store) Del(key string) error {
 
 // Has check if the store is exist ( without updating recency or frequency )
 func (store *Store) Has(key string) bool {
-	filter := bson.D{{Key: "key", Value: key}}
+	filter := alive(key)
 	result, err := store.Collection.CountDocuments(context.TODO(), filter)
 	if err != nil {
 		log.Error("Store mongo Has: %s", err.Error())
@@ -92,7 +108,7 @@ func (store *Store) Has(key string) bool {
 
 // Len returns the number of stored entries (**not O(1)**)
 func (store *Store) Len() int {
-	result, err := store.Collection.CountDocuments(context.TODO(), bson.D{})
+	result, err := store.Collection.CountDocuments(context.TODO(), bson.D{unexpired()})
 	if err != nil {
 		log.Error("Store mongo Has: %s", err.Error())
 		return 0
@@ -100,12 +116,19 @@ func (store *Store) Len() int {
 	return int(result)
 }
 
-// Keys returns all the cached keys
-func (store *Store) Keys() []string {
-	cursor, err := store.Collection.Find(context.TODO(), bson.D{})
+// Keys returns the cached keys match the pattern
+func (store *Store) Keys(pattern ...string) []string {
+	filter := bson.D{unexpired()}
+	if len(pattern) > 0 && pattern[0] != "" && pattern[0] != "*" {
+		filter = append(filter, bson.E{Key: "key", Value: bson.D{{Key: "$regex", Value: helper.GlobRegexp(pattern[0])}}})
+	}
+
+	cursor, err := store.Collection.Find(context.TODO(), filter)
 	if err != nil {
-		panic(err)
+		log.Error("Store mongo Keys: %s", err.Error())
+		return []string{}
 	}
+	defer cursor.Close(context.TODO())
 
 	keys := []string{}
 	for cursor.Next(context.TODO()) {
@@ -195,3 +218,139 @@ func (store *Store) GetSetMulti(keys []string, ttl time.Duration, getValue func(
 	}
 	return values
 }
+
+// Incr increase the integer value atomically, the missing key is set to the delta
+func (store *Store) Incr(key string, delta int) (int, error) {
+	store.removeExpired(key)
+
+	var result bson.M
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "value", Value: int64(delta)}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := store.Collection.FindOneAndUpdate(context.TODO(), alive(key), update, opts).Decode(&result)
+	if err != nil {
+		log.Error("Store mongo Incr %s: %s", key, err.Error())
+		return 0, err
+	}
+
+	switch value := result["value"].(type) {
+	case int32:
+		return int(value), nil
+	case int64:
+		return int(value), nil
+	case float64:
+		return int(value), nil
+	}
+	return 0, fmt.Errorf("the value of %s is not an integer", key)
+}
+
+// Decr decrease the integer value atomically
+func (store *Store) Decr(key string, delta int) (int, error) {
+	return store.Incr(key, -delta)
+}
+
+// SetNX set the value if the key does not exist
+func (store *Store) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	store.removeExpired(key)
+
+	doc := bson.D{{Key: "key", Value: key}, {Key: "value", Value: value}}
+	if ttl > 0 {
+		doc = append(doc, bson.E{Key: "expired_at", Value: time.Now().Add(ttl)})
+	}
+
+	_, err := store.Collection.InsertOne(context.TODO(), doc)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		log.Error("Store mongo SetNX %s: %s", key, err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+// CAS set the value if the current value equals to the old one, nil old means the key does not exist
+func (store *Store) CAS(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	if old == nil {
+		return store.SetNX(key, value, ttl)
+	}
+
+	filter := append(alive(key), bson.E{Key: "value", Value: old})
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}}}}
+	if ttl > 0 {
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}, {Key: "expired_at", Value: time.Now().Add(ttl)}}}}
+	} else {
+		update = append(update, bson.E{Key: "$unset", Value: bson.D{{Key: "expired_at", Value: ""}}})
+	}
+
+	res, err := store.Collection.UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		log.Error("Store mongo CAS %s: %s", key, err.Error())
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// CAD delete the key if the current value equals to the value
+func (store *Store) CAD(key string, value interface{}) (bool, error) {
+	filter := append(alive(key), bson.E{Key: "value", Value: value})
+	res, err := store.Collection.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		log.Error("Store mongo CAD %s: %s", key, err.Error())
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// TTL the remaining time to live of the key, -1 if the key never expires
+func (store *Store) TTL(key string) (time.Duration, bool) {
+	var result bson.M
+	err := store.Collection.FindOne(context.TODO(), alive(key)).Decode(&result)
+	if err != nil {
+		if !strings.Contains(err.Error(), "no documents in result") {
+			log.Error("Store mongo TTL %s: %s", key, err.Error())
+		}
+		return 0, false
+	}
+
+	expiredAt, ok := result["expired_at"].(primitive.DateTime)
+	if !ok {
+		return -1, true
+	}
+	return time.Until(expiredAt.Time()), true
+}
+
+// Expire set the time to live of the key, ttl <= 0 removes the expiration
+func (store *Store) Expire(key string, ttl time.Duration) (bool, error) {
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "expired_at", Value: ""}}}}
+	if ttl > 0 {
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "expired_at", Value: time.Now().Add(ttl)}}}}
+	}
+
+	res, err := store.Collection.UpdateOne(context.TODO(), alive(key), update)
+	if err != nil {
+		log.Error("Store mongo Expire %s: %s", key, err.Error())
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// removeExpired remove the expired document of the key, the TTL index removes the expired documents periodically
+func (store *Store) removeExpired(key string) {
+	filter := bson.D{{Key: "key", Value: key}, {Key: "expired_at", Value: bson.D{{Key: "$lte", Value: time.Now()}}}}
+	_, err := store.Collection.DeleteOne(context.TODO(), filter)
+	if err != nil {
+		log.Error("Store mongo %s: %s", key, err.Error())
+	}
+}
+
+// alive the filter of the unexpired document of the key
+func alive(key string) bson.D {
+	return bson.D{{Key: "key", Value: key}, unexpired()}
+}
+
+func unexpired() bson.E {
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "expired_at", Value: nil}},
+		bson.D{{Key: "expired_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}}},
+	}}
+}
@@ -1,9 +1,11 @@
 package store
 
 import (
+	"math"
 	"time"
 
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
 )
 
@@ -17,6 +19,14 @@ var StoreHandlers = map[string]process.Handler{
 	"len":    processStoreLen,
 	"keys":   processStoreKeys,
 	"clear":  processStoreClear,
+	"incr":   processStoreIncr,
+	"decr":   processStoreDecr,
+	"setnx":  processStoreSetNX,
+	"cas":    processStoreCAS,
+	"ttl":    processStoreTTL,
+	"expire": processStoreExpire,
+	"lock":   processStoreLock,
+	"unlock": processStoreUnlock,
 }
 
 func init() {
@@ -80,6 +90,9 @@ func processStoreLen(process *process.Process) interface{} {
 // processStoreKeys stores.<name>.Keys
 func processStoreKeys(process *process.Process) interface{} {
 	store := Select(process.ID)
+	if process.NumOfArgs() > 0 {
+		return store.Keys(process.ArgsString(0))
+	}
 	return store.Keys()
 }
 
@@ -89,3 +102,105 @@ func processStoreClear(process *process.Process) interface{} {
 	store.Clear()
 	return nil
 }
+
+// processStoreIncr stores.<name>.Incr
+func processStoreIncr(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	store := Select(process.ID)
+	value, err := store.Incr(process.ArgsString(0), process.ArgsInt(1, 1))
+	if err != nil {
+		exception.New("store %s Incr: %s", 500, process.ID, err.Error()).Throw()
+	}
+	return value
+}
+
+// processStoreDecr stores.<name>.Decr
+func processStoreDecr(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	store := Select(process.ID)
+	value, err := store.Decr(process.ArgsString(0), process.ArgsInt(1, 1))
+	if err != nil {
+		exception.New("store %s Decr: %s", 500, process.ID, err.Error()).Throw()
+	}
+	return value
+}
+
+// processStoreSetNX stores.<name>.SetNX
+func processStoreSetNX(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	store := Select(process.ID)
+	duration := process.ArgsInt(2, 0)
+	ok, err := store.SetNX(process.ArgsString(0), process.Args[1], time.Duration(duration)*time.Second)
+	if err != nil {
+		exception.New("store %s SetNX: %s", 500, process.ID, err.Error()).Throw()
+	}
+	return ok
+}
+
+// processStoreCAS stores.<name>.CAS
+func processStoreCAS(process *process.Process) interface{} {
+	process.ValidateArgNums(3)
+	store := Select(process.ID)
+	duration := process.ArgsInt(3, 0)
+	ok, err := store.CAS(process.ArgsString(0), process.Args[1], process.Args[2], time.Duration(duration)*time.Second)
+	if err != nil {
+		exception.New("store %s CAS: %s", 500, process.ID, err.Error()).Throw()
+	}
+	return ok
+}
+
+// processStoreTTL stores.<name>.TTL the remaining seconds, -1 if the key never expires, -2 if the key does not exist
+func processStoreTTL(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	store := Select(process.ID)
+	ttl, ok := store.TTL(process.ArgsString(0))
+	if !ok {
+		return -2
+	}
+
+	if ttl < 0 {
+		return -1
+	}
+	return int(math.Ceil(ttl.Seconds()))
+}
+
+// processStoreExpire stores.<name>.Expire
+func processStoreExpire(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	store := Select(process.ID)
+	ok, err := store.Expire(process.ArgsString(0), time.Duration(process.ArgsInt(1))*time.Second)
+	if err != nil {
+		exception.New("store %s Expire: %s", 500, process.ID, err.Error()).Throw()
+	}
+	return ok
+}
+
+// processStoreLock stores.<name>.Lock(key, ttl seconds, timeout milliseconds) returns the token, nil if the lock is held by others
+func processStoreLock(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	store := Select(process.ID)
+	ttl := time.Duration(process.ArgsInt(1, 30)) * time.Second
+	timeout := time.Duration(process.ArgsInt(2, 0)) * time.Millisecond
+
+	lock := NewLock(store, process.ArgsString(0), ttl)
+	ok, err := lock.Acquire(timeout)
+	if err != nil {
+		exception.New("store %s Lock: %s", 500, process.ID, err.Error()).Throw()
+	}
+
+	if !ok {
+		return nil
+	}
+	return lock.Token
+}
+
+// processStoreUnlock stores.<name>.Unlock(key, token)
+func processStoreUnlock(process *process.Process) interface{} {
+	process.ValidateArgNums(2)
+	store := Select(process.ID)
+	ok, err := Unlock(store, process.ArgsString(0), process.ArgsString(1))
+	if err != nil {
+		exception.New("store %s Unlock: %s", 500, process.ID, err.Error()).Throw()
+	}
+	return ok
+}
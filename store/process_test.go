@@ -48,3 +48,60 @@ func testStoreProcess(t *testing.T, name string) {
 	assert.Contains(t, value, "key1")
 	assert.NotContains(t, value, "key2")
 }
+
+func TestStoreProcessAtomic(t *testing.T) {
+	prepare(t)
+	prepareStores(t)
+	for _, name := range []string{"cache", "share", "data"} {
+		process.New(fmt.Sprintf("stores.%s.Clear", name)).Run()
+
+		value := process.New(fmt.Sprintf("stores.%s.Incr", name), "counter").Run()
+		assert.Equal(t, 1, value)
+		value = process.New(fmt.Sprintf("stores.%s.Incr", name), "counter", 10).Run()
+		assert.Equal(t, 11, value)
+		value = process.New(fmt.Sprintf("stores.%s.Decr", name), "counter").Run()
+		assert.Equal(t, 10, value)
+
+		value = process.New(fmt.Sprintf("stores.%s.SetNX", name), "lock", "foo").Run()
+		assert.True(t, value.(bool))
+		value = process.New(fmt.Sprintf("stores.%s.SetNX", name), "lock", "bar").Run()
+		assert.False(t, value.(bool))
+		value = process.New(fmt.Sprintf("stores.%s.CAS", name), "lock", "foo", "bar").Run()
+		assert.True(t, value.(bool))
+
+		value = process.New(fmt.Sprintf("stores.%s.TTL", name), "lock").Run()
+		assert.Equal(t, -1, value)
+		value = process.New(fmt.Sprintf("stores.%s.Expire", name), "lock", 60).Run()
+		assert.True(t, value.(bool))
+		value = process.New(fmt.Sprintf("stores.%s.TTL", name), "lock").Run()
+		assert.Greater(t, value.(int), 0)
+		value = process.New(fmt.Sprintf("stores.%s.TTL", name), "unknown").Run()
+		assert.Equal(t, -2, value)
+
+		value = process.New(fmt.Sprintf("stores.%s.Keys", name), "c*").Run()
+		assert.Equal(t, []string{"counter"}, value)
+		process.New(fmt.Sprintf("stores.%s.Clear", name)).Run()
+	}
+}
+
+func TestStoreProcessLock(t *testing.T) {
+	prepare(t)
+	prepareStores(t)
+	for _, name := range []string{"cache", "share", "data"} {
+		token := process.New(fmt.Sprintf("stores.%s.Lock", name), "lock:unit", 10).Run()
+		assert.NotNil(t, token)
+
+		value := process.New(fmt.Sprintf("stores.%s.Lock", name), "lock:unit", 10, 100).Run()
+		assert.Nil(t, value)
+
+		value = process.New(fmt.Sprintf("stores.%s.Unlock", name), "lock:unit", "unknown").Run()
+		assert.False(t, value.(bool))
+
+		value = process.New(fmt.Sprintf("stores.%s.Unlock", name), "lock:unit", token).Run()
+		assert.True(t, value.(bool))
+
+		value = process.New(fmt.Sprintf("stores.%s.Lock", name), "lock:unit", 10, 100).Run()
+		assert.NotNil(t, value)
+		process.New(fmt.Sprintf("stores.%s.Del", name), "lock:unit").Run()
+	}
+}
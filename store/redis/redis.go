@@ -7,19 +7,24 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/yaoapp/gou/connector"
-	"github.com/yaoapp/gou/connector/redis"
+	rdb "github.com/yaoapp/gou/connector/redis"
+	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/kun/log"
 )
 
+// scriptCAD delete the key if the value equals to the given one
+var scriptCAD = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`)
+
 // New create a new store via connector
 func New(c connector.Connector) (*Store, error) {
-	redis, ok := c.(*redis.Connector)
+	conn, ok := c.(*rdb.Connector)
 	if !ok {
 		return nil, fmt.Errorf("the connector was not a *redis.Connector")
 	}
-	return &Store{rdb: redis.Rdb, Option: Option{Prefix: fmt.Sprintf("%s:", redis.Name)}}, nil
+	return &Store{rdb: conn.Rdb, Option: Option{Prefix: fmt.Sprintf("%s:", conn.Name)}}, nil
 }
 
 // Get looks up a key's value from the store.
@@ -99,17 +104,23 @@ func (store *Store) Len() int {
 	return intv
 }
 
-// Keys returns all the cached keys
-func (store *Store) Keys() []string {
+// Keys returns the cached keys match the pattern
+func (store *Store) Keys(pattern ...string) []string {
 	prefix := store.Option.Prefix
-	keys, err := store.rdb.Keys(context.Background(), prefix+"*").Result()
-	if err != nil {
-		log.Error("Store redis Keys:%s", err.Error())
-		return []string{}
+	match := "*"
+	if len(pattern) > 0 && pattern[0] != "" {
+		match = pattern[0]
 	}
 
-	for i := range keys {
-		keys[i] = strings.TrimPrefix(keys[i], prefix)
+	keys := []string{}
+	iter := store.rdb.Scan(context.Background(), 0, prefix+match, 1000).Iterator()
+	for iter.Next(context.Background()) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), prefix))
+	}
+
+	if err := iter.Err(); err != nil {
+		log.Error("Store redis Keys:%s", err.Error())
+		return []string{}
 	}
 
 	return keys
@@ -190,3 +201,138 @@ func (store *Store) GetSetMulti(keys []string, ttl time.Duration, getValue func(
 	}
 	return values
 }
+
+// Incr increase the integer value atomically, the missing key is set to the delta
+func (store *Store) Incr(key string, delta int) (int, error) {
+	key = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	value, err := store.rdb.IncrBy(context.Background(), key, int64(delta)).Result()
+	if err != nil {
+		log.Error("Store redis Incr %s: %s", key, err.Error())
+		return 0, err
+	}
+	return int(value), nil
+}
+
+// Decr decrease the integer value atomically
+func (store *Store) Decr(key string, delta int) (int, error) {
+	return store.Incr(key, -delta)
+}
+
+// SetNX set the value if the key does not exist
+func (store *Store) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	key = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := store.rdb.SetNX(context.Background(), key, bytes, ttl).Result()
+	if err != nil {
+		log.Error("Store redis SetNX %s: %s", key, err.Error())
+		return false, err
+	}
+	return ok, nil
+}
+
+// CAS set the value if the current value equals to the old one, nil old means the key does not exist
+func (store *Store) CAS(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	if old == nil {
+		return store.SetNX(key, value, ttl)
+	}
+
+	key = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	swapped := false
+	err = store.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var current interface{}
+		if err := jsoniter.Unmarshal([]byte(val), &current); err != nil || !helper.Equal(current, old) {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, bytes, ttl)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		swapped = true
+		return nil
+	}, key)
+
+	// the key has been changed by the others
+	if err == redis.TxFailedErr {
+		return false, nil
+	}
+
+	if err != nil {
+		log.Error("Store redis CAS %s: %s", key, err.Error())
+		return false, err
+	}
+	return swapped, nil
+}
+
+// CAD delete the key if the current (encoded) value equals to the value
+func (store *Store) CAD(key string, value interface{}) (bool, error) {
+	key = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	bytes, err := jsoniter.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := scriptCAD.Run(context.Background(), store.rdb, []string{key}, bytes).Int()
+	if err != nil {
+		log.Error("Store redis CAD %s: %s", key, err.Error())
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// TTL the remaining time to live of the key, -1 if the key never expires
+func (store *Store) TTL(key string) (time.Duration, bool) {
+	key = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	ttl, err := store.rdb.PTTL(context.Background(), key).Result()
+	if err != nil {
+		log.Error("Store redis TTL %s: %s", key, err.Error())
+		return 0, false
+	}
+
+	switch ttl {
+	case -2:
+		return 0, false
+	case -1:
+		return -1, true
+	}
+	return ttl, true
+}
+
+// Expire set the time to live of the key, ttl <= 0 removes the expiration
+func (store *Store) Expire(key string, ttl time.Duration) (bool, error) {
+	key = fmt.Sprintf("%s%s", store.Option.Prefix, key)
+	if ttl <= 0 {
+		if store.rdb.Exists(context.Background(), key).Val() == 0 {
+			return false, nil
+		}
+		err := store.rdb.Persist(context.Background(), key).Err()
+		return err == nil, err
+	}
+
+	ok, err := store.rdb.PExpire(context.Background(), key, ttl).Result()
+	if err != nil {
+		log.Error("Store redis Expire %s: %s", key, err.Error())
+		return false, err
+	}
+	return ok, nil
+}
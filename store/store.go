@@ -2,11 +2,14 @@ package store
 
 import (
 	"fmt"
+	"io"
+	"path/filepath"
 	"strings"
 
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/store/file"
 	"github.com/yaoapp/gou/store/lru"
 	"github.com/yaoapp/gou/store/mongo"
 	"github.com/yaoapp/gou/store/redis"
@@ -30,6 +33,12 @@ func Load(file string, name string) (Store, error) {
 		return nil, err
 	}
 
+	// the file store holds the data file, the loaded one is closed before it is reopened
+	err = release(name)
+	if err != nil {
+		return nil, err
+	}
+
 	typ := strings.ToLower(inst.Type)
 	if typ == "lru" {
 		stor, err := New(nil, inst.Option)
@@ -40,6 +49,15 @@ func Load(file string, name string) (Store, error) {
 		return Pools[name], nil
 	}
 
+	if typ == "file" {
+		stor, err := NewFile(name, inst.Option)
+		if err != nil {
+			return nil, err
+		}
+		Pools[name] = stor
+		return Pools[name], nil
+	}
+
 	connector, has := connector.Connectors[inst.Connector]
 	if !has {
 		return nil, fmt.Errorf("Store %s Connector:%s was not loaded", name, inst.Connector)
@@ -54,6 +72,20 @@ func Load(file string, name string) (Store, error) {
 	return Pools[name], nil
 }
 
+// release close the loaded store of the name and remove it from the pools
+func release(name string) error {
+	stor, has := Pools[name]
+	if !has {
+		return nil
+	}
+
+	delete(Pools, name)
+	if closer, ok := stor.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 // Select Select loaded kv store
 func Select(name string) Store {
 	store, has := Pools[name]
@@ -76,12 +108,50 @@ func New(c connector.Connector, option Option) (Store, error) {
 		return lru.New(size)
 	}
 
+	var remote Store
+	var err error
 	if c.Is(connector.REDIS) {
-		return redis.New(c)
+		remote, err = redis.New(c)
 	} else if c.Is(connector.MONGO) {
-		return mongo.New(c)
+		remote, err = mongo.New(c)
+	} else {
+		return nil, fmt.Errorf("the connector does not support")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// layer the local lru cache in front of the remote store
+	if option != nil && option["local"] != nil {
+		return NewTiered(remote, c, option)
+	}
+	return remote, nil
+
+}
+
+// NewFile create a file store, the option path is relative to the application root, default is stores/<name>.db
+func NewFile(name string, option Option) (Store, error) {
+	path := filepath.Join("stores", fmt.Sprintf("%s.db", name))
+	opt := file.Option{}
+	if option != nil {
+		if v, has := option["path"]; has {
+			path = helper.EnvString(v, path)
+		}
+		if v, has := option["sync"]; has {
+			opt.Sync = helper.EnvString(v)
+		}
+		if v, has := option["shrink"]; has {
+			opt.Shrink = helper.EnvInt(v)
+		}
+		if v, has := option["shrink_size"]; has {
+			opt.ShrinkSize = helper.EnvInt(v)
+		}
 	}
 
-	return nil, fmt.Errorf("the connector does not support")
+	if path != ":memory:" && !filepath.IsAbs(path) && application.App != nil {
+		path = filepath.Join(application.App.Root(), path)
+	}
 
+	return file.New(path, opt)
 }
@@ -5,10 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yaoapp/gou/application"
 	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/gou/store/file"
 	"github.com/yaoapp/kun/any"
 )
 
@@ -20,18 +22,67 @@ func TestLRU(t *testing.T) {
 	lru := newStore(t, nil)
 	testBasic(t, lru)
 	testMulti(t, lru)
+	testAtomic(t, lru)
 }
 
 func TestRedis(t *testing.T) {
 	redis := newStore(t, getConnector(t, "redis"))
 	testBasic(t, redis)
 	testMulti(t, redis)
+	testAtomic(t, redis)
 }
 
 func TestMongo(t *testing.T) {
 	mongo := newStore(t, getConnector(t, "mongo"))
 	testBasic(t, mongo)
 	testMulti(t, mongo)
+	testAtomic(t, mongo)
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "file.db")
+	kv, err := NewFile("file", Option{"path": path, "sync": "always"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBasic(t, kv)
+	testMulti(t, kv)
+	testAtomic(t, kv)
+
+	// persistence
+	kv.Set("key1", "foo", 0)
+	kv.Set("key2", "bar", 200*time.Millisecond)
+	kv.Del("key1")
+	kv.Set("key1", map[string]interface{}{"foo": "bar"}, 0)
+	err = kv.(*file.Store).Shrink()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv.(*file.Store).Close()
+
+	time.Sleep(300 * time.Millisecond)
+	kv, err = NewFile("file", Option{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.(*file.Store).Close()
+
+	value, ok := kv.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, value)
+	assert.False(t, kv.Has("key2"))
+	assert.Equal(t, []string{"key1"}, kv.Keys())
+
+	// the loaded store is closed before it is replaced
+	Pools["unit.file"] = kv
+	assert.Nil(t, release("unit.file"))
+	_, has := Pools["unit.file"]
+	assert.False(t, has)
+	assert.NotNil(t, kv.(*file.Store).Close())
+
+	_, err = NewFile("file", Option{"path": path, "sync": "unknown"})
+	assert.NotNil(t, err)
 }
 
 func testBasic(t *testing.T, kv Store) {
@@ -144,6 +195,86 @@ func testMulti(t *testing.T, kv Store) {
 	assert.Equal(t, 0, kv.Len())
 }
 
+func testAtomic(t *testing.T, kv Store) {
+
+	kv.Clear()
+	value, err := kv.Incr("counter", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, value)
+
+	value, err = kv.Incr("counter", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 6, value)
+
+	value, err = kv.Decr("counter", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, value)
+
+	kv.Set("name", "foo", 0)
+	_, err = kv.Incr("name", 1)
+	assert.NotNil(t, err)
+
+	ok, err := kv.SetNX("lock", "foo", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = kv.SetNX("lock", "bar", 0)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ok, err = kv.CAS("lock", "bar", "baz", 0)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = kv.CAS("lock", "foo", "baz", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = kv.CAS("lock", nil, "foo", 0)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = kv.CAS("missing", nil, 1, 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = kv.CAD("missing", 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ok, err = kv.CAD("missing", 1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.False(t, kv.Has("missing"))
+	ok, err = kv.CAD("missing", 1)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ttl, ok := kv.TTL("lock")
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(-1), ttl)
+	_, ok = kv.TTL("unknown")
+	assert.False(t, ok)
+
+	ok, err = kv.Expire("lock", time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ttl, ok = kv.TTL("lock")
+	assert.True(t, ok)
+	assert.True(t, ttl > 0 && ttl <= time.Second)
+
+	ok, err = kv.Expire("unknown", time.Second)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	kv.Set("key1", "foo", 0)
+	kv.Set("key2", "bar", 0)
+	keys := kv.Keys("key?")
+	assert.ElementsMatch(t, []string{"key1", "key2"}, keys)
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.False(t, kv.Has("lock"))
+	ok, err = kv.SetNX("lock", "bar", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	kv.Clear()
+}
+
 func newStore(t *testing.T, c connector.Connector) Store {
 	store, err := New(c, Option{"size": 20480})
 	if err != nil {
@@ -0,0 +1,520 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/connector"
+	rdb "github.com/yaoapp/gou/connector/redis"
+	"github.com/yaoapp/gou/helper"
+	"github.com/yaoapp/gou/store/lru"
+	"github.com/yaoapp/kun/log"
+)
+
+// NewTiered create a tiered store, the local lru cache is layered in front of the remote store.
+// option: {"local": {"size": 10240, "ttl": 60}, "write": "through|behind", "flush": 1000, "invalidate": true|"<redis connector>"}
+func NewTiered(remote Store, c connector.Connector, option Option) (*Tiered, error) {
+	opt, err := tieredOption(option)
+	if err != nil {
+		return nil, err
+	}
+
+	local, err := lru.New(opt.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	tiered := &Tiered{
+		local:   local,
+		remote:  remote,
+		id:      uuid.NewString(),
+		pending: map[string]pendingWrite{},
+		stop:    make(chan struct{}),
+		Option:  opt,
+	}
+
+	if opt.Invalidate != "" {
+		if err := tiered.subscribe(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.WriteBehind {
+		tiered.wg.Add(1)
+		go tiered.flushLoop()
+	}
+
+	return tiered, nil
+}
+
+// Close stop the background flush and the invalidation subscription, the pending writes are flushed.
+// returns the error if some of the pending writes could not be written to the remote store
+func (tiered *Tiered) Close() error {
+	select {
+	case <-tiered.stop:
+		return nil
+	default:
+		close(tiered.stop)
+	}
+
+	if tiered.pubsub != nil {
+		tiered.pubsub.Close()
+	}
+	tiered.wg.Wait()
+	return tiered.Flush()
+}
+
+// Get looks up a key's value from the local cache first, then the remote store.
+func (tiered *Tiered) Get(key string) (value interface{}, ok bool) {
+	if value, ok := tiered.local.Get(key); ok {
+		return value, true
+	}
+
+	if write, has := tiered.pendingOf(key); has {
+		return write.value, !write.deleted
+	}
+
+	value, ok = tiered.remote.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	tiered.backfill(key, value)
+	return value, true
+}
+
+// Set adds a value to the local cache and the remote store.
+func (tiered *Tiered) Set(key string, value interface{}, ttl time.Duration) error {
+	if tiered.Option.WriteBehind {
+		tiered.local.Set(key, value, tiered.localTTL(ttl))
+		tiered.enqueue(key, pendingWrite{value: value, ttl: ttl, at: time.Now()})
+		return nil
+	}
+
+	err := tiered.remote.Set(key, value, ttl)
+	if err != nil {
+		tiered.local.Del(key)
+		return err
+	}
+	tiered.local.Set(key, value, tiered.localTTL(ttl))
+	tiered.publish(key)
+	return nil
+}
+
+// Del remove is used to purge a key from the local cache and the remote store
+func (tiered *Tiered) Del(key string) error {
+	tiered.local.Del(key)
+	if tiered.Option.WriteBehind {
+		tiered.enqueue(key, pendingWrite{deleted: true, at: time.Now()})
+		return nil
+	}
+
+	err := tiered.remote.Del(key)
+	tiered.publish(key)
+	return err
+}
+
+// Has check if the key exists in the local cache or the remote store
+func (tiered *Tiered) Has(key string) bool {
+	if tiered.local.Has(key) {
+		return true
+	}
+
+	if write, has := tiered.pendingOf(key); has {
+		return !write.deleted
+	}
+	return tiered.remote.Has(key)
+}
+
+// Len returns the number of the remote stored entries
+func (tiered *Tiered) Len() int {
+	if err := tiered.Flush(); err != nil {
+		log.Error("%s", err.Error())
+	}
+	return tiered.remote.Len()
+}
+
+// Keys returns the remote stored keys match the pattern
+func (tiered *Tiered) Keys(pattern ...string) []string {
+	if err := tiered.Flush(); err != nil {
+		log.Error("%s", err.Error())
+	}
+	return tiered.remote.Keys(pattern...)
+}
+
+// Clear is used to clear the local cache and the remote store
+func (tiered *Tiered) Clear() {
+	tiered.mutex.Lock()
+	tiered.pending = map[string]pendingWrite{}
+	tiered.mutex.Unlock()
+
+	tiered.local.Clear()
+	tiered.remote.Clear()
+	tiered.publish("*")
+}
+
+// GetSet looks up a key's value from the store. if does not exist add to the store
+func (tiered *Tiered) GetSet(key string, ttl time.Duration, getValue func(key string) (interface{}, error)) (interface{}, error) {
+	value, ok := tiered.Get(key)
+	if !ok {
+		var err error
+		value, err = getValue(key)
+		if err != nil {
+			return nil, err
+		}
+		tiered.Set(key, value, ttl)
+	}
+	return value, nil
+}
+
+// GetDel looks up a key's value from the store, then remove it.
+func (tiered *Tiered) GetDel(key string) (value interface{}, ok bool) {
+	value, ok = tiered.Get(key)
+	if !ok {
+		return nil, false
+	}
+
+	err := tiered.Del(key)
+	if err != nil {
+		return value, false
+	}
+	return value, true
+}
+
+// GetMulti mulit get values
+func (tiered *Tiered) GetMulti(keys []string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		value, _ := tiered.Get(key)
+		values[key] = value
+	}
+	return values
+}
+
+// SetMulti mulit set values
+func (tiered *Tiered) SetMulti(values map[string]interface{}, ttl time.Duration) {
+	for key, value := range values {
+		tiered.Set(key, value, ttl)
+	}
+}
+
+// DelMulti mulit remove values
+func (tiered *Tiered) DelMulti(keys []string) {
+	for _, key := range keys {
+		tiered.Del(key)
+	}
+}
+
+// GetSetMulti mulit get values, if does not exist add to the store
+func (tiered *Tiered) GetSetMulti(keys []string, ttl time.Duration, getValue func(key string) (interface{}, error)) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		value, err := tiered.GetSet(key, ttl, getValue)
+		if err != nil {
+			log.Error("GetSetMulti Set %s: %s", key, err.Error())
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// Incr increase the integer value atomically on the remote store
+func (tiered *Tiered) Incr(key string, delta int) (int, error) {
+	if err := tiered.flushKey(key); err != nil {
+		return 0, err
+	}
+	value, err := tiered.remote.Incr(key, delta)
+	tiered.invalidate(key)
+	return value, err
+}
+
+// Decr decrease the integer value atomically on the remote store
+func (tiered *Tiered) Decr(key string, delta int) (int, error) {
+	return tiered.Incr(key, -delta)
+}
+
+// SetNX set the value if the key does not exist on the remote store
+func (tiered *Tiered) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	if err := tiered.flushKey(key); err != nil {
+		return false, err
+	}
+	ok, err := tiered.remote.SetNX(key, value, ttl)
+	if ok {
+		tiered.invalidate(key)
+	}
+	return ok, err
+}
+
+// CAS set the value if the current value of the remote store equals to the old one
+func (tiered *Tiered) CAS(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) {
+	if err := tiered.flushKey(key); err != nil {
+		return false, err
+	}
+	ok, err := tiered.remote.CAS(key, old, value, ttl)
+	if ok {
+		tiered.invalidate(key)
+	}
+	return ok, err
+}
+
+// CAD delete the key if the current value of the remote store equals to the value
+func (tiered *Tiered) CAD(key string, value interface{}) (bool, error) {
+	if err := tiered.flushKey(key); err != nil {
+		return false, err
+	}
+	ok, err := tiered.remote.CAD(key, value)
+	if ok {
+		tiered.invalidate(key)
+	}
+	return ok, err
+}
+
+// TTL the remaining time to live of the key on the remote store
+func (tiered *Tiered) TTL(key string) (time.Duration, bool) {
+	tiered.flushKey(key)
+	return tiered.remote.TTL(key)
+}
+
+// Expire set the time to live of the key on the remote store
+func (tiered *Tiered) Expire(key string, ttl time.Duration) (bool, error) {
+	if err := tiered.flushKey(key); err != nil {
+		return false, err
+	}
+	ok, err := tiered.remote.Expire(key, ttl)
+	if ok {
+		tiered.invalidate(key)
+	}
+	return ok, err
+}
+
+// Flush write the pending writes to the remote store (write-behind mode), the failed writes are kept pending
+// and written again on the next flush, unless the key was written again in the meantime.
+func (tiered *Tiered) Flush() error {
+	tiered.flushing.Lock()
+	defer tiered.flushing.Unlock()
+
+	tiered.mutex.Lock()
+	pending := tiered.pending
+	tiered.pending = map[string]pendingWrite{}
+	tiered.mutex.Unlock()
+
+	failed := 0
+	var last error
+	for key, write := range pending {
+		if err := tiered.write(key, write); err != nil {
+			tiered.requeue(key, write)
+			failed++
+			last = err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Store tiered flush %d of %d writes failed: %s", failed, len(pending), last.Error())
+	}
+	return nil
+}
+
+// backfill the local cache, the local ttl is no longer than the remote one
+func (tiered *Tiered) backfill(key string, value interface{}) {
+	ttl := tiered.Option.TTL
+	if remain, ok := tiered.remote.TTL(key); ok && remain > 0 && (ttl <= 0 || remain < ttl) {
+		ttl = remain
+	}
+	tiered.local.Set(key, value, ttl)
+}
+
+// localTTL the ttl of the local entry
+func (tiered *Tiered) localTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && (tiered.Option.TTL <= 0 || ttl < tiered.Option.TTL) {
+		return ttl
+	}
+	return tiered.Option.TTL
+}
+
+// invalidate remove the key from the local caches of all instances
+func (tiered *Tiered) invalidate(key string) {
+	tiered.local.Del(key)
+	tiered.publish(key)
+}
+
+func (tiered *Tiered) pendingOf(key string) (pendingWrite, bool) {
+	tiered.mutex.Lock()
+	defer tiered.mutex.Unlock()
+	write, has := tiered.pending[key]
+	return write, has
+}
+
+func (tiered *Tiered) enqueue(key string, write pendingWrite) {
+	tiered.mutex.Lock()
+	defer tiered.mutex.Unlock()
+	tiered.pending[key] = write
+}
+
+// requeue keep the failed write pending if the key was not written again
+func (tiered *Tiered) requeue(key string, write pendingWrite) {
+	tiered.mutex.Lock()
+	defer tiered.mutex.Unlock()
+	if _, has := tiered.pending[key]; !has {
+		tiered.pending[key] = write
+	}
+}
+
+// flushKey write the pending write of the key before the atomic operations
+func (tiered *Tiered) flushKey(key string) error {
+	tiered.flushing.Lock()
+	defer tiered.flushing.Unlock()
+
+	tiered.mutex.Lock()
+	write, has := tiered.pending[key]
+	delete(tiered.pending, key)
+	tiered.mutex.Unlock()
+
+	if !has {
+		return nil
+	}
+
+	err := tiered.write(key, write)
+	if err != nil {
+		tiered.requeue(key, write)
+	}
+	return err
+}
+
+func (tiered *Tiered) write(key string, write pendingWrite) error {
+	var err error
+	if write.deleted {
+		err = tiered.remote.Del(key)
+	} else if write.ttl <= 0 {
+		err = tiered.remote.Set(key, write.value, 0)
+	} else if remain := write.ttl - time.Since(write.at); remain > 0 {
+		err = tiered.remote.Set(key, write.value, remain)
+	} else {
+		err = tiered.remote.Del(key) // expired before flushing
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %s", key, err.Error())
+	}
+	tiered.publish(key)
+	return nil
+}
+
+func (tiered *Tiered) flushLoop() {
+	defer tiered.wg.Done()
+	ticker := time.NewTicker(tiered.Option.Flush)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tiered.stop:
+			return
+		case <-ticker.C:
+			if err := tiered.Flush(); err != nil {
+				log.Error("%s", err.Error())
+			}
+		}
+	}
+}
+
+// subscribe the invalidation messages via redis pub/sub
+func (tiered *Tiered) subscribe(c connector.Connector) error {
+	if tiered.Option.Invalidate != "true" {
+		var has bool
+		c, has = connector.Connectors[tiered.Option.Invalidate]
+		if !has {
+			return fmt.Errorf("the invalidate connector %s was not loaded", tiered.Option.Invalidate)
+		}
+	}
+
+	conn, ok := c.(*rdb.Connector)
+	if !ok {
+		return fmt.Errorf("the invalidate connector should be a redis connector")
+	}
+
+	if tiered.Option.Channel == "" {
+		tiered.Option.Channel = fmt.Sprintf("%s:store:invalidate", conn.Name)
+	}
+
+	tiered.rdb = conn.Rdb
+	tiered.pubsub = conn.Rdb.Subscribe(context.Background(), tiered.Option.Channel)
+	if _, err := tiered.pubsub.Receive(context.Background()); err != nil {
+		tiered.pubsub.Close()
+		return err
+	}
+
+	tiered.wg.Add(1)
+	go func() {
+		defer tiered.wg.Done()
+		for msg := range tiered.pubsub.Channel() {
+			tiered.onInvalidate(msg.Payload)
+		}
+	}()
+	return nil
+}
+
+// onInvalidate the message is <instance id>|<key>, * means all of the keys
+func (tiered *Tiered) onInvalidate(payload string) {
+	id, key, found := strings.Cut(payload, "|")
+	if !found || id == tiered.id {
+		return
+	}
+
+	if key == "*" {
+		tiered.local.Clear()
+		return
+	}
+	tiered.local.Del(key)
+}
+
+func (tiered *Tiered) publish(key string) {
+	if tiered.rdb == nil {
+		return
+	}
+
+	err := tiered.rdb.Publish(context.Background(), tiered.Option.Channel, fmt.Sprintf("%s|%s", tiered.id, key)).Err()
+	if err != nil {
+		log.Error("Store tiered publish %s: %s", key, err.Error())
+	}
+}
+
+func tieredOption(option Option) (TieredOption, error) {
+	opt := TieredOption{Size: 10240, TTL: 60 * time.Second, Flush: time.Second}
+	if local, ok := option["local"].(map[string]interface{}); ok {
+		if v, has := local["size"]; has {
+			opt.Size = helper.EnvInt(v, opt.Size)
+		}
+		if v, has := local["ttl"]; has {
+			opt.TTL = time.Duration(helper.EnvInt(v, 60)) * time.Second
+		}
+	}
+
+	switch strings.ToLower(helper.EnvString(option["write"], "through")) {
+	case "", "through":
+	case "behind":
+		opt.WriteBehind = true
+	default:
+		return opt, fmt.Errorf("the write mode %v does not support", option["write"])
+	}
+
+	if v, has := option["flush"]; has {
+		if flush := helper.EnvInt(v, 1000); flush > 0 {
+			opt.Flush = time.Duration(flush) * time.Millisecond
+		}
+	}
+
+	switch v := option["invalidate"].(type) {
+	case bool:
+		if v {
+			opt.Invalidate = "true"
+		}
+	case string:
+		opt.Invalidate = helper.EnvString(v)
+	}
+
+	if v, has := option["channel"]; has {
+		opt.Channel = helper.EnvString(v)
+	}
+	return opt, nil
+}
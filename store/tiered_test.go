@@ -0,0 +1,162 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/store/lru"
+)
+
+func TestTiered(t *testing.T) {
+	remote, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiered, err := NewTiered(remote, nil, Option{"local": map[string]interface{}{"size": 100, "ttl": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tiered.Close()
+
+	testBasic(t, tiered)
+	testMulti(t, tiered)
+	testAtomic(t, tiered)
+
+	// read through with backfill
+	remote.Set("key1", "foo", 0)
+	value, ok := tiered.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", value)
+
+	remote.Set("key1", "bar", 0)
+	value, _ = tiered.Get("key1")
+	assert.Equal(t, "foo", value)
+
+	// the local entry is expired after the local ttl
+	time.Sleep(1100 * time.Millisecond)
+	value, _ = tiered.Get("key1")
+	assert.Equal(t, "bar", value)
+
+	// the invalidation message of the other instance
+	remote.Set("key1", "baz", 0)
+	tiered.onInvalidate(tiered.id + "|key1")
+	value, _ = tiered.Get("key1")
+	assert.Equal(t, "bar", value)
+	tiered.onInvalidate("other|key1")
+	value, _ = tiered.Get("key1")
+	assert.Equal(t, "baz", value)
+	tiered.Clear()
+}
+
+func TestTieredWriteBehind(t *testing.T) {
+	remote, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiered, err := NewTiered(remote, nil, Option{"local": map[string]interface{}{"size": 100}, "write": "behind", "flush": 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiered.Set("key1", "foo", 0)
+	tiered.Set("key2", "bar", time.Minute)
+	assert.False(t, remote.Has("key1"))
+	value, ok := tiered.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", value)
+
+	time.Sleep(200 * time.Millisecond)
+	value, _ = remote.Get("key1")
+	assert.Equal(t, "foo", value)
+	ttl, _ := remote.TTL("key2")
+	assert.True(t, ttl > 0)
+
+	// the atomic operations flush the pending write of the key first
+	tiered.Set("counter", 10, 0)
+	count, err := tiered.Incr("counter", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 11, count)
+
+	tiered.Del("key1")
+	assert.False(t, tiered.Has("key1"))
+	assert.True(t, remote.Has("key1"))
+
+	tiered.Close()
+	assert.False(t, remote.Has("key1"))
+
+	_, err = NewTiered(remote, nil, Option{"local": map[string]interface{}{}, "write": "unknown"})
+	assert.NotNil(t, err)
+}
+
+func TestTieredWriteBehindFailure(t *testing.T) {
+	cache, err := lru.New(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := &unreachableStore{Cache: cache, down: true}
+	tiered, err := NewTiered(remote, nil, Option{"local": map[string]interface{}{"size": 100}, "write": "behind", "flush": 60000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiered.Set("key1", "foo", 0)
+	assert.NotNil(t, tiered.Flush())
+	assert.False(t, cache.Has("key1"))
+
+	// the failed write is kept pending
+	_, err = tiered.Incr("key1", 1)
+	assert.NotNil(t, err)
+	value, _ := tiered.Get("key1")
+	assert.Equal(t, "foo", value)
+
+	remote.down = false
+	assert.Nil(t, tiered.Close())
+	value, _ = cache.Get("key1")
+	assert.Equal(t, "foo", value)
+}
+
+// unreachableStore the remote store fails the writes while it is down
+type unreachableStore struct {
+	*lru.Cache
+	down bool
+}
+
+func (s *unreachableStore) Set(key string, value interface{}, ttl time.Duration) error {
+	if s.down {
+		return fmt.Errorf("connection refused")
+	}
+	return s.Cache.Set(key, value, ttl)
+}
+
+func TestTieredRedis(t *testing.T) {
+	prepare(t)
+	c := getConnector(t, "redis")
+	option := Option{"local": map[string]interface{}{"size": 100, "ttl": 60}, "invalidate": true}
+
+	first, err := New(c, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.(*Tiered).Close()
+
+	second, err := New(c, option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.(*Tiered).Close()
+
+	first.Set("key1", "foo", 0)
+	value, _ := second.Get("key1")
+	assert.Equal(t, "foo", value)
+
+	first.Set("key1", "bar", 0)
+	time.Sleep(100 * time.Millisecond)
+	value, _ = second.Get("key1")
+	assert.Equal(t, "bar", value)
+	first.Clear()
+}
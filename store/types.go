@@ -1,6 +1,12 @@
 package store
 
-import "time"
+import (
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yaoapp/gou/store/lru"
+)
 
 // Store The interface of a key-value store
 type Store interface {
@@ -9,7 +15,7 @@ type Store interface {
 	Del(key string) error
 	Has(key string) bool
 	Len() int
-	Keys() []string
+	Keys(pattern ...string) []string // the keys match the glob pattern, e.g. user:*, all of the keys if the pattern is not given
 	Clear()
 	GetSet(key string, ttl time.Duration, getValue func(key string) (interface{}, error)) (interface{}, error)
 	GetDel(key string) (value interface{}, ok bool)
@@ -17,6 +23,13 @@ type Store interface {
 	SetMulti(values map[string]interface{}, ttl time.Duration)
 	DelMulti(keys []string)
 	GetSetMulti(keys []string, ttl time.Duration, getValue func(key string) (interface{}, error)) map[string]interface{}
+	Incr(key string, delta int) (int, error)                                             // increase the integer value atomically, the missing key is set to the delta
+	Decr(key string, delta int) (int, error)                                             // decrease the integer value atomically
+	SetNX(key string, value interface{}, ttl time.Duration) (bool, error)                // set the value if the key does not exist
+	CAS(key string, old interface{}, value interface{}, ttl time.Duration) (bool, error) // set the value if the current value equals to the old one, nil old means the key does not exist
+	CAD(key string, value interface{}) (bool, error)                                     // delete the key if the current value equals to the value
+	TTL(key string) (ttl time.Duration, ok bool)                                         // the remaining time to live, -1 if the key never expires
+	Expire(key string, ttl time.Duration) (bool, error)                                  // set the time to live of the key, ttl <= 0 removes the expiration
 }
 
 // Instance the kv-store setting
@@ -30,3 +43,54 @@ type Instance struct {
 
 // Option the store option
 type Option map[string]interface{}
+
+// Lock the distributed lock on the store
+type Lock struct {
+	store Store
+	Key   string
+	Token string
+	TTL   time.Duration
+}
+
+// Leader the leader election via the lock, the holder of the lock is the leader
+type Leader struct {
+	lock     *Lock
+	interval time.Duration
+	leading  bool
+	until    time.Time
+	stop     chan struct{}
+	done     chan struct{}
+	mutex    sync.Mutex
+}
+
+// Tiered the tiered store, the local lru cache is layered in front of the remote store
+type Tiered struct {
+	local    *lru.Cache
+	remote   Store
+	id       string                  // the instance id, the invalidation messages of the instance itself are ignored
+	pending  map[string]pendingWrite // the pending writes of the write-behind mode
+	rdb      *redis.Client
+	pubsub   *redis.PubSub
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+	flushing sync.Mutex // serialize the flushes, a stale pending write is not written after a newer one
+	Option   TieredOption
+}
+
+// TieredOption the tiered store option
+type TieredOption struct {
+	Size        int           // the size of the local cache
+	TTL         time.Duration // the max time to live of the local entries
+	WriteBehind bool          // write the remote store in the background
+	Flush       time.Duration // the flush interval of the write-behind mode
+	Invalidate  string        // "true" uses the redis connector of the remote store, or the redis connector name
+	Channel     string        // the pub/sub channel of the invalidation messages
+}
+
+type pendingWrite struct {
+	value   interface{}
+	ttl     time.Duration
+	at      time.Time
+	deleted bool
+}
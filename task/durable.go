@@ -0,0 +1,261 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yaoapp/gou/task/queue"
+	"github.com/yaoapp/kun/log"
+)
+
+// push add a job to the durable queue
+func (t *Task) push(option AddOption, args ...interface{}) (int, error) {
+	record := &queue.Job{
+		Args:     args,
+		Priority: option.Priority,
+		RunAt:    option.runAt(),
+		Unique:   option.Unique,
+		Merge:    option.Merge,
+	}
+
+	if t.handlers.NextID != nil {
+		id, err := t.handlers.NextID()
+		if err != nil {
+			return 0, fmt.Errorf("[TASK] %s can't get next id (%s)", t.name, err.Error())
+		}
+		record.ID = id
+	}
+
+	created, err := t.queue.Push(record)
+	if err != nil {
+		return 0, fmt.Errorf("[TASK] %s push the job: %s", t.name, err.Error())
+	}
+
+	// the job is duplicated
+	if !created {
+		return record.ID, nil
+	}
+
+	t.add(&Job{id: record.ID, args: args})
+	t.wakeup()
+	return record.ID, nil
+}
+
+// dispatch dispatch the due jobs when there are idle workers
+func (t *Task) dispatch(interrupt chan os.Signal) {
+	for {
+		var worker *Worker
+		select {
+		case worker = <-t.pool.workerque:
+		case <-interrupt:
+			return
+		case <-t.ctx.Done():
+			return
+		}
+
+		job := t.next()
+		if job != nil {
+			worker.job <- job
+			continue
+		}
+
+		// there is no due job, wait for the next one
+		t.pool.workerque <- worker
+		select {
+		case <-t.notify:
+		case <-time.After(t.idle()):
+		case <-interrupt:
+			return
+		case <-t.ctx.Done():
+			return
+		}
+	}
+}
+
+// next the next due job, the jobs are claimed from the durable queue or popped from the in-memory heap
+func (t *Task) next() *Job {
+	if t.queue != nil {
+		return t.claim()
+	}
+	return t.pop()
+}
+
+// claim claim the next job of the durable queue
+func (t *Task) claim() *Job {
+	record, err := t.queue.Claim(t.worker, t.visibility())
+	if err != nil {
+		log.Error("[TASK] %s claim the job: %s", t.name, err.Error())
+		return nil
+	}
+
+	if record == nil {
+		return nil
+	}
+
+	if record.Attempts > 1 {
+		log.Warn("[TASK] %s Job:%v was recovered (attempts %d)", t.name, record.ID, record.Attempts)
+	}
+
+	timeout := time.Duration(t.timeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	job := &Job{
+		id:       record.ID,
+		attempts: record.Attempts,
+		priority: record.Priority,
+		args:     record.Args,
+		timeout:  timeout,
+		ctx:      ctx,
+		cancel:   cancel,
+		status:   WAITING,
+		curr:     record.Current,
+		total:    record.Total,
+		message:  record.Message,
+		record:   record,
+	}
+
+	t.mutex.Lock()
+	t.jobs[job.id] = job
+	t.mutex.Unlock()
+	return job
+}
+
+// heartbeat extend the visibility timeout of the claimed job, the job is canceled if it was claimed by the others
+func (t *Task) heartbeat(job *Job, done chan struct{}) {
+	ticker := time.NewTicker(t.visibility() / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if !t.save(job) {
+				log.Error("[TASK] %s Job:%v the job was claimed by the others or canceled", t.name, job.id)
+				job.cancel()
+				return
+			}
+		}
+	}
+}
+
+// save save the progress of the claimed job, false if the job was claimed by the others
+func (t *Task) save(job *Job) bool {
+	if job.record == nil {
+		return true
+	}
+
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.record.Current = job.curr
+	job.record.Total = job.total
+	job.record.Message = job.message
+	ok, err := t.queue.Save(job.record, t.visibility())
+	if err != nil {
+		log.Error("[TASK] %s Job:%v save the progress: %s", t.name, job.id, err.Error())
+		return true // keep running, the visibility timeout will be extended on the next heartbeat
+	}
+	return ok
+}
+
+// complete save the result of the claimed job, the canceled job was saved by the queue
+func (t *Task) complete(job *Job) {
+	if job.record == nil {
+		return
+	}
+
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	if job.canceled {
+		return
+	}
+
+	job.record.Status = job.status
+	job.record.Dead = job.dead
+	job.record.Response = job.response
+	job.record.Current = job.curr
+	job.record.Total = job.total
+	job.record.Message = job.message
+	ok, err := t.queue.Complete(job.record)
+	if err != nil {
+		log.Error("[TASK] %s Job:%v save the result: %s", t.name, job.id, err.Error())
+		return
+	}
+
+	if !ok {
+		log.Error("[TASK] %s Job:%v the result was discarded, the job was claimed by the others", t.name, job.id)
+	}
+}
+
+// release release the claimed job, it will be claimed again after the given time
+func (t *Task) release(job *Job, at time.Time) {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	job.record.Response = job.response
+	job.record.Current = job.curr
+	job.record.Total = job.total
+	job.record.Message = job.message
+	ok, err := t.queue.Retry(job.record, at)
+	if err != nil {
+		log.Error("[TASK] %s Job:%v release the job: %s", t.name, job.id, err.Error())
+		return
+	}
+
+	if !ok {
+		log.Error("[TASK] %s Job:%v the job was not released, the job was claimed by the others", t.name, job.id)
+	}
+}
+
+// interrupt release the running job when the task is stopped, it will be claimed again by any instance
+func (t *Task) interrupt(job *Job) {
+	job.lock.Lock()
+	job.status = WAITING
+	job.lock.Unlock()
+
+	t.release(job, time.Now())
+	t.remove(job.id)
+}
+
+func (t *Task) job(id int) (*Job, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	job, has := t.jobs[id]
+	return job, has
+}
+
+func (t *Task) remove(id int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.jobs, id)
+}
+
+// done remove the completed job, the dead in-memory job is moved to the dead-letter list
+func (t *Task) done(job *Job) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.jobs, job.id)
+	t.count(job)
+	if job.record != nil {
+		return
+	}
+
+	if job.dead {
+		t.dead[job.id] = job
+		return
+	}
+	t.finish(job)
+}
+
+func (t *Task) visibility() time.Duration {
+	return time.Duration(t.Option.Visibility) * time.Second
+}
+
+// workerID the worker id of the instance
+func workerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
@@ -0,0 +1,178 @@
+package task
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// newHeap create a heap of the in-memory jobs
+func newHeap(less func(a, b *Job) bool) *jobHeap {
+	return &jobHeap{jobs: []*Job{}, less: less}
+}
+
+// byPriority the jobs of the higher priority first, then the earlier enqueued
+func byPriority(a, b *Job) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.order < b.order
+}
+
+// byRunAt the jobs of the earlier run time first
+func byRunAt(a, b *Job) bool {
+	return a.runAt.Before(b.runAt)
+}
+
+func (h *jobHeap) Len() int           { return len(h.jobs) }
+func (h *jobHeap) Less(i, j int) bool { return h.less(h.jobs[i], h.jobs[j]) }
+func (h *jobHeap) Swap(i, j int) {
+	h.jobs[i], h.jobs[j] = h.jobs[j], h.jobs[i]
+	h.jobs[i].index = i
+	h.jobs[j].index = j
+}
+
+// Push push the job (heap.Interface)
+func (h *jobHeap) Push(x interface{}) {
+	job := x.(*Job)
+	job.index = len(h.jobs)
+	h.jobs = append(h.jobs, job)
+}
+
+// Pop pop the job (heap.Interface)
+func (h *jobHeap) Pop() interface{} {
+	n := len(h.jobs)
+	job := h.jobs[n-1]
+	h.jobs[n-1] = nil
+	h.jobs = h.jobs[:n-1]
+	job.index = -1
+	return job
+}
+
+func (h *jobHeap) has(job *Job) bool {
+	return job.index >= 0 && job.index < len(h.jobs) && h.jobs[job.index] == job
+}
+
+// remove remove the job from the heap, false if the job is not in the heap
+func (h *jobHeap) remove(job *Job) bool {
+	if !h.has(job) {
+		return false
+	}
+	heap.Remove(h, job.index)
+	return true
+}
+
+// runAt the run time of the job, zero if the job runs immediately
+func (option AddOption) runAt() time.Time {
+	if !option.RunAt.IsZero() {
+		return option.RunAt
+	}
+
+	if option.Delay > 0 {
+		return time.Now().Add(option.Delay)
+	}
+	return time.Time{}
+}
+
+// enqueue push the in-memory job to the waiting or delayed heap (the lock must be held)
+func (t *Task) enqueue(job *Job) {
+	t.enqueued++
+	job.order = t.enqueued
+	job.status = WAITING
+	if job.runAt.After(time.Now()) {
+		heap.Push(t.delayed, job)
+	} else {
+		heap.Push(t.waiting, job)
+	}
+	t.wakeup()
+}
+
+// pop pop the due in-memory job of the highest priority, nil if there is no due job
+func (t *Task) pop() *Job {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	for t.delayed.Len() > 0 && !t.delayed.jobs[0].runAt.After(now) {
+		heap.Push(t.waiting, heap.Pop(t.delayed))
+	}
+
+	if t.waiting.Len() == 0 {
+		return nil
+	}
+
+	job := heap.Pop(t.waiting).(*Job)
+	t.releaseKey(job)
+
+	// the timeout starts when the job is dispatched
+	ctx, cancel := context.WithTimeout(context.Background(), job.timeout)
+	job.lock.Lock()
+	job.ctx, job.cancel = ctx, cancel
+	if job.canceled {
+		cancel()
+	}
+	job.lock.Unlock()
+	return job
+}
+
+// unqueue remove the waiting in-memory job, false if the job is not waiting
+func (t *Task) unqueue(job *Job) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.waiting.remove(job) || t.delayed.remove(job) {
+		t.releaseKey(job)
+		return true
+	}
+	return false
+}
+
+// merge merge the duplicated job into the waiting one (the lock must be held)
+func (t *Task) merge(job *Job, option AddOption, args []interface{}) {
+	job.args = args
+	if option.Priority > job.priority {
+		job.priority = option.Priority
+		if t.waiting.has(job) {
+			heap.Fix(t.waiting, job.index)
+		}
+	}
+}
+
+// releaseKey release the uniqueness key of the job which is no longer waiting (the lock must be held)
+func (t *Task) releaseKey(job *Job) {
+	if pending, has := t.unique[job.unique]; has && pending == job {
+		delete(t.unique, job.unique)
+	}
+}
+
+// size the number of the waiting in-memory jobs (the lock must be held)
+func (t *Task) size() int {
+	return t.waiting.Len() + t.delayed.Len()
+}
+
+// idle the time to wait for the next job when there is no due job
+func (t *Task) idle() time.Duration {
+	poll := time.Duration(t.Option.Poll) * time.Millisecond
+	if t.queue != nil {
+		return poll
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.delayed.Len() > 0 {
+		if wait := time.Until(t.delayed.jobs[0].runAt); wait < poll {
+			if wait < 0 {
+				return 0
+			}
+			return wait
+		}
+	}
+	return poll
+}
+
+// wakeup wake up the dispatcher
+func (t *Task) wakeup() {
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
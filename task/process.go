@@ -1,9 +1,16 @@
 package task
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/connector"
 	"github.com/yaoapp/gou/helper"
 	"github.com/yaoapp/gou/process"
+	"github.com/yaoapp/gou/task/queue"
 	"github.com/yaoapp/kun/any"
 	"github.com/yaoapp/kun/exception"
 	"github.com/yaoapp/kun/log"
@@ -12,8 +19,15 @@ import (
 // TaskHandlers task process handlers
 var TaskHandlers = map[string]process.Handler{
 	"add":      processTaskAdd,
+	"addwith":  processTaskAddWith,
 	"progress": processTaskProgress,
 	"get":      processTaskGet,
+	"cancel":   processTaskCancel,
+	"dead":     processTaskDead,
+	"requeue":  processTaskRequeue,
+	"purge":    processTaskPurge,
+	"list":     processTaskList,
+	"stats":    processTaskStats,
 }
 
 func init() {
@@ -22,14 +36,18 @@ func init() {
 
 // ProcessOption the task process option
 type ProcessOption struct {
-	Name         string      `json:"name"`
-	Process      string      `json:"process"`
-	Size         interface{} `json:"size,omitempty"`
-	WorkerNums   interface{} `json:"worker_nums,omitempty"`
-	AttemptAfter interface{} `json:"attempt_after,omitempty"`
-	Attempts     interface{} `json:"attempts,omitempty"`
-	Timeout      interface{} `json:"timeout,omitempty"`
-	Event        struct {
+	Name            string       `json:"name"`
+	Process         string       `json:"process"`
+	Size            interface{}  `json:"size,omitempty"`
+	WorkerNums      interface{}  `json:"worker_nums,omitempty"`
+	AttemptAfter    interface{}  `json:"attempt_after,omitempty"`
+	Attempts        interface{}  `json:"attempts,omitempty"`
+	Backoff         string       `json:"backoff,omitempty"`
+	MaxAttemptAfter interface{}  `json:"max_attempt_after,omitempty"`
+	Retry           *RetryOption `json:"retry,omitempty"`
+	Timeout         interface{}  `json:"timeout,omitempty"`
+	Queue           *QueueOption `json:"queue,omitempty"`
+	Event           struct {
 		Next     string `json:"next,omitempty"`
 		Add      string `json:"add,omitempty"`
 		Success  string `json:"success,omitempty"`
@@ -38,6 +56,20 @@ type ProcessOption struct {
 	} `json:"event"`
 }
 
+// QueueOption the durable job queue option of the task
+type QueueOption struct {
+	Connector  string      `json:"connector,omitempty"`  // the redis, mongo or database connector, the jobs are kept in the memory if it is empty
+	Table      string      `json:"table,omitempty"`      // the table (collection) name of the jobs, default is task_jobs
+	Retention  interface{} `json:"retention,omitempty"`  // the completed jobs are removed after the retention (seconds)
+	Visibility interface{} `json:"visibility,omitempty"` // the visibility timeout of the claimed jobs (seconds)
+}
+
+// RetryOption decide whether the failed job should be retried by the error message, e.g. "Exception|503" or "connection refused"
+type RetryOption struct {
+	On     []string `json:"on,omitempty"`     // the errors contain one of the patterns are retried, all the errors are retried if it is empty
+	Except []string `json:"except,omitempty"` // the errors contain one of the patterns are never retried
+}
+
 // Load load task
 func Load(file string, name string) (*Task, error) {
 
@@ -53,12 +85,40 @@ func Load(file string, name string) (*Task, error) {
 	}
 
 	option := Option{
-		Name:           name,
-		Timeout:        helper.EnvInt(o.Timeout),
-		WorkerNums:     helper.EnvInt(o.WorkerNums),
-		JobQueueLength: helper.EnvInt(o.Size),
-		AttemptAfter:   helper.EnvInt(o.AttemptAfter),
-		Attempts:       helper.EnvInt(o.Attempts),
+		Name:            name,
+		Timeout:         helper.EnvInt(o.Timeout),
+		WorkerNums:      helper.EnvInt(o.WorkerNums),
+		JobQueueLength:  helper.EnvInt(o.Size),
+		AttemptAfter:    helper.EnvInt(o.AttemptAfter),
+		Attempts:        helper.EnvInt(o.Attempts),
+		Backoff:         o.Backoff,
+		MaxAttemptAfter: helper.EnvInt(o.MaxAttemptAfter),
+	}
+
+	switch option.Backoff {
+	case "", "fixed", "exponential", "jitter":
+	default:
+		return nil, fmt.Errorf("Task %s backoff %s does not support (fixed, exponential or jitter)", name, option.Backoff)
+	}
+
+	if o.Queue != nil {
+		var c connector.Connector
+		if o.Queue.Connector != "" {
+			var has bool
+			c, has = connector.Connectors[o.Queue.Connector]
+			if !has {
+				return nil, fmt.Errorf("Task %s Connector:%s was not loaded", name, o.Queue.Connector)
+			}
+		}
+
+		option.Queue, err = queue.New(name, c, queue.Option{
+			Table:     o.Queue.Table,
+			Retention: time.Duration(helper.EnvInt(o.Queue.Retention)) * time.Second,
+		})
+		if err != nil {
+			return nil, err
+		}
+		option.Visibility = helper.EnvInt(o.Queue.Visibility)
 	}
 
 	handlers := taskEventHandlers(name, o)
@@ -91,6 +151,22 @@ func processTaskAdd(process *process.Process) interface{} {
 	return v
 }
 
+// processTaskAddWith tasks.<name>.AddWith(option, args...) the option: {"priority": 10, "delay": 60, "run_at": "2023-08-01 00:00:00", "unique": "reindex", "merge": true}
+func processTaskAddWith(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	t := Select(process.ID)
+	option, err := addOptionOf(process.Args[0])
+	if err != nil {
+		exception.New("Task %s AddWith: %s", 400, process.ID, err).Throw()
+	}
+
+	v, err := t.AddWith(option, process.Args[1:]...)
+	if err != nil {
+		exception.New("Task %s AddWith: %s", 500, process.ID, err).Throw()
+	}
+	return v
+}
+
 // processTaskProgress
 func processTaskProgress(process *process.Process) interface{} {
 	process.ValidateArgNums(4)
@@ -118,14 +194,208 @@ func processTaskGet(process *process.Process) interface{} {
 	return job
 }
 
+// processTaskCancel
+func processTaskCancel(process *process.Process) interface{} {
+	process.ValidateArgNums(1)
+	t := Select(process.ID)
+	canceled, err := t.Cancel(process.ArgsInt(0))
+	if err != nil {
+		exception.New("Task %s Cancel: %s", 500, process.ID, err).Throw()
+	}
+	return canceled
+}
+
+// processTaskDead
+func processTaskDead(process *process.Process) interface{} {
+	t := Select(process.ID)
+	jobs, err := t.Dead()
+	if err != nil {
+		exception.New("Task %s Dead: %s", 500, process.ID, err).Throw()
+	}
+	return jobs
+}
+
+// processTaskRequeue
+func processTaskRequeue(process *process.Process) interface{} {
+	t := Select(process.ID)
+	requeued, err := t.Requeue(argsIDs(process)...)
+	if err != nil {
+		exception.New("Task %s Requeue: %s", 500, process.ID, err).Throw()
+	}
+	return requeued
+}
+
+// processTaskPurge
+func processTaskPurge(process *process.Process) interface{} {
+	t := Select(process.ID)
+	removed, err := t.Purge(argsIDs(process)...)
+	if err != nil {
+		exception.New("Task %s Purge: %s", 500, process.ID, err).Throw()
+	}
+	return removed
+}
+
+// processTaskList tasks.<name>.List(status, page, pagesize) the status: WAITING, RUNNING, SUCCESS, FAILURE or empty for all
+func processTaskList(process *process.Process) interface{} {
+	t := Select(process.ID)
+	jobStatus := 0
+	if process.NumOfArgs() > 0 {
+		var err error
+		jobStatus, err = statusOf(process.Args[0])
+		if err != nil {
+			exception.New("Task %s List: %s", 400, process.ID, err).Throw()
+		}
+	}
+
+	page := 1
+	if process.NumOfArgs() > 1 {
+		page = process.ArgsInt(1)
+	}
+
+	pagesize := 20
+	if process.NumOfArgs() > 2 {
+		pagesize = process.ArgsInt(2)
+	}
+
+	res, err := t.List(jobStatus, page, pagesize)
+	if err != nil {
+		exception.New("Task %s List: %s", 500, process.ID, err).Throw()
+	}
+	return res
+}
+
+// processTaskStats
+func processTaskStats(process *process.Process) interface{} {
+	t := Select(process.ID)
+	res, err := t.Stats()
+	if err != nil {
+		exception.New("Task %s Stats: %s", 500, process.ID, err).Throw()
+	}
+	return res
+}
+
+// statusOf the job status of the name or the code, 0 if it is empty
+func statusOf(value interface{}) (int, error) {
+	name, ok := value.(string)
+	if !ok {
+		return any.Of(value).CInt(), nil
+	}
+
+	if name == "" {
+		return 0, nil
+	}
+
+	for code, text := range status {
+		if strings.EqualFold(text, name) {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("the status %s does not support (WAITING, RUNNING, SUCCESS or FAILURE)", name)
+}
+
+// argsIDs the job ids of the args, the ids could be an array or the arguments list
+func argsIDs(process *process.Process) []int {
+	ids := []int{}
+	for _, arg := range process.Args {
+		if values, ok := arg.([]interface{}); ok {
+			for _, value := range values {
+				ids = append(ids, any.Of(value).CInt())
+			}
+			continue
+		}
+		ids = append(ids, any.Of(arg).CInt())
+	}
+	return ids
+}
+
+// addOptionOf the per-job option of the process argument
+// delay: the seconds or the duration string (e.g. "5m"). run_at: the unix timestamp (seconds), RFC3339 or "2006-01-02 15:04:05" (local time)
+func addOptionOf(value interface{}) (AddOption, error) {
+	option := AddOption{}
+	if value == nil {
+		return option, nil
+	}
+
+	input, ok := value.(map[string]interface{})
+	if !ok {
+		return option, fmt.Errorf("the option should be a map, %#v given", value)
+	}
+
+	if v, has := input["priority"]; has {
+		option.Priority = any.Of(v).CInt()
+	}
+
+	if v, has := input["unique"]; has && v != nil {
+		option.Unique = fmt.Sprintf("%v", v)
+	}
+
+	if v, has := input["merge"]; has {
+		option.Merge = any.Of(v).CBool()
+	}
+
+	switch v := input["delay"].(type) {
+	case nil:
+	case string:
+		delay, err := time.ParseDuration(v)
+		if err != nil {
+			return option, fmt.Errorf("delay %s", err.Error())
+		}
+		option.Delay = delay
+	default:
+		option.Delay = time.Duration(any.Of(v).CFloat64() * float64(time.Second))
+	}
+
+	switch v := input["run_at"].(type) {
+	case nil:
+	case string:
+		runAt, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			runAt, err = time.ParseInLocation("2006-01-02 15:04:05", v, time.Local)
+		}
+
+		if err != nil {
+			return option, fmt.Errorf("run_at %s should be RFC3339 or 2006-01-02 15:04:05", v)
+		}
+		option.RunAt = runAt
+	case time.Time:
+		option.RunAt = v
+	default:
+		option.RunAt = time.Unix(int64(any.Of(v).CInt()), 0)
+	}
+
+	return option, nil
+}
+
 func taskEventHandlers(name string, o ProcessOption) *Handlers {
 	handlers := &Handlers{
-		Exec: func(id int, args ...interface{}) (interface{}, error) {
+		Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
 			args = append([]interface{}{id}, args...)
-			return process.New(o.Process, args...).Exec()
+			return process.New(o.Process, args...).WithContext(ctx).Exec()
 		},
 	}
 
+	if o.Retry != nil {
+		handlers.Retry = func(id int, err error) bool {
+			message := err.Error()
+			for _, pattern := range o.Retry.Except {
+				if strings.Contains(message, pattern) {
+					return false
+				}
+			}
+
+			if len(o.Retry.On) == 0 {
+				return true
+			}
+
+			for _, pattern := range o.Retry.On {
+				if strings.Contains(message, pattern) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
 	if o.Event.Next != "" {
 		handlers.NextID = func() (int, error) {
 			id, err := process.New(o.Event.Next).Exec()
@@ -0,0 +1,534 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/connector"
+	"github.com/yaoapp/kun/any"
+	"github.com/yaoapp/kun/log"
+	"github.com/yaoapp/xun/dbal/query"
+	"github.com/yaoapp/xun/dbal/schema"
+)
+
+// claimRetries the number of the retries when the candidate job was claimed by the others
+const claimRetries = 5
+
+// NewDatabase create a database queue, the table is created if it does not exist
+func NewDatabase(name string, c connector.Connector, option Option) (*Database, error) {
+	sch, err := c.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	has, err := sch.HasTable(option.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	if !has {
+		err = sch.CreateTable(option.Table, func(table schema.Blueprint) {
+			table.BigIncrements("id")
+			table.String("task", 200).Index()
+			table.Text("args").Null()
+			table.TinyInteger("status").Index()
+			table.Integer("current").Null()
+			table.Integer("total").Null()
+			table.Text("message").Null()
+			table.Text("response").Null()
+			table.Integer("attempts").Null()
+			table.String("worker", 200).Null()
+			table.BigInteger("lease_until").Null().Index() // the times are unix milliseconds
+			table.BigInteger("run_at").Null().Index()
+			table.Integer("priority").Null().Index()
+			table.String("unique_key", 200).Null().Index()
+			table.TinyInteger("dead").Null().Index()
+			table.BigInteger("created_at").Null()
+			table.BigInteger("started_at").Null()
+			table.BigInteger("ended_at").Null()
+			table.BigInteger("expired_at").Null().Index()
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	qb, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Database{name: name, query: qb, table: option.Table, option: option}, nil
+}
+
+// Push enqueue the job, the id is the auto-increment primary key if it is 0
+// the uniqueness key is checked before inserting, the equivalent jobs pushed at the same time by the different instances may be duplicated
+func (db *Database) Push(job *Job) (bool, error) {
+	args, err := jsoniter.MarshalToString(job.Args)
+	if err != nil {
+		return false, err
+	}
+
+	if job.Unique != "" {
+		id, err := db.pending(job, args)
+		if err != nil {
+			return false, err
+		}
+
+		if id != 0 {
+			job.ID = id
+			return false, nil
+		}
+	}
+
+	job.Task = db.name
+	job.Status = WAITING
+	job.CreatedAt = time.Now()
+	row := map[string]interface{}{
+		"task":       job.Task,
+		"args":       args,
+		"status":     WAITING,
+		"attempts":   0,
+		"run_at":     unixMilli(job.RunAt),
+		"priority":   job.Priority,
+		"dead":       0,
+		"current":    0,
+		"total":      0,
+		"created_at": job.CreatedAt.UnixMilli(),
+	}
+
+	if job.Unique != "" {
+		row["unique_key"] = job.Unique
+	}
+
+	if job.ID != 0 {
+		row["id"] = job.ID
+		return true, db.query.New().Table(db.table).Insert(row)
+	}
+
+	id, err := db.query.New().Table(db.table).InsertGetID(row)
+	if err != nil {
+		return false, err
+	}
+	job.ID = int(id)
+	return true, nil
+}
+
+// Claim claim the next job, the candidate is updated only if it was not claimed by the others
+func (db *Database) Claim(worker string, visibility time.Duration) (*Job, error) {
+	db.removeExpired()
+	for i := 0; i < claimRetries; i++ {
+		now := time.Now()
+		rows, err := db.query.New().Table(db.table).
+			Where("task", db.name).
+			Where(func(qb query.Query) {
+				qb.Where(func(qb query.Query) {
+					qb.Where("status", WAITING).Where("run_at", "<=", now.UnixMilli())
+				}).OrWhere(func(qb query.Query) {
+					qb.Where("status", RUNNING).Where("lease_until", "<", now.UnixMilli())
+				})
+			}).
+			OrderBy("priority", "desc").
+			OrderBy("id", "asc").
+			Limit(1).
+			Get()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rows) == 0 {
+			return nil, nil
+		}
+
+		job, err := jobOf(rows[0])
+		if err != nil {
+			return nil, err
+		}
+
+		qb := db.query.New().Table(db.table).Where("id", job.ID).Where("status", job.Status)
+		if job.Status == RUNNING {
+			qb.Where("lease_until", job.LeaseUntil.UnixMilli())
+		}
+
+		job.Status = RUNNING
+		job.Worker = worker
+		job.Attempts++
+		job.StartedAt = now
+		job.LeaseUntil = now.Add(visibility)
+		// the uniqueness key is released once the job is claimed
+		effect, err := qb.Update(map[string]interface{}{
+			"status":      RUNNING,
+			"unique_key":  nil,
+			"worker":      worker,
+			"attempts":    job.Attempts,
+			"started_at":  now.UnixMilli(),
+			"lease_until": job.LeaseUntil.UnixMilli(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if effect == 1 {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Save save the progress of the claimed job
+func (db *Database) Save(job *Job, visibility time.Duration) (bool, error) {
+	job.LeaseUntil = time.Now().Add(visibility)
+	effect, err := db.claimed(job).Update(map[string]interface{}{
+		"current":     job.Current,
+		"total":       job.Total,
+		"message":     job.Message,
+		"lease_until": job.LeaseUntil.UnixMilli(),
+	})
+	if err != nil {
+		return false, err
+	}
+	return effect == 1, nil
+}
+
+// Complete save the result of the claimed job
+func (db *Database) Complete(job *Job) (bool, error) {
+	response, err := jsoniter.MarshalToString(job.Response)
+	if err != nil {
+		return false, err
+	}
+
+	job.EndedAt = time.Now()
+	row := map[string]interface{}{
+		"status":   job.Status,
+		"current":  job.Current,
+		"total":    job.Total,
+		"message":  job.Message,
+		"response": response,
+		"ended_at": job.EndedAt.UnixMilli(),
+		"dead":     0,
+	}
+
+	if job.Dead {
+		row["dead"] = 1
+	} else if db.option.Retention > 0 {
+		row["expired_at"] = job.EndedAt.Add(db.option.Retention).UnixMilli()
+	}
+
+	effect, err := db.claimed(job).Update(row)
+	if err != nil {
+		return false, err
+	}
+	return effect == 1, nil
+}
+
+// Retry release the claimed job, it will be claimed again after the given time
+func (db *Database) Retry(job *Job, at time.Time) (bool, error) {
+	response, err := jsoniter.MarshalToString(job.Response)
+	if err != nil {
+		return false, err
+	}
+
+	effect, err := db.claimed(job).Update(map[string]interface{}{
+		"status":   WAITING,
+		"worker":   "",
+		"run_at":   at.UnixMilli(),
+		"current":  job.Current,
+		"total":    job.Total,
+		"message":  job.Message,
+		"response": response,
+	})
+	if err != nil {
+		return false, err
+	}
+	return effect == 1, nil
+}
+
+// Cancel cancel the waiting or running job
+func (db *Database) Cancel(id int) (bool, error) {
+	response, err := jsoniter.MarshalToString(ErrCanceled.Error())
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	row := map[string]interface{}{"status": FAILURE, "response": response, "ended_at": now.UnixMilli(), "unique_key": nil}
+	if db.option.Retention > 0 {
+		row["expired_at"] = now.Add(db.option.Retention).UnixMilli()
+	}
+
+	effect, err := db.query.New().Table(db.table).
+		Where("task", db.name).
+		Where("id", id).
+		WhereIn("status", []interface{}{WAITING, RUNNING}).
+		Update(row)
+	if err != nil {
+		return false, err
+	}
+	return effect == 1, nil
+}
+
+// Dead the jobs of the dead-letter list
+func (db *Database) Dead() ([]*Job, error) {
+	rows, err := db.query.New().Table(db.table).Where("task", db.name).Where("dead", 1).OrderBy("id", "asc").Get()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*Job{}
+	for _, row := range rows {
+		job, err := jobOf(row)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Requeue move the dead job back to the queue
+func (db *Database) Requeue(id int) (bool, error) {
+	effect, err := db.query.New().Table(db.table).
+		Where("task", db.name).
+		Where("id", id).
+		Where("dead", 1).
+		Update(map[string]interface{}{"status": WAITING, "dead": 0, "attempts": 0, "worker": "", "run_at": 0, "ended_at": nil})
+	if err != nil {
+		return false, err
+	}
+	return effect == 1, nil
+}
+
+// Purge remove the dead jobs
+func (db *Database) Purge(ids ...int) (int, error) {
+	qb := db.query.New().Table(db.table).Where("task", db.name).Where("dead", 1)
+	if len(ids) > 0 {
+		values := []interface{}{}
+		for _, id := range ids {
+			values = append(values, id)
+		}
+		qb.WhereIn("id", values)
+	}
+
+	effect, err := qb.Delete()
+	if err != nil {
+		return 0, err
+	}
+	return int(effect), nil
+}
+
+// Get get the job by id
+func (db *Database) Get(id int) (*Job, error) {
+	rows, err := db.query.New().Table(db.table).Where("task", db.name).Where("id", id).Limit(1).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	job, err := jobOf(rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	// the job was expired but not removed yet
+	if db.option.Retention > 0 && !job.Dead && !job.EndedAt.IsZero() && time.Since(job.EndedAt) > db.option.Retention {
+		return nil, nil
+	}
+	return job, nil
+}
+
+// List the jobs of the status, the newest first
+func (db *Database) List(status int, offset, limit int) ([]*Job, int, error) {
+	filter := func() query.Query {
+		qb := db.alive()
+		if status != 0 {
+			qb.Where("status", status)
+		}
+		return qb
+	}
+
+	total, err := filter().Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	qb := filter()
+	qb.OrderBy("id", "desc").Offset(offset)
+	if limit > 0 {
+		qb.Limit(limit)
+	}
+
+	rows, err := qb.Get()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	jobs := []*Job{}
+	for _, row := range rows {
+		job, err := jobOf(row)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, int(total), nil
+}
+
+// Count count the number of the jobs by the status
+func (db *Database) Count() (*Counts, error) {
+	counts := &Counts{}
+	fields := map[int]*int{WAITING: &counts.Waiting, RUNNING: &counts.Running, SUCCESS: &counts.Success, FAILURE: &counts.Failure}
+	for status, field := range fields {
+		n, err := db.alive().Where("status", status).Count()
+		if err != nil {
+			return nil, err
+		}
+		*field = int(n)
+	}
+
+	n, err := db.query.New().Table(db.table).Where("task", db.name).Where("dead", 1).Count()
+	if err != nil {
+		return nil, err
+	}
+	counts.Dead = int(n)
+	return counts, nil
+}
+
+// Close the queue, the connection is managed by the connector
+func (db *Database) Close() error {
+	return nil
+}
+
+// pending the id of the waiting job of the uniqueness key, the job is merged if it is required. 0 if there is no such job
+func (db *Database) pending(job *Job, args string) (int, error) {
+	rows, err := db.query.New().Table(db.table).
+		Select("id", "priority").
+		Where("task", db.name).
+		Where("unique_key", job.Unique).
+		Where("status", WAITING).
+		Limit(1).
+		Get()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	id := any.Of(rows[0]["id"]).CInt()
+	if job.Merge {
+		row := map[string]interface{}{"args": args}
+		if job.Priority > any.Of(rows[0]["priority"]).CInt() {
+			row["priority"] = job.Priority
+		}
+
+		_, err := db.query.New().Table(db.table).Where("id", id).Where("status", WAITING).Update(row)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// alive the query of the jobs of the task which are not expired
+func (db *Database) alive() query.Query {
+	return db.query.New().Table(db.table).
+		Where("task", db.name).
+		Where(func(qb query.Query) {
+			qb.WhereNull("expired_at").OrWhere("expired_at", ">", time.Now().UnixMilli())
+		})
+}
+
+// claimed the query of the job claimed by the worker
+func (db *Database) claimed(job *Job) query.Query {
+	return db.query.New().Table(db.table).
+		Where("id", job.ID).
+		Where("status", RUNNING).
+		Where("worker", job.Worker)
+}
+
+// removeExpired remove the expired jobs at most once a minute
+func (db *Database) removeExpired() {
+	if db.option.Retention <= 0 {
+		return
+	}
+
+	db.mutex.Lock()
+	if time.Since(db.sweep) < time.Minute {
+		db.mutex.Unlock()
+		return
+	}
+	db.sweep = time.Now()
+	db.mutex.Unlock()
+
+	_, err := db.query.New().Table(db.table).
+		Where("task", db.name).
+		Where("expired_at", "<", time.Now().UnixMilli()).
+		Delete()
+	if err != nil {
+		log.Error("[TASK] %s remove the expired jobs: %s", db.name, err.Error())
+	}
+}
+
+// jobOf the job of the database row
+func jobOf(row map[string]interface{}) (*Job, error) {
+	job := &Job{
+		ID:         any.Of(row["id"]).CInt(),
+		Task:       text(row["task"]),
+		Status:     any.Of(row["status"]).CInt(),
+		Current:    any.Of(row["current"]).CInt(),
+		Total:      any.Of(row["total"]).CInt(),
+		Message:    text(row["message"]),
+		Attempts:   any.Of(row["attempts"]).CInt(),
+		Worker:     text(row["worker"]),
+		Dead:       any.Of(row["dead"]).CInt() == 1,
+		Priority:   any.Of(row["priority"]).CInt(),
+		Unique:     text(row["unique_key"]),
+		LeaseUntil: timeOf(row["lease_until"]),
+		RunAt:      timeOf(row["run_at"]),
+		CreatedAt:  timeOf(row["created_at"]),
+		StartedAt:  timeOf(row["started_at"]),
+		EndedAt:    timeOf(row["ended_at"]),
+	}
+
+	if args := text(row["args"]); args != "" {
+		if err := jsoniter.UnmarshalFromString(args, &job.Args); err != nil {
+			return nil, err
+		}
+	}
+
+	if response := text(row["response"]); response != "" {
+		if err := jsoniter.UnmarshalFromString(response, &job.Response); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// timeOf the time of the unix milliseconds column
+func timeOf(value interface{}) time.Time {
+	switch v := value.(type) {
+	case int64:
+		return milli(fmt.Sprintf("%d", v))
+	case float64:
+		return milli(fmt.Sprintf("%.0f", v))
+	}
+	return milli(text(value))
+}
+
+// text the string value of the database column
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,290 @@
+package queue
+
+import (
+	"sort"
+	"time"
+)
+
+// NewMemory create a in-process queue
+func NewMemory(name string, option Option) *Memory {
+	return &Memory{name: name, jobs: map[int]*Job{}, waiting: []int{}, unique: map[string]int{}, option: option}
+}
+
+// Push enqueue the job
+func (mem *Memory) Push(job *Job) (bool, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	if id, has := mem.unique[job.Unique]; has && job.Unique != "" {
+		if pending, has := mem.jobs[id]; has && pending.Status == WAITING {
+			if job.Merge {
+				pending.merge(job)
+			}
+			job.ID = id
+			return false, nil
+		}
+	}
+
+	if job.ID == 0 {
+		mem.seq++
+		job.ID = mem.seq
+	} else if job.ID > mem.seq {
+		mem.seq = job.ID
+	}
+
+	job.Task = mem.name
+	job.Status = WAITING
+	job.CreatedAt = time.Now()
+
+	saved := *job
+	mem.jobs[job.ID] = &saved
+	mem.waiting = append(mem.waiting, job.ID)
+	if job.Unique != "" {
+		mem.unique[job.Unique] = job.ID
+	}
+	return true, nil
+}
+
+// Claim claim the due job of the highest priority, the expired running jobs are claimed first
+func (mem *Memory) Claim(worker string, visibility time.Duration) (*Job, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+	mem.sweep()
+
+	now := time.Now()
+	var job *Job
+	for _, j := range mem.jobs {
+		if j.Status == RUNNING && j.LeaseUntil.Before(now) && (job == nil || j.ID < job.ID) {
+			job = j
+		}
+	}
+
+	if job == nil {
+		waiting := []int{}
+		for _, id := range mem.waiting {
+			j, has := mem.jobs[id]
+			if !has || j.Status != WAITING {
+				continue
+			}
+
+			waiting = append(waiting, id)
+			if !j.RunAt.After(now) && (job == nil || j.Priority > job.Priority || (j.Priority == job.Priority && j.ID < job.ID)) {
+				job = j
+			}
+		}
+		mem.waiting = waiting
+	}
+
+	if job == nil {
+		return nil, nil
+	}
+
+	mem.release(job)
+	job.Status = RUNNING
+	job.Worker = worker
+	job.Attempts++
+	job.StartedAt = now
+	job.LeaseUntil = now.Add(visibility)
+	clone := *job
+	return &clone, nil
+}
+
+// Save save the progress of the claimed job
+func (mem *Memory) Save(job *Job, visibility time.Duration) (bool, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	saved, has := mem.jobs[job.ID]
+	if !has || saved.Status != RUNNING || saved.Worker != job.Worker {
+		return false, nil
+	}
+
+	saved.Current = job.Current
+	saved.Total = job.Total
+	saved.Message = job.Message
+	saved.LeaseUntil = time.Now().Add(visibility)
+	job.LeaseUntil = saved.LeaseUntil
+	return true, nil
+}
+
+// Complete save the result of the claimed job
+func (mem *Memory) Complete(job *Job) (bool, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	saved, has := mem.jobs[job.ID]
+	if !has || saved.Status != RUNNING || saved.Worker != job.Worker {
+		return false, nil
+	}
+
+	job.EndedAt = time.Now()
+	*saved = *job
+	return true, nil
+}
+
+// Retry release the claimed job, it will be claimed again after the given time
+func (mem *Memory) Retry(job *Job, at time.Time) (bool, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	saved, has := mem.jobs[job.ID]
+	if !has || saved.Status != RUNNING || saved.Worker != job.Worker {
+		return false, nil
+	}
+
+	saved.Status = WAITING
+	saved.Worker = ""
+	saved.RunAt = at
+	saved.Current = job.Current
+	saved.Total = job.Total
+	saved.Message = job.Message
+	saved.Response = job.Response
+	mem.waiting = append(mem.waiting, job.ID)
+	return true, nil
+}
+
+// Cancel cancel the waiting or running job
+func (mem *Memory) Cancel(id int) (bool, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	job, has := mem.jobs[id]
+	if !has || (job.Status != WAITING && job.Status != RUNNING) {
+		return false, nil
+	}
+
+	mem.release(job)
+	job.Status = FAILURE
+	job.Response = ErrCanceled.Error()
+	job.EndedAt = time.Now()
+	return true, nil
+}
+
+// Dead the jobs of the dead-letter list
+func (mem *Memory) Dead() ([]*Job, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	jobs := []*Job{}
+	for _, job := range mem.jobs {
+		if job.Dead {
+			clone := *job
+			jobs = append(jobs, &clone)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// Requeue move the dead job back to the queue
+func (mem *Memory) Requeue(id int) (bool, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	job, has := mem.jobs[id]
+	if !has || !job.Dead {
+		return false, nil
+	}
+
+	job.Dead = false
+	job.Status = WAITING
+	job.Worker = ""
+	job.Attempts = 0
+	job.RunAt = time.Time{}
+	job.EndedAt = time.Time{}
+	mem.waiting = append(mem.waiting, id)
+	return true, nil
+}
+
+// Purge remove the dead jobs
+func (mem *Memory) Purge(ids ...int) (int, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	if len(ids) == 0 {
+		for id, job := range mem.jobs {
+			if job.Dead {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	removed := 0
+	for _, id := range ids {
+		if job, has := mem.jobs[id]; has && job.Dead {
+			delete(mem.jobs, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Get get the job by id
+func (mem *Memory) Get(id int) (*Job, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+	mem.sweep()
+
+	job, has := mem.jobs[id]
+	if !has {
+		return nil, nil
+	}
+	clone := *job
+	return &clone, nil
+}
+
+// List the jobs of the status, the newest first
+func (mem *Memory) List(status int, offset, limit int) ([]*Job, int, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+	mem.sweep()
+
+	jobs := []*Job{}
+	for _, job := range mem.jobs {
+		if status == 0 || job.Status == status {
+			clone := *job
+			jobs = append(jobs, &clone)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	return page(jobs, offset, limit), len(jobs), nil
+}
+
+// Count count the number of the jobs by the status
+func (mem *Memory) Count() (*Counts, error) {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+	mem.sweep()
+
+	counts := &Counts{}
+	for _, job := range mem.jobs {
+		counts.add(job)
+	}
+	return counts, nil
+}
+
+// Close the queue
+func (mem *Memory) Close() error {
+	return nil
+}
+
+// release release the uniqueness key of the job which is no longer waiting (the lock must be held)
+func (mem *Memory) release(job *Job) {
+	if id, has := mem.unique[job.Unique]; has && id == job.ID {
+		delete(mem.unique, job.Unique)
+	}
+}
+
+// sweep remove the completed jobs after the retention, the dead jobs are kept (the lock must be held)
+func (mem *Memory) sweep() {
+	if mem.option.Retention <= 0 {
+		return
+	}
+
+	deadline := time.Now().Add(-mem.option.Retention)
+	for id, job := range mem.jobs {
+		if (job.Status == SUCCESS || job.Status == FAILURE) && !job.Dead && job.EndedAt.Before(deadline) {
+			delete(mem.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/yaoapp/gou/connector"
+	mongodb "github.com/yaoapp/gou/connector/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// registry decode the embedded documents and the arrays of the args and the response as the maps and the slices
+var registry = func() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	registry.RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(map[string]interface{}{}))
+	registry.RegisterTypeMapEntry(bsontype.Array, reflect.TypeOf([]interface{}{}))
+	return registry
+}()
+
+// NewMongo create a mongo queue, the jobs of all tasks are in the same collection
+func NewMongo(name string, c connector.Connector, option Option) (*Mongo, error) {
+	conn, ok := c.(*mongodb.Connector)
+	if !ok {
+		return nil, fmt.Errorf("the connector was not a *mongo.Connector")
+	}
+
+	coll := conn.Database.Collection(option.Table, options.Collection().SetRegistry(registry))
+	_, err := coll.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "task", Value: 1}, {Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "task", Value: 1}, {Key: "status", Value: 1}, {Key: "lease_until", Value: 1}}},
+		{Keys: bson.D{{Key: "task", Value: 1}, {Key: "status", Value: 1}, {Key: "priority", Value: -1}, {Key: "id", Value: 1}}},
+		{ // only one waiting job of the uniqueness key
+			Keys:    bson.D{{Key: "task", Value: 1}, {Key: "unique", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": WAITING, "unique": bson.M{"$type": "string"}}),
+		},
+		{Keys: bson.D{{Key: "expired_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}, // remove the completed jobs after the retention
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Mongo{
+		name:    name,
+		coll:    coll,
+		counter: conn.Database.Collection(fmt.Sprintf("%s_seq", option.Table)),
+		option:  option,
+	}, nil
+}
+
+// Push enqueue the job
+func (m *Mongo) Push(job *Job) (bool, error) {
+	if job.Unique != "" {
+		id, err := m.pending(job)
+		if err != nil {
+			return false, err
+		}
+
+		if id != 0 {
+			job.ID = id
+			return false, nil
+		}
+	}
+
+	if job.ID == 0 {
+		var seq struct {
+			Seq int `bson:"seq"`
+		}
+		err := m.counter.FindOneAndUpdate(context.TODO(),
+			bson.M{"_id": m.name},
+			bson.M{"$inc": bson.M{"seq": 1}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&seq)
+		if err != nil {
+			return false, err
+		}
+		job.ID = seq.Seq
+	}
+
+	job.Task = m.name
+	job.Status = WAITING
+	job.CreatedAt = time.Now()
+	_, err := m.coll.InsertOne(context.TODO(), job)
+	if err != nil && mongo.IsDuplicateKeyError(err) && job.Unique != "" {
+		// the equivalent job was pushed by the others at the same time
+		id, e := m.pending(job)
+		if e == nil && id != 0 {
+			job.ID = id
+			return false, nil
+		}
+	}
+
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Claim claim the next job, the expired running jobs are claimed as well
+func (m *Mongo) Claim(worker string, visibility time.Duration) (*Job, error) {
+	now := time.Now()
+	filter := bson.M{"task": m.name, "$or": bson.A{
+		bson.M{"status": WAITING, "run_at": bson.M{"$lte": now}},
+		bson.M{"status": RUNNING, "lease_until": bson.M{"$lt": now}},
+	}}
+
+	// the uniqueness key is released once the job is claimed
+	update := bson.M{
+		"$set":   bson.M{"status": RUNNING, "worker": worker, "started_at": now, "lease_until": now.Add(visibility)},
+		"$inc":   bson.M{"attempts": 1},
+		"$unset": bson.M{"unique": ""},
+	}
+
+	job := &Job{}
+	err := m.coll.FindOneAndUpdate(context.TODO(), filter, update,
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "id", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Save save the progress of the claimed job
+func (m *Mongo) Save(job *Job, visibility time.Duration) (bool, error) {
+	job.LeaseUntil = time.Now().Add(visibility)
+	res, err := m.coll.UpdateOne(context.TODO(), m.claimed(job), bson.M{"$set": bson.M{
+		"current":     job.Current,
+		"total":       job.Total,
+		"message":     job.Message,
+		"lease_until": job.LeaseUntil,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// Complete save the result of the claimed job
+func (m *Mongo) Complete(job *Job) (bool, error) {
+	job.EndedAt = time.Now()
+	set := bson.M{
+		"status":   job.Status,
+		"current":  job.Current,
+		"total":    job.Total,
+		"message":  job.Message,
+		"response": job.Response,
+		"ended_at": job.EndedAt,
+		"dead":     job.Dead,
+	}
+
+	if m.option.Retention > 0 && !job.Dead {
+		set["expired_at"] = job.EndedAt.Add(m.option.Retention)
+	}
+
+	res, err := m.coll.UpdateOne(context.TODO(), m.claimed(job), bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// Retry release the claimed job, it will be claimed again after the given time
+func (m *Mongo) Retry(job *Job, at time.Time) (bool, error) {
+	res, err := m.coll.UpdateOne(context.TODO(), m.claimed(job), bson.M{"$set": bson.M{
+		"status":   WAITING,
+		"worker":   "",
+		"run_at":   at,
+		"current":  job.Current,
+		"total":    job.Total,
+		"message":  job.Message,
+		"response": job.Response,
+	}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// Cancel cancel the waiting or running job
+func (m *Mongo) Cancel(id int) (bool, error) {
+	now := time.Now()
+	set := bson.M{"status": FAILURE, "response": ErrCanceled.Error(), "ended_at": now}
+	if m.option.Retention > 0 {
+		set["expired_at"] = now.Add(m.option.Retention)
+	}
+
+	filter := bson.M{"task": m.name, "id": id, "status": bson.M{"$in": bson.A{WAITING, RUNNING}}}
+	res, err := m.coll.UpdateOne(context.TODO(), filter, bson.M{"$set": set, "$unset": bson.M{"unique": ""}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// Dead the jobs of the dead-letter list
+func (m *Mongo) Dead() ([]*Job, error) {
+	cursor, err := m.coll.Find(context.TODO(), bson.M{"task": m.name, "dead": true}, options.Find().SetSort(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*Job{}
+	if err := cursor.All(context.TODO(), &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// Requeue move the dead job back to the queue
+func (m *Mongo) Requeue(id int) (bool, error) {
+	res, err := m.coll.UpdateOne(context.TODO(), bson.M{"task": m.name, "id": id, "dead": true}, bson.M{
+		"$set": bson.M{"status": WAITING, "dead": false, "attempts": 0, "worker": "", "run_at": time.Time{}, "ended_at": time.Time{}},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+// Purge remove the dead jobs
+func (m *Mongo) Purge(ids ...int) (int, error) {
+	filter := bson.M{"task": m.name, "dead": true}
+	if len(ids) > 0 {
+		filter["id"] = bson.M{"$in": ids}
+	}
+
+	res, err := m.coll.DeleteMany(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.DeletedCount), nil
+}
+
+// Get get the job by id
+func (m *Mongo) Get(id int) (*Job, error) {
+	filter := m.alive()
+	filter["id"] = id
+
+	job := &Job{}
+	err := m.coll.FindOne(context.TODO(), filter).Decode(job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// List the jobs of the status, the newest first
+func (m *Mongo) List(status int, offset, limit int) ([]*Job, int, error) {
+	filter := m.alive()
+	if status != 0 {
+		filter["status"] = status
+	}
+
+	total, err := m.coll.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: -1}}).SetSkip(int64(offset))
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := m.coll.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	jobs := []*Job{}
+	if err := cursor.All(context.TODO(), &jobs); err != nil {
+		return nil, 0, err
+	}
+	return jobs, int(total), nil
+}
+
+// Count count the number of the jobs by the status
+func (m *Mongo) Count() (*Counts, error) {
+	counts := &Counts{}
+	fields := map[int]*int{WAITING: &counts.Waiting, RUNNING: &counts.Running, SUCCESS: &counts.Success, FAILURE: &counts.Failure}
+	for status, field := range fields {
+		filter := m.alive()
+		filter["status"] = status
+		n, err := m.coll.CountDocuments(context.TODO(), filter)
+		if err != nil {
+			return nil, err
+		}
+		*field = int(n)
+	}
+
+	n, err := m.coll.CountDocuments(context.TODO(), bson.M{"task": m.name, "dead": true})
+	if err != nil {
+		return nil, err
+	}
+	counts.Dead = int(n)
+	return counts, nil
+}
+
+// Close the queue, the connection is managed by the connector
+func (m *Mongo) Close() error {
+	return nil
+}
+
+// pending the id of the waiting job of the uniqueness key, the job is merged if it is required. 0 if there is no such job
+func (m *Mongo) pending(job *Job) (int, error) {
+	filter := bson.M{"task": m.name, "unique": job.Unique, "status": WAITING}
+	var res *mongo.SingleResult
+	if job.Merge {
+		update := bson.M{"$set": bson.M{"args": job.Args}, "$max": bson.M{"priority": job.Priority}}
+		res = m.coll.FindOneAndUpdate(context.TODO(), filter, update)
+	} else {
+		res = m.coll.FindOne(context.TODO(), filter)
+	}
+
+	pending := &Job{}
+	err := res.Decode(pending)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return pending.ID, nil
+}
+
+// alive the filter of the jobs of the task which are not expired
+func (m *Mongo) alive() bson.M {
+	// the expired jobs are removed by the ttl index in background
+	return bson.M{"task": m.name, "$or": bson.A{
+		bson.M{"expired_at": nil},
+		bson.M{"expired_at": bson.M{"$gt": time.Now()}},
+	}}
+}
+
+// claimed the filter of the job claimed by the worker
+func (m *Mongo) claimed(job *Job) bson.M {
+	return bson.M{"task": m.name, "id": job.ID, "status": RUNNING, "worker": job.Worker}
+}
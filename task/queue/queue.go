@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"fmt"
+
+	"github.com/yaoapp/gou/connector"
+)
+
+// New create a job queue of the task via connector, the in-process queue is used if the connector is nil
+func New(name string, c connector.Connector, option Option) (Queue, error) {
+	if option.Table == "" {
+		option.Table = "task_jobs"
+	}
+
+	if c == nil {
+		return NewMemory(name, option), nil
+	}
+
+	if c.Is(connector.REDIS) {
+		return NewRedis(name, c, option)
+	} else if c.Is(connector.MONGO) {
+		return NewMongo(name, c, option)
+	} else if c.Is(connector.DATABASE) {
+		return NewDatabase(name, c, option)
+	}
+
+	return nil, fmt.Errorf("the connector %s does not support", c.ID())
+}
+
+// Map the job map, the format is the same as task.Get
+func (job *Job) Map(status map[int]string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       job.ID,
+		"status":   status[job.Status],
+		"current":  job.Current,
+		"total":    job.Total,
+		"message":  job.Message,
+		"response": job.Response,
+		"attempts": job.Attempts,
+		"priority": job.Priority,
+	}
+}
+
+// merge merge the duplicated job into the pending one, the args are replaced and the priority is raised
+func (job *Job) merge(duplicated *Job) {
+	job.Args = duplicated.Args
+	if duplicated.Priority > job.Priority {
+		job.Priority = duplicated.Priority
+	}
+}
+
+// add count the job
+func (counts *Counts) add(job *Job) {
+	switch job.Status {
+	case WAITING:
+		counts.Waiting++
+	case RUNNING:
+		counts.Running++
+	case SUCCESS:
+		counts.Success++
+	case FAILURE:
+		counts.Failure++
+	}
+
+	if job.Dead {
+		counts.Dead++
+	}
+}
+
+// page the jobs of the page
+func page(jobs []*Job, offset, limit int) []*Job {
+	if offset >= len(jobs) {
+		return []*Job{}
+	}
+
+	end := len(jobs)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return jobs[offset:end]
+}
@@ -0,0 +1,406 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/application"
+	"github.com/yaoapp/gou/connector"
+)
+
+func TestMemory(t *testing.T) {
+	q, err := New(unitName(), nil, Option{Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testQueue(t, q)
+	testRetry(t, q)
+	testPriority(t, q)
+
+	q, err = New(unitName(), nil, Option{Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testList(t, q)
+}
+
+func TestRedis(t *testing.T) {
+	c := getConnector(t, "redis")
+	q, err := New(unitName(), c, Option{Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testQueue(t, q)
+	testRetry(t, q)
+	testPriority(t, q)
+
+	q, err = New(unitName(), c, Option{Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testList(t, q)
+}
+
+func TestMongo(t *testing.T) {
+	c := getConnector(t, "mongo")
+	q, err := New(unitName(), c, Option{Table: "unit_task_jobs", Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.(*Mongo).coll.Drop(context.TODO())
+	testQueue(t, q)
+	testRetry(t, q)
+	testPriority(t, q)
+
+	q, err = New(unitName(), c, Option{Table: "unit_task_jobs", Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testList(t, q)
+}
+
+func TestDatabase(t *testing.T) {
+	c := getConnector(t, "sqlite")
+	q, err := New(unitName(), c, Option{Table: "unit_task_jobs", Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		sch, _ := c.Schema()
+		sch.DropTableIfExists("unit_task_jobs")
+	}()
+	testQueue(t, q)
+	testRetry(t, q)
+	testPriority(t, q)
+
+	q, err = New(unitName(), c, Option{Table: "unit_task_jobs", Retention: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testList(t, q)
+}
+
+func testQueue(t *testing.T, q Queue) {
+	first := &Job{Args: []interface{}{"foo", 1.0}}
+	second := &Job{Args: []interface{}{}}
+	push(t, q, first)
+	push(t, q, second)
+	assert.NotEqual(t, 0, first.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	job, err := q.Get(first.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, WAITING, job.Status)
+	assert.Equal(t, []interface{}{"foo", 1.0}, job.Args)
+
+	// claim in order
+	job, err = q.Claim("worker-1", 100*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, job.ID)
+	assert.Equal(t, RUNNING, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+
+	job.Current = 1
+	job.Total = 2
+	job.Message = "half"
+	ok, err := q.Save(job, 100*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// the job of the crashed worker is claimed again after the visibility timeout
+	other, err := q.Claim("worker-2", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, second.ID, other.ID)
+
+	none, err := q.Claim("worker-2", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, none)
+
+	time.Sleep(150 * time.Millisecond)
+	recovered, err := q.Claim("worker-2", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, first.ID, recovered.ID)
+	assert.Equal(t, 2, recovered.Attempts)
+	assert.Equal(t, "half", recovered.Message)
+
+	// the crashed worker can not save or complete the job
+	ok, err = q.Save(job, time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	job.Status = SUCCESS
+	ok, err = q.Complete(job)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	recovered.Status = SUCCESS
+	recovered.Response = map[string]interface{}{"foo": "bar"}
+	ok, err = q.Complete(recovered)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	job, err = q.Get(first.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, SUCCESS, job.Status)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, job.Response)
+	assert.Equal(t, "SUCCESS", job.Map(map[int]string{SUCCESS: "SUCCESS"})["status"])
+
+	other.Status = FAILURE
+	other.Response = "error"
+	ok, _ = q.Complete(other)
+	assert.True(t, ok)
+
+	// the completed jobs are removed after the retention
+	time.Sleep(300 * time.Millisecond)
+	job, err = q.Get(first.ID)
+	assert.Nil(t, err)
+	assert.Nil(t, job)
+}
+
+func testRetry(t *testing.T, q Queue) {
+	job := &Job{Args: []interface{}{}}
+	push(t, q, job)
+	claimed, err := q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+
+	// the job is claimed again after the given time
+	claimed.Response = "unavailable"
+	ok, err := q.Retry(claimed, time.Now().Add(100*time.Millisecond))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	none, err := q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, none)
+
+	time.Sleep(150 * time.Millisecond)
+	claimed, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+	assert.Equal(t, 2, claimed.Attempts)
+	assert.Equal(t, "unavailable", claimed.Response)
+
+	// the dead jobs are kept after the retention
+	claimed.Status = FAILURE
+	claimed.Dead = true
+	ok, err = q.Complete(claimed)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	time.Sleep(300 * time.Millisecond)
+	dead, err := q.Dead()
+	assert.Nil(t, err)
+	assert.Len(t, dead, 1)
+	assert.Equal(t, job.ID, dead[0].ID)
+
+	ok, err = q.Requeue(job.ID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = q.Requeue(job.ID)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	dead, err = q.Dead()
+	assert.Nil(t, err)
+	assert.Len(t, dead, 0)
+
+	claimed, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, job.ID, claimed.ID)
+	assert.Equal(t, 1, claimed.Attempts)
+
+	// cancel the running job, the worker can not save it anymore
+	ok, err = q.Cancel(job.ID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = q.Save(claimed, time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	canceled, err := q.Get(job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, FAILURE, canceled.Status)
+	assert.Equal(t, ErrCanceled.Error(), canceled.Response)
+
+	ok, err = q.Cancel(job.ID)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// cancel the waiting job
+	waiting := &Job{Args: []interface{}{}}
+	push(t, q, waiting)
+	ok, err = q.Cancel(waiting.ID)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	none, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, none)
+
+	// purge the dead jobs
+	other := &Job{Args: []interface{}{}}
+	push(t, q, other)
+	claimed, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	claimed.Status = FAILURE
+	claimed.Dead = true
+	ok, _ = q.Complete(claimed)
+	assert.True(t, ok)
+
+	removed, err := q.Purge()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+
+	job, err = q.Get(other.ID)
+	assert.Nil(t, err)
+	assert.Nil(t, job)
+}
+
+func testPriority(t *testing.T, q Queue) {
+	low := &Job{Args: []interface{}{"low"}}
+	high := &Job{Args: []interface{}{"high"}, Priority: 10}
+	delayed := &Job{Args: []interface{}{"delayed"}, Priority: 100, RunAt: time.Now().Add(150 * time.Millisecond)}
+	unique := &Job{Args: []interface{}{"a"}, Unique: "reindex"}
+	push(t, q, low)
+	push(t, q, high)
+	push(t, q, delayed)
+	push(t, q, unique)
+
+	// the duplicated job is dropped
+	dropped := &Job{Args: []interface{}{"b"}, Unique: "reindex"}
+	created, err := q.Push(dropped)
+	assert.Nil(t, err)
+	assert.False(t, created)
+	assert.Equal(t, unique.ID, dropped.ID)
+
+	// the duplicated job is merged into the pending one
+	merged := &Job{Args: []interface{}{"c"}, Unique: "reindex", Priority: 5, Merge: true}
+	created, err = q.Push(merged)
+	assert.Nil(t, err)
+	assert.False(t, created)
+	assert.Equal(t, unique.ID, merged.ID)
+
+	job, err := q.Get(unique.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"c"}, job.Args)
+	assert.Equal(t, 5, job.Priority)
+
+	// the jobs of the higher priority are claimed first, the delayed job is not due
+	for _, id := range []int{high.ID, unique.ID, low.ID} {
+		job, err := q.Claim("worker-1", time.Minute)
+		assert.Nil(t, err)
+		assert.Equal(t, id, job.ID)
+	}
+
+	none, err := q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, none)
+
+	// the uniqueness key is released once the job is claimed
+	again := &Job{Args: []interface{}{"d"}, Unique: "reindex"}
+	push(t, q, again)
+	assert.NotEqual(t, unique.ID, again.ID)
+
+	time.Sleep(200 * time.Millisecond)
+	job, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, delayed.ID, job.ID)
+
+	job, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, again.ID, job.ID)
+}
+
+func testList(t *testing.T, q Queue) {
+	jobs := []*Job{{Args: []interface{}{}}, {Args: []interface{}{}}, {Args: []interface{}{}}, {Args: []interface{}{}, RunAt: time.Now().Add(time.Minute)}}
+	for _, job := range jobs {
+		push(t, q, job)
+	}
+
+	job, err := q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	job.Status = SUCCESS
+	ok, err := q.Complete(job)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	job, err = q.Claim("worker-1", time.Minute)
+	assert.Nil(t, err)
+	job.Status = FAILURE
+	job.Dead = true
+	ok, err = q.Complete(job)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	counts, err := q.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, Counts{Waiting: 2, Running: 0, Success: 1, Failure: 1, Dead: 1}, *counts)
+
+	// the newest first
+	list, total, err := q.List(0, 0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, total)
+	if assert.Len(t, list, 4) {
+		assert.Equal(t, jobs[3].ID, list[0].ID)
+		assert.Equal(t, jobs[0].ID, list[3].ID)
+	}
+
+	list, total, err = q.List(WAITING, 1, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, jobs[2].ID, list[0].ID)
+	}
+
+	list, total, err = q.List(FAILURE, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, jobs[1].ID, list[0].ID)
+		assert.True(t, list[0].Dead)
+	}
+
+	// the completed jobs are not listed after the retention, the dead jobs are kept
+	time.Sleep(300 * time.Millisecond)
+	list, total, err = q.List(SUCCESS, 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, total)
+	assert.Len(t, list, 0)
+
+	counts, err = q.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, Counts{Waiting: 2, Running: 0, Success: 0, Failure: 1, Dead: 1}, *counts)
+}
+
+func push(t *testing.T, q Queue, job *Job) {
+	created, err := q.Push(job)
+	assert.Nil(t, err)
+	assert.True(t, created)
+}
+
+// unitName the unique task name, the jobs of the previous runs are ignored
+func unitName() string {
+	return fmt.Sprintf("unit.%d", time.Now().UnixNano())
+}
+
+func getConnector(t *testing.T, name string) connector.Connector {
+	root := os.Getenv("GOU_TEST_APPLICATION")
+	app, err := application.OpenFromDisk(root) // Load app
+	if err != nil {
+		t.Fatal(err)
+	}
+	application.Load(app)
+
+	c, err := connector.Load(filepath.Join("connectors", name+".conn.yao"), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
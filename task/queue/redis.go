@@ -0,0 +1,500 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/yaoapp/gou/connector"
+	rdb "github.com/yaoapp/gou/connector/redis"
+)
+
+// scoreLua the score of the waiting job, the jobs of the higher priority and the smaller id are claimed first
+// the score is formatted to keep the precision of the large numbers
+const scoreLua = `
+local function score(id, priority)
+	return string.format('%.0f', tonumber(id) - tonumber(priority or 0) * 1e12)
+end
+`
+
+// pushScript KEYS: job key prefix, waiting set, delayed set, unique key (empty if no uniqueness key). ARGV: id, priority, run at, now, merge, args, field value pairs...
+// returns the id of the pending job if it is duplicated
+var pushScript = redis.NewScript(scoreLua + `
+if KEYS[4] ~= '' then
+	local pending = redis.call('GET', KEYS[4])
+	if pending and redis.call('HGET', KEYS[1] .. pending, 'status') == '1' then
+		if ARGV[5] == '1' then
+			redis.call('HSET', KEYS[1] .. pending, 'args', ARGV[6])
+			if tonumber(ARGV[2]) > tonumber(redis.call('HGET', KEYS[1] .. pending, 'priority') or '0') then
+				redis.call('HSET', KEYS[1] .. pending, 'priority', ARGV[2])
+				redis.call('ZADD', KEYS[2], 'XX', score(pending, ARGV[2]), pending)
+			end
+		end
+		return pending
+	end
+	redis.call('SET', KEYS[4], ARGV[1])
+end
+redis.call('HSET', KEYS[1] .. ARGV[1], 'args', ARGV[6], 'priority', ARGV[2], 'run_at', ARGV[3], unpack(ARGV, 7))
+if tonumber(ARGV[3]) > tonumber(ARGV[4]) then
+	redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+else
+	redis.call('ZADD', KEYS[2], score(ARGV[1], ARGV[2]), ARGV[1])
+end
+return ARGV[1]
+`)
+
+// claimScript KEYS: waiting set, running set, job key prefix, delayed set, unique key prefix. ARGV: now, lease until, worker
+// the expired running jobs are claimed first, then the waiting jobs. the delayed jobs are moved to the waiting set when they are due
+var claimScript = redis.NewScript(scoreLua + `
+local id = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, 1)[1]
+for _, due in ipairs(redis.call('ZRANGEBYSCORE', KEYS[4], '-inf', ARGV[1])) do
+	redis.call('ZREM', KEYS[4], due)
+	redis.call('ZADD', KEYS[1], score(due, redis.call('HGET', KEYS[3] .. due, 'priority')), due)
+end
+while not id do
+	id = redis.call('ZRANGE', KEYS[1], 0, 0)[1]
+	if not id then return false end
+	redis.call('ZREM', KEYS[1], id)
+	if redis.call('HGET', KEYS[3] .. id, 'status') ~= '1' then id = nil end
+end
+local unique = redis.call('HGET', KEYS[3] .. id, 'unique')
+if unique and unique ~= '' and redis.call('GET', KEYS[5] .. unique) == id then redis.call('DEL', KEYS[5] .. unique) end
+redis.call('ZADD', KEYS[2], ARGV[2], id)
+redis.call('HSET', KEYS[3] .. id, 'status', 2, 'worker', ARGV[3], 'lease_until', ARGV[2], 'started_at', ARGV[1])
+redis.call('HINCRBY', KEYS[3] .. id, 'attempts', 1)
+return id
+`)
+
+// saveScript KEYS: job key, running set. ARGV: id, worker, lease until, field value pairs...
+// the fields are saved only if the job is still claimed by the worker
+var saveScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'worker') ~= ARGV[2] or redis.call('HGET', KEYS[1], 'status') ~= '2' then return 0 end
+if #ARGV > 3 then redis.call('HSET', KEYS[1], unpack(ARGV, 4)) end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+// completeScript KEYS: job key, running set, dead set, success or failure set. ARGV: id, worker, retention ms, dead, ended at, field value pairs...
+// the dead jobs are added to the dead-letter list and never expire, the others are added to the success or failure set
+var completeScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'worker') ~= ARGV[2] or redis.call('HGET', KEYS[1], 'status') ~= '2' then return 0 end
+redis.call('HSET', KEYS[1], 'dead', ARGV[4], unpack(ARGV, 6))
+redis.call('ZREM', KEYS[2], ARGV[1])
+if ARGV[4] == '1' then
+	redis.call('ZADD', KEYS[3], ARGV[1], ARGV[1])
+	return 1
+end
+redis.call('ZADD', KEYS[4], ARGV[5], ARGV[1])
+if tonumber(ARGV[3]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[3])
+	redis.call('ZREMRANGEBYSCORE', KEYS[4], '-inf', tonumber(ARGV[5]) - tonumber(ARGV[3]))
+end
+return 1
+`)
+
+// retryScript KEYS: job key, running set, delayed set. ARGV: id, worker, run at, field value pairs...
+var retryScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'worker') ~= ARGV[2] or redis.call('HGET', KEYS[1], 'status') ~= '2' then return 0 end
+redis.call('HSET', KEYS[1], 'status', 1, 'worker', '', 'run_at', ARGV[3], unpack(ARGV, 4))
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZADD', KEYS[3], ARGV[3], ARGV[1])
+return 1
+`)
+
+// cancelScript KEYS: job key, running set, delayed set, waiting set, unique key prefix, failure set. ARGV: id, response, ended at, retention ms
+var cancelScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[1], 'status')
+if status ~= '1' and status ~= '2' then return 0 end
+local unique = redis.call('HGET', KEYS[1], 'unique')
+if unique and unique ~= '' and redis.call('GET', KEYS[5] .. unique) == ARGV[1] then redis.call('DEL', KEYS[5] .. unique) end
+redis.call('HSET', KEYS[1], 'status', 4, 'response', ARGV[2], 'ended_at', ARGV[3])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('ZREM', KEYS[3], ARGV[1])
+redis.call('ZREM', KEYS[4], ARGV[1])
+redis.call('ZADD', KEYS[6], ARGV[3], ARGV[1])
+if tonumber(ARGV[4]) > 0 then redis.call('PEXPIRE', KEYS[1], ARGV[4]) end
+return 1
+`)
+
+// requeueScript KEYS: job key, dead set, waiting set. ARGV: id
+var requeueScript = redis.NewScript(scoreLua + `
+if redis.call('ZREM', KEYS[2], ARGV[1]) == 0 then return 0 end
+redis.call('HSET', KEYS[1], 'status', 1, 'dead', 0, 'attempts', 0, 'worker', '', 'run_at', 0, 'ended_at', 0)
+redis.call('ZADD', KEYS[3], score(ARGV[1], redis.call('HGET', KEYS[1], 'priority')), ARGV[1])
+return 1
+`)
+
+// NewRedis create a redis queue
+func NewRedis(name string, c connector.Connector, option Option) (*Redis, error) {
+	conn, ok := c.(*rdb.Connector)
+	if !ok {
+		return nil, fmt.Errorf("the connector was not a *redis.Connector")
+	}
+	return &Redis{name: name, rdb: conn.Rdb, prefix: fmt.Sprintf("%s:task:%s:", conn.Name, name), option: option}, nil
+}
+
+// Push enqueue the job
+func (r *Redis) Push(job *Job) (bool, error) {
+	ctx := context.Background()
+	if job.ID == 0 {
+		id, err := r.rdb.Incr(ctx, r.key("seq")).Result()
+		if err != nil {
+			return false, err
+		}
+		job.ID = int(id)
+	}
+
+	job.Task = r.name
+	job.Status = WAITING
+	job.CreatedAt = time.Now()
+	args, err := jsoniter.MarshalToString(job.Args)
+	if err != nil {
+		return false, err
+	}
+
+	unique := ""
+	if job.Unique != "" {
+		unique = r.key("unique:" + job.Unique)
+	}
+
+	merge := 0
+	if job.Merge {
+		merge = 1
+	}
+
+	keys := []string{r.key("job:"), r.key("waiting"), r.key("delayed"), unique}
+	id, err := pushScript.Run(ctx, r.rdb, keys, job.ID, job.Priority, unixMilli(job.RunAt), job.CreatedAt.UnixMilli(), merge, args,
+		"id", job.ID, "task", job.Task, "status", WAITING, "unique", job.Unique,
+		"attempts", 0, "dead", 0, "created_at", job.CreatedAt.UnixMilli(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+
+	if id != job.ID {
+		job.ID = id
+		return false, nil
+	}
+	return true, nil
+}
+
+// Claim claim the next job
+func (r *Redis) Claim(worker string, visibility time.Duration) (*Job, error) {
+	now := time.Now()
+	keys := []string{r.key("waiting"), r.key("running"), r.key("job:"), r.key("delayed"), r.key("unique:")}
+	id, err := claimScript.Run(context.Background(), r.rdb, keys, now.UnixMilli(), now.Add(visibility).UnixMilli(), worker).Text()
+	if err == redis.Nil {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	jobID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(jobID)
+}
+
+// Save save the progress of the claimed job
+func (r *Redis) Save(job *Job, visibility time.Duration) (bool, error) {
+	job.LeaseUntil = time.Now().Add(visibility)
+	args := []interface{}{job.ID, job.Worker, job.LeaseUntil.UnixMilli(),
+		"current", job.Current, "total", job.Total, "message", job.Message, "lease_until", job.LeaseUntil.UnixMilli(),
+	}
+	ok, err := saveScript.Run(context.Background(), r.rdb, []string{r.job(job.ID), r.key("running")}, args...).Int()
+	return ok == 1, err
+}
+
+// Complete save the result of the claimed job
+func (r *Redis) Complete(job *Job) (bool, error) {
+	job.EndedAt = time.Now()
+	response, err := jsoniter.MarshalToString(job.Response)
+	if err != nil {
+		return false, err
+	}
+
+	dead := 0
+	if job.Dead {
+		dead = 1
+	}
+
+	done := r.key("success")
+	if job.Status != SUCCESS {
+		done = r.key("failure")
+	}
+
+	args := []interface{}{job.ID, job.Worker, r.option.Retention.Milliseconds(), dead, job.EndedAt.UnixMilli(),
+		"status", job.Status, "current", job.Current, "total", job.Total, "message", job.Message,
+		"response", response, "ended_at", job.EndedAt.UnixMilli(),
+	}
+	keys := []string{r.job(job.ID), r.key("running"), r.key("dead"), done}
+	ok, err := completeScript.Run(context.Background(), r.rdb, keys, args...).Int()
+	return ok == 1, err
+}
+
+// Retry release the claimed job, it will be claimed again after the given time
+func (r *Redis) Retry(job *Job, at time.Time) (bool, error) {
+	response, err := jsoniter.MarshalToString(job.Response)
+	if err != nil {
+		return false, err
+	}
+
+	args := []interface{}{job.ID, job.Worker, at.UnixMilli(),
+		"current", job.Current, "total", job.Total, "message", job.Message, "response", response,
+	}
+	ok, err := retryScript.Run(context.Background(), r.rdb, []string{r.job(job.ID), r.key("running"), r.key("delayed")}, args...).Int()
+	return ok == 1, err
+}
+
+// Cancel cancel the waiting or running job
+func (r *Redis) Cancel(id int) (bool, error) {
+	response, err := jsoniter.MarshalToString(ErrCanceled.Error())
+	if err != nil {
+		return false, err
+	}
+
+	keys := []string{r.job(id), r.key("running"), r.key("delayed"), r.key("waiting"), r.key("unique:"), r.key("failure")}
+	ok, err := cancelScript.Run(context.Background(), r.rdb, keys, id, response, time.Now().UnixMilli(), r.option.Retention.Milliseconds()).Int()
+	return ok == 1, err
+}
+
+// Dead the jobs of the dead-letter list
+func (r *Redis) Dead() ([]*Job, error) {
+	ids, err := r.rdb.ZRange(context.Background(), r.key("dead"), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []*Job{}
+	for _, id := range ids {
+		job, err := r.Get(atoi(id))
+		if err != nil {
+			return nil, err
+		}
+
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// Requeue move the dead job back to the queue
+func (r *Redis) Requeue(id int) (bool, error) {
+	keys := []string{r.job(id), r.key("dead"), r.key("waiting")}
+	ok, err := requeueScript.Run(context.Background(), r.rdb, keys, id).Int()
+	return ok == 1, err
+}
+
+// Purge remove the dead jobs
+func (r *Redis) Purge(ids ...int) (int, error) {
+	ctx := context.Background()
+	if len(ids) == 0 {
+		members, err := r.rdb.ZRange(ctx, r.key("dead"), 0, -1).Result()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, id := range members {
+			ids = append(ids, atoi(id))
+		}
+	}
+
+	removed := 0
+	for _, id := range ids {
+		n, err := r.rdb.ZRem(ctx, r.key("dead"), id).Result()
+		if err != nil {
+			return removed, err
+		}
+
+		if n == 1 {
+			removed++
+			if err := r.rdb.Del(ctx, r.job(id)).Err(); err != nil {
+				return removed, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// Get get the job by id
+func (r *Redis) Get(id int) (*Job, error) {
+	values, err := r.rdb.HGetAll(context.Background(), r.job(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	job := &Job{
+		ID:         id,
+		Task:       values["task"],
+		Message:    values["message"],
+		Worker:     values["worker"],
+		Status:     atoi(values["status"]),
+		Current:    atoi(values["current"]),
+		Total:      atoi(values["total"]),
+		Attempts:   atoi(values["attempts"]),
+		Dead:       values["dead"] == "1",
+		Priority:   atoi(values["priority"]),
+		Unique:     values["unique"],
+		LeaseUntil: milli(values["lease_until"]),
+		RunAt:      milli(values["run_at"]),
+		CreatedAt:  milli(values["created_at"]),
+		StartedAt:  milli(values["started_at"]),
+		EndedAt:    milli(values["ended_at"]),
+	}
+
+	if args := values["args"]; args != "" {
+		if err := jsoniter.UnmarshalFromString(args, &job.Args); err != nil {
+			return nil, err
+		}
+	}
+
+	if response := values["response"]; response != "" {
+		if err := jsoniter.UnmarshalFromString(response, &job.Response); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// List the jobs of the status, the newest first
+func (r *Redis) List(status int, offset, limit int) ([]*Job, int, error) {
+	ctx := context.Background()
+	r.trim(ctx)
+
+	ids := []int{}
+	seen := map[int]bool{}
+	for _, set := range r.sets(status) {
+		members, err := r.rdb.ZRange(ctx, r.key(set), 0, -1).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, member := range members {
+			id := atoi(member)
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	total := len(ids)
+	if offset >= total {
+		return []*Job{}, total, nil
+	}
+
+	end := total
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+
+	jobs := []*Job{}
+	for _, id := range ids[offset:end] {
+		job, err := r.Get(id)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if job != nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, total, nil
+}
+
+// Count count the number of the jobs by the status
+func (r *Redis) Count() (*Counts, error) {
+	ctx := context.Background()
+	r.trim(ctx)
+
+	cards := map[string]*redis.IntCmd{}
+	_, err := r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, set := range []string{"waiting", "delayed", "running", "success", "failure", "dead"} {
+			cards[set] = pipe.ZCard(ctx, r.key(set))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Counts{
+		Waiting: int(cards["waiting"].Val() + cards["delayed"].Val()),
+		Running: int(cards["running"].Val()),
+		Success: int(cards["success"].Val()),
+		Failure: int(cards["failure"].Val() + cards["dead"].Val()),
+		Dead:    int(cards["dead"].Val()),
+	}, nil
+}
+
+// sets the sorted sets of the jobs of the status, all of them if the status is 0
+func (r *Redis) sets(status int) []string {
+	switch status {
+	case WAITING:
+		return []string{"waiting", "delayed"}
+	case RUNNING:
+		return []string{"running"}
+	case SUCCESS:
+		return []string{"success"}
+	case FAILURE:
+		return []string{"failure", "dead"}
+	}
+	return []string{"waiting", "delayed", "running", "success", "failure", "dead"}
+}
+
+// trim remove the expired jobs from the success and failure sets
+func (r *Redis) trim(ctx context.Context) {
+	if r.option.Retention <= 0 {
+		return
+	}
+
+	expired := fmt.Sprintf("%d", time.Now().Add(-r.option.Retention).UnixMilli())
+	r.rdb.ZRemRangeByScore(ctx, r.key("success"), "-inf", expired)
+	r.rdb.ZRemRangeByScore(ctx, r.key("failure"), "-inf", expired)
+}
+
+// Close the queue, the connection is managed by the connector
+func (r *Redis) Close() error {
+	return nil
+}
+
+func (r *Redis) key(name string) string {
+	return r.prefix + name
+}
+
+func (r *Redis) job(id int) string {
+	return fmt.Sprintf("%sjob:%d", r.prefix, id)
+}
+
+func atoi(value string) int {
+	v, _ := strconv.Atoi(value)
+	return v
+}
+
+// unixMilli the unix milliseconds of the time, 0 if the time is zero
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// milli the time of the unix milliseconds, zero time if the value is empty
+func milli(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
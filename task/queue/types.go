@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yaoapp/xun/dbal/query"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// WAITING the job is waiting
+	WAITING = iota + 1
+
+	// RUNNING the job is running
+	RUNNING
+
+	// SUCCESS the job is success
+	SUCCESS
+
+	// FAILURE the job is failure
+	FAILURE
+)
+
+// ErrCanceled the response of the canceled jobs
+var ErrCanceled = errors.New("the job was canceled")
+
+// Queue the durable job queue, the jobs are claimed by the workers of multiple instances
+type Queue interface {
+	Push(job *Job) (bool, error)                                 // enqueue the job, the id is generated if it is 0. false if an equivalent job is pending, the id is set to the pending one
+	Claim(worker string, visibility time.Duration) (*Job, error) // claim the due job of the highest priority, the job is visible to the others again after the visibility timeout. nil if there is no job
+	Save(job *Job, visibility time.Duration) (bool, error)       // save the progress of the claimed job and extend the visibility timeout, false if the job was claimed by the others
+	Complete(job *Job) (bool, error)                             // save the result of the claimed job, the job is removed after the retention. the dead jobs are kept in the dead-letter list
+	Retry(job *Job, at time.Time) (bool, error)                  // release the claimed job, it will be claimed again after the given time
+	Cancel(id int) (bool, error)                                 // cancel the waiting or running job, false if the job was completed
+	Dead() ([]*Job, error)                                       // the jobs of the dead-letter list
+	Requeue(id int) (bool, error)                                // move the dead job back to the queue, the attempts are reset
+	Purge(ids ...int) (int, error)                               // remove the dead jobs, all of them if the ids are empty
+	Get(id int) (*Job, error)                                    // get the job by id, nil if the job does not exist or was removed
+	List(status int, offset, limit int) ([]*Job, int, error)     // the jobs of the status (all if it is 0) and the total, the newest first
+	Count() (*Counts, error)                                     // the number of the jobs by the status
+	Close() error
+}
+
+// Job the persisted job
+type Job struct {
+	ID         int           `json:"id" bson:"id"`
+	Task       string        `json:"task" bson:"task"`
+	Args       []interface{} `json:"args" bson:"args"`
+	Status     int           `json:"status" bson:"status"`
+	Current    int           `json:"current" bson:"current"`
+	Total      int           `json:"total" bson:"total"`
+	Message    string        `json:"message" bson:"message"`
+	Response   interface{}   `json:"response" bson:"response"`
+	Attempts   int           `json:"attempts" bson:"attempts"`                 // the number of the job was claimed
+	Worker     string        `json:"worker" bson:"worker"`                     // the worker claimed the job
+	LeaseUntil time.Time     `json:"lease_until" bson:"lease_until"`           // the visibility timeout of the running job
+	RunAt      time.Time     `json:"run_at" bson:"run_at"`                     // the waiting job is not claimed until the time
+	Priority   int           `json:"priority" bson:"priority"`                 // the jobs of the higher priority are claimed first
+	Unique     string        `json:"unique,omitempty" bson:"unique,omitempty"` // the uniqueness key, only one waiting job of the key is in the queue
+	Merge      bool          `json:"-" bson:"-"`                               // merge the duplicated job into the pending one when it is pushed, otherwise it is dropped
+	Dead       bool          `json:"dead" bson:"dead"`                         // the job failed after all attempts
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	StartedAt  time.Time     `json:"started_at" bson:"started_at"`
+	EndedAt    time.Time     `json:"ended_at" bson:"ended_at"`
+}
+
+// Counts the number of the jobs by the status, the failure jobs include the dead ones
+type Counts struct {
+	Waiting int `json:"waiting"`
+	Running int `json:"running"`
+	Success int `json:"success"`
+	Failure int `json:"failure"`
+	Dead    int `json:"dead"`
+}
+
+// Option the queue option
+type Option struct {
+	Table     string        // the table name of the database queue, or the collection name of the mongo queue. default is task_jobs
+	Retention time.Duration // the completed jobs are removed after the retention, 0 means forever. the dead jobs are kept until purged
+}
+
+// Memory the in-process queue, the jobs are lost on restart
+type Memory struct {
+	unique  map[string]int // the waiting job of the uniqueness key
+	name    string
+	seq     int
+	jobs    map[int]*Job
+	waiting []int
+	option  Option
+	mutex   sync.Mutex
+}
+
+// Redis the redis queue, the job is a hash, the waiting jobs are in a sorted set scored by the priority and the id, the claimed jobs are in a sorted set scored by the visibility timeout.
+// the delayed jobs are in a sorted set scored by the run time, the dead jobs are in a sorted set scored by the id. the uniqueness key refers to the waiting job.
+// the completed jobs are in the success and failure sorted sets scored by the end time
+type Redis struct {
+	name   string
+	rdb    *redis.Client
+	prefix string
+	option Option
+}
+
+// Mongo the mongo queue
+type Mongo struct {
+	name    string
+	coll    *mongo.Collection
+	counter *mongo.Collection
+	option  Option
+}
+
+// Database the database queue via the xun query builder
+type Database struct {
+	name   string
+	query  query.Query
+	table  string
+	option Option
+	sweep  time.Time // the last time the expired jobs were removed
+	mutex  sync.Mutex
+}
@@ -0,0 +1,255 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/yaoapp/gou/task/queue"
+	"github.com/yaoapp/kun/log"
+)
+
+// Permanent wrap the error, the job is failed without retry
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// Error the error message
+func (err *PermanentError) Error() string {
+	return err.Err.Error()
+}
+
+// Unwrap the wrapped error
+func (err *PermanentError) Unwrap() error {
+	return err.Err
+}
+
+// Cancel cancel the waiting or running job, false if the job does not exist or was completed
+func (t *Task) Cancel(id int) (bool, error) {
+	canceled := false
+	if t.queue != nil {
+		ok, err := t.queue.Cancel(id)
+		if err != nil {
+			return false, err
+		}
+		canceled = ok
+	}
+
+	job, has := t.job(id)
+	if !has {
+		return canceled, nil
+	}
+
+	// the in-memory job is waiting (or waiting for the retry)
+	waiting := t.unqueue(job)
+
+	job.lock.Lock()
+	job.canceled = true
+	if waiting {
+		job.lock.Unlock()
+		t.failure(job, queue.ErrCanceled)
+		return true, nil
+	}
+
+	if job.cancel != nil {
+		job.cancel()
+	}
+	job.lock.Unlock()
+	return true, nil
+}
+
+// Dead the jobs of the dead-letter list
+func (t *Task) Dead() ([]map[string]interface{}, error) {
+	res := []map[string]interface{}{}
+	if t.queue != nil {
+		records, err := t.queue.Dead()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			res = append(res, record.Map(status))
+		}
+		return res, nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, job := range t.dead {
+		res = append(res, job.Map())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i]["id"].(int) < res[j]["id"].(int) })
+	return res, nil
+}
+
+// Requeue move the dead jobs back to the queue, all of them if the ids are empty. returns the number of the requeued jobs
+func (t *Task) Requeue(ids ...int) (int, error) {
+	if t.queue != nil {
+		return t.requeueDurable(ids...)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(ids) == 0 {
+		for id := range t.dead {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+	}
+
+	requeued := 0
+	for _, id := range ids {
+		job, has := t.dead[id]
+		if !has {
+			continue
+		}
+
+		if t.size() >= t.pool.max {
+			return requeued, fmt.Errorf("[TASK] %s reached the limit of jobs queue", t.name)
+		}
+
+		delete(t.dead, id)
+		job.attempts = 0
+		job.dead = false
+		job.canceled = false
+		job.runAt = time.Time{}
+		t.jobs[id] = job
+		t.enqueue(job)
+		requeued++
+	}
+	return requeued, nil
+}
+
+// Purge remove the dead jobs, all of them if the ids are empty. returns the number of the removed jobs
+func (t *Task) Purge(ids ...int) (int, error) {
+	if t.queue != nil {
+		return t.queue.Purge(ids...)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(ids) == 0 {
+		removed := len(t.dead)
+		t.dead = map[int]*Job{}
+		return removed, nil
+	}
+
+	removed := 0
+	for _, id := range ids {
+		if _, has := t.dead[id]; has {
+			delete(t.dead, id)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (t *Task) requeueDurable(ids ...int) (int, error) {
+	if len(ids) == 0 {
+		records, err := t.queue.Dead()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, record := range records {
+			ids = append(ids, record.ID)
+		}
+	}
+
+	requeued := 0
+	for _, id := range ids {
+		ok, err := t.queue.Requeue(id)
+		if err != nil {
+			return requeued, err
+		}
+
+		if ok {
+			requeued++
+		}
+	}
+
+	t.wakeup()
+	return requeued, nil
+}
+
+// retryable check if the failed job should be retried
+func (t *Task) retryable(job *Job, err error) bool {
+	if job.attempts >= t.Option.Attempts || t.ctx.Err() != nil || t.canceled(job) {
+		return false
+	}
+
+	var permanent *PermanentError
+	if errors.As(err, &permanent) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if t.handlers.Retry != nil {
+		return t.handlers.Retry(job.id, err)
+	}
+	return true
+}
+
+// retry run the failed job again after the backoff
+func (t *Task) retry(job *Job, err error) {
+	delay := t.backoff(job.attempts)
+	log.Warn("[TASK] %s Job:%v attempt %d failed, retry after %v (%s)", t.name, job.id, job.attempts, delay, err.Error())
+
+	job.lock.Lock()
+	job.status = WAITING
+	job.response = err.Error()
+	job.lock.Unlock()
+
+	t.mutex.Lock()
+	t.counters.retried++
+	t.mutex.Unlock()
+
+	// the job is claimed again by any instance
+	if job.record != nil {
+		t.release(job, time.Now().Add(delay))
+		t.remove(job.id)
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	job.runAt = time.Now().Add(delay)
+	t.enqueue(job)
+}
+
+// backoff the delay before the next attempt
+func (t *Task) backoff(attempts int) time.Duration {
+	delay := time.Duration(t.Option.AttemptAfter) * time.Millisecond
+	limit := time.Duration(t.Option.MaxAttemptAfter) * time.Millisecond
+	if t.Option.Backoff == "exponential" || t.Option.Backoff == "jitter" {
+		for i := 1; i < attempts && i < 32 && (limit == 0 || delay < limit); i++ {
+			delay = delay * 2
+		}
+	}
+
+	if limit > 0 && delay > limit {
+		delay = limit
+	}
+
+	// the half of the delay is random
+	if t.Option.Backoff == "jitter" && delay > 1 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+	}
+	return delay
+}
+
+// cause the error of the done job context
+func (t *Task) cause(job *Job, err error) error {
+	if t.canceled(job) {
+		return queue.ErrCanceled
+	}
+	return err
+}
+
+func (t *Task) canceled(job *Job) bool {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+	return job.canceled
+}
@@ -0,0 +1,182 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// finishedSize the maximum number of the recently completed in-memory jobs
+const finishedSize = 100
+
+// List the jobs of the status with pagination, all the jobs if the status is 0. the newest first
+// the in-memory task lists the waiting, running, dead and the recently completed jobs
+func (t *Task) List(jobStatus int, page int, pagesize int) (map[string]interface{}, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	if pagesize < 1 {
+		pagesize = 20
+	}
+
+	data := []map[string]interface{}{}
+	total := 0
+	offset := (page - 1) * pagesize
+	if t.queue != nil {
+		records, n, err := t.queue.List(jobStatus, offset, pagesize)
+		if err != nil {
+			return nil, fmt.Errorf("[TASK] %s list the jobs: %s", t.name, err.Error())
+		}
+
+		for _, record := range records {
+			data = append(data, record.Map(status))
+		}
+		total = n
+
+	} else {
+		jobs := t.memoryJobs(jobStatus)
+		total = len(jobs)
+		for i := offset; i < total && i < offset+pagesize; i++ {
+			data = append(data, jobs[i].Map())
+		}
+	}
+
+	pagecnt := (total + pagesize - 1) / pagesize
+	next, prev := -1, -1
+	if page < pagecnt {
+		next = page + 1
+	}
+
+	if page > 1 {
+		prev = page - 1
+	}
+
+	return map[string]interface{}{
+		"data":     data,
+		"pagesize": pagesize,
+		"pagecnt":  pagecnt,
+		"page":     page,
+		"next":     next,
+		"prev":     prev,
+		"total":    total,
+	}, nil
+}
+
+// Stats the queue depth, the worker utilization, the throughput and the failure rate of the task
+// the throughput and the failure rate are of the jobs completed by the instance in the last minute
+func (t *Task) Stats() (map[string]interface{}, error) {
+	busy := int(atomic.LoadInt32(&t.busy))
+	res := map[string]interface{}{
+		"name":        t.name,
+		"workers":     t.pool.size,
+		"busy":        busy,
+		"utilization": float64(busy) / float64(t.pool.size),
+	}
+
+	t.mutex.Lock()
+	success, failure := t.lastMinute()
+	res["success"] = t.counters.success
+	res["failure"] = t.counters.failure
+	res["retried"] = t.counters.retried
+	res["canceled"] = t.counters.canceled
+	res["throughput"] = success + failure
+	res["failure_rate"] = 0.0
+	if success+failure > 0 {
+		res["failure_rate"] = float64(failure) / float64(success+failure)
+	}
+
+	if t.queue == nil {
+		running := 0
+		for _, job := range t.jobs {
+			if job.status == RUNNING {
+				running++
+			}
+		}
+		res["waiting"] = t.size()
+		res["running"] = running
+		res["dead"] = len(t.dead)
+		t.mutex.Unlock()
+		return res, nil
+	}
+	t.mutex.Unlock()
+
+	// the durable queue is shared by all the instances
+	counts, err := t.queue.Count()
+	if err != nil {
+		return nil, fmt.Errorf("[TASK] %s count the jobs: %s", t.name, err.Error())
+	}
+	res["waiting"] = counts.Waiting
+	res["running"] = counts.Running
+	res["dead"] = counts.Dead
+	res["queue"] = counts
+	return res, nil
+}
+
+// memoryJobs the in-memory jobs of the status, the newest first
+func (t *Task) memoryJobs(status int) []*Job {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	jobs := []*Job{}
+	for _, group := range [][]*Job{values(t.jobs), values(t.dead), t.finished} {
+		for _, job := range group {
+			if status == 0 || job.status == status {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].id > jobs[j].id })
+	return jobs
+}
+
+// count count the completed job (the lock must be held)
+func (t *Task) count(job *Job) {
+	now := time.Now().Unix()
+	sec := &t.counters.seconds[now%int64(len(t.counters.seconds))]
+	if sec.at != now {
+		*sec = second{at: now}
+	}
+
+	if job.status == SUCCESS {
+		t.counters.success++
+		sec.success++
+		return
+	}
+
+	t.counters.failure++
+	sec.failure++
+	if t.canceled(job) {
+		t.counters.canceled++
+	}
+}
+
+// lastMinute the number of the jobs succeeded and failed in the last minute (the lock must be held)
+func (t *Task) lastMinute() (int, int) {
+	success, failure := 0, 0
+	since := time.Now().Unix() - int64(len(t.counters.seconds))
+	for _, sec := range t.counters.seconds {
+		if sec.at > since {
+			success += sec.success
+			failure += sec.failure
+		}
+	}
+	return success, failure
+}
+
+// finish keep the recently completed in-memory job (the lock must be held)
+func (t *Task) finish(job *Job) {
+	t.finished = append(t.finished, job)
+	if len(t.finished) > finishedSize {
+		t.finished = t.finished[len(t.finished)-finishedSize:]
+	}
+}
+
+func values(jobs map[int]*Job) []*Job {
+	res := []*Job{}
+	for _, job := range jobs {
+		res = append(res, job)
+	}
+	return res
+}
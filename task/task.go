@@ -2,12 +2,15 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/yaoapp/gou/task/queue"
 	"github.com/yaoapp/kun/log"
 )
 
@@ -36,10 +39,17 @@ func New(handlers *Handlers, option Option) *Task {
 	pool := &Pool{
 		size:      option.WorkerNums,
 		max:       option.JobQueueLength,
-		jobque:    make(chan *Job, option.JobQueueLength),
 		workerque: make(chan *Worker, option.WorkerNums),
 	}
 
+	if option.Queue != nil && option.Visibility == 0 {
+		option.Visibility = 60
+	}
+
+	if option.Poll == 0 {
+		option.Poll = 500
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Task{
 		name:     option.Name,
@@ -50,6 +60,13 @@ func New(handlers *Handlers, option Option) *Task {
 		cancel:   cancel,
 		pool:     pool,
 		timeout:  option.Timeout,
+		queue:    option.Queue,
+		worker:   workerID(),
+		notify:   make(chan struct{}, 1),
+		dead:     map[int]*Job{},
+		waiting:  newHeap(byPriority),
+		delayed:  newHeap(byRunAt),
+		unique:   map[string]*Job{},
 		Option:   option,
 	}
 }
@@ -64,17 +81,7 @@ func (t *Task) Start() {
 		t.startWorker(w)
 	}
 
-	for {
-		select {
-		case job := <-t.pool.jobque:
-			worker := <-t.pool.workerque
-			worker.job <- job
-		case <-interrupt:
-			return
-		case <-t.ctx.Done():
-			return
-		}
-	}
+	t.dispatch(interrupt)
 }
 
 // Stop the task
@@ -84,27 +91,47 @@ func (t *Task) Stop() {
 
 // Add a job to the job queue
 func (t *Task) Add(args ...interface{}) (int, error) {
+	return t.AddWith(AddOption{}, args...)
+}
+
+// AddWith add a job with the priority, the run time or the uniqueness key to the job queue
+// returns the id of the waiting job if the job is duplicated
+func (t *Task) AddWith(option AddOption, args ...interface{}) (int, error) {
+	if t.queue != nil {
+		return t.push(option, args...)
+	}
+
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
-	id := t.nextID()
-	if len(t.pool.jobque) > t.pool.max {
+	if job, has := t.unique[option.Unique]; has && option.Unique != "" {
+		if option.Merge {
+			t.merge(job, option, args)
+		}
+		return job.id, nil
+	}
+
+	if t.size() >= t.pool.max {
 		return 0, fmt.Errorf("[TASK] %s reached the limit of jobs queue", t.name)
 	}
 
-	timeout := time.Duration(t.timeout) * time.Second
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	id := t.nextID()
 	job := &Job{
-		id:      id,
-		args:    args,
-		timeout: timeout,
-		ctx:     ctx,
-		cancel:  cancel,
+		id:       id,
+		args:     args,
+		timeout:  time.Duration(t.timeout) * time.Second,
+		priority: option.Priority,
+		runAt:    option.runAt(),
+		unique:   option.Unique,
 	}
 
 	t.jobs[id] = job
+	if job.unique != "" {
+		t.unique[job.unique] = job
+	}
+
 	t.add(job)
-	t.pool.jobque <- job
+	t.enqueue(job)
 	return id, nil
 }
 
@@ -116,7 +143,7 @@ func Progress(name string, id, curr, total int, message string) error {
 		return fmt.Errorf("task %s does not exist", name)
 	}
 
-	job, has := t.jobs[id]
+	job, has := t.job(id)
 	if !has {
 		return fmt.Errorf("job %d does not exist or was completed", id)
 	}
@@ -124,16 +151,40 @@ func Progress(name string, id, curr, total int, message string) error {
 	job.curr = curr
 	job.total = total
 	job.message = message
+	t.save(job)
 	t.progress(job, curr, total, message)
 	return nil
 }
 
 // Get get job by job id
 func (t *Task) Get(id int) (map[string]interface{}, error) {
-	job, has := t.jobs[id]
+	job, has := t.job(id)
+	if !has && t.queue == nil {
+		t.mutex.Lock()
+		job, has = t.dead[id]
+		t.mutex.Unlock()
+	}
+
+	if !has && t.queue != nil {
+		record, err := t.queue.Get(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if record == nil {
+			return nil, fmt.Errorf("job %d does not exist or was removed", id)
+		}
+		return record.Map(status), nil
+	}
+
 	if !has {
 		return nil, fmt.Errorf("job %d does not exist or was completed", id)
 	}
+	return job.Map(), nil
+}
+
+// Map the job map
+func (job *Job) Map() map[string]interface{} {
 	return map[string]interface{}{
 		"id":       job.id,
 		"status":   status[job.status],
@@ -141,7 +192,9 @@ func (t *Task) Get(id int) (map[string]interface{}, error) {
 		"total":    job.total,
 		"message":  job.message,
 		"response": job.response,
-	}, nil
+		"attempts": job.attempts,
+		"priority": job.priority,
+	}
 }
 
 // createWorker create a new worker
@@ -168,7 +221,26 @@ func (t *Task) startWorker(w *Worker) {
 func (t *Task) start(job *Job) {
 
 	defer job.cancel()
-	defer delete(t.jobs, job.id)
+
+	atomic.AddInt32(&t.busy, 1)
+	defer atomic.AddInt32(&t.busy, -1)
+
+	if job.record == nil {
+		job.attempts++ // the attempts of the claimed jobs are counted by the queue
+	}
+
+	// the job was canceled or timeout before running
+	if err := job.ctx.Err(); err != nil {
+		t.failure(job, t.cause(job, err))
+		return
+	}
+
+	// extend the visibility timeout of the claimed job until the job is completed
+	if job.record != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go t.heartbeat(job, done)
+	}
 
 	ch := make(chan interface{}, 1) // the result channel
 	chError := make(chan error, 1)  // the error channel
@@ -192,12 +264,17 @@ func (t *Task) start(job *Job) {
 	select {
 	case <-t.ctx.Done():
 		log.Error("[TASK] %s Job:%v the task was canceled (%v)", t.name, job.id, t.ctx.Err())
+		if job.record != nil { // the claimed job is run again after the restart
+			t.interrupt(job)
+			return
+		}
 		t.failure(job, t.ctx.Err())
 		return
 
 	case <-job.ctx.Done():
-		log.Error("[TASK] %s Job:%v the job was canceled (%v)", t.name, job.id, job.ctx.Err())
-		t.failure(job, job.ctx.Err())
+		err := t.cause(job, job.ctx.Err())
+		log.Error("[TASK] %s Job:%v the job was canceled (%v)", t.name, job.id, err)
+		t.failure(job, err)
 		return
 
 	case err := <-chError:
@@ -214,33 +291,40 @@ func (t *Task) start(job *Job) {
 
 func (t *Task) nextID() int {
 	if t.handlers.NextID == nil {
-		return len(t.pool.jobque) + 1
+		t.seq++
+		return t.seq
 	}
 
 	id, err := t.handlers.NextID()
 	if err != nil {
 		log.Error("[TASK] %s can't get next id (%s)", t.name, err.Error())
-		return len(t.pool.jobque) + 1
+		t.seq++
+		return t.seq
 	}
 	return id
 }
 
-// exec excute the job
-// @todo:
-//  1. The goroutine will be running until the handler completed, it should be killed.
-//  2. Should retry if the handler is error or panic
+// exec excute the job, the handler should return when the context of the job is done
 func (t *Task) exec(job *Job) (interface{}, error) {
 	job.status = RUNNING
 	if t.handlers.Exec == nil {
 		err := fmt.Errorf("[TASK] %s Job:%v, is not set the execute handler", t.name, job.id)
 		return nil, err
 	}
-	return t.handlers.Exec(job.id, job.args...)
+	return t.handlers.Exec(job.ctx, job.id, job.args...)
 }
 
 func (t *Task) failure(job *Job, err error) {
+	if t.retryable(job, err) {
+		t.retry(job, err)
+		return
+	}
+
 	job.status = FAILURE
 	job.response = err.Error()
+	job.dead = t.Option.Attempts > 0 && t.ctx.Err() == nil && !errors.Is(err, context.Canceled) && err != queue.ErrCanceled
+	t.complete(job)
+	t.done(job)
 	if t.handlers.Error == nil {
 		return
 	}
@@ -250,6 +334,8 @@ func (t *Task) failure(job *Job, err error) {
 func (t *Task) success(job *Job, response interface{}) {
 	job.status = SUCCESS
 	job.response = response
+	t.complete(job)
+	t.done(job)
 	if t.handlers.Success == nil {
 		return
 	}
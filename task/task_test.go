@@ -1,12 +1,15 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/yaoapp/gou/task/queue"
 )
 
 func TestStart(t *testing.T) {
@@ -52,7 +55,7 @@ func TestStart(t *testing.T) {
 func TestGet(t *testing.T) {
 	task := New(
 		&Handlers{
-			Exec: func(id int, args ...interface{}) (interface{}, error) {
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
 				for i := 1; i < 3; i++ {
 					time.Sleep(500 * time.Millisecond)
 					Progress("unit-test-task", id, i, 2, fmt.Sprintf("Progress %v/%v", i, 2))
@@ -103,6 +106,556 @@ func TestGet(t *testing.T) {
 
 }
 
+func TestQueue(t *testing.T) {
+	task := New(
+		&Handlers{
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				time.Sleep(200 * time.Millisecond)
+				Progress("unit-test-queue", id, 1, 1, "done")
+				return fmt.Sprintf("%d %v", id, args), nil
+			},
+		},
+		Option{
+			Name:       "unit-test-queue",
+			WorkerNums: 2,
+			Timeout:    5,
+			Queue:      queue.NewMemory("unit-test-queue", queue.Option{}),
+			Poll:       50,
+		},
+	)
+
+	Tasks["unit-test-queue"] = task
+	defer task.Stop()
+	ids := []int{}
+	for i := 0; i < 3; i++ {
+		id, err := task.Add("JOB", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	job, err := task.Get(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "WAITING", job["status"])
+
+	go task.Start()
+	time.Sleep(1 * time.Second)
+
+	// the completed jobs are kept in the queue
+	for i, id := range ids {
+		job, err := task.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "SUCCESS", job["status"])
+		assert.Equal(t, "done", job["message"])
+		assert.Equal(t, fmt.Sprintf("%d [JOB %d]", id, i), job["response"])
+		assert.Equal(t, 1, job["attempts"])
+	}
+}
+
+func TestQueueInterrupt(t *testing.T) {
+	q := queue.NewMemory("unit-test-queue-interrupt", queue.Option{})
+	newTask := func(delay time.Duration) *Task {
+		return New(
+			&Handlers{
+				Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+					time.Sleep(delay)
+					return "done", nil
+				},
+			},
+			Option{Name: "unit-test-queue-interrupt", WorkerNums: 1, Timeout: 5, Queue: q, Poll: 50},
+		)
+	}
+
+	task := newTask(2 * time.Second)
+	id, err := task.Add("JOB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go task.Start()
+	time.Sleep(200 * time.Millisecond)
+	task.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// the running job was released
+	job, err := task.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "WAITING", job["status"])
+
+	// claimed again after the restart
+	task = newTask(0)
+	defer task.Stop()
+	go task.Start()
+	time.Sleep(500 * time.Millisecond)
+
+	job, err = task.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "SUCCESS", job["status"])
+}
+
+func TestRetry(t *testing.T) {
+	attempts := map[int]int{}
+	res := map[int]interface{}{}
+	var mutex sync.Mutex
+	task := New(
+		&Handlers{
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				attempts[id]++
+				if attempts[id] < 3 {
+					return nil, fmt.Errorf("attempt %d failed", attempts[id])
+				}
+				return attempts[id], nil
+			},
+			Success: func(id int, response interface{}) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				res[id] = response
+			},
+		},
+		Option{
+			Name:         "unit-test-retry",
+			WorkerNums:   2,
+			Timeout:      5,
+			Attempts:     3,
+			AttemptAfter: 50,
+			Backoff:      "exponential",
+		},
+	)
+
+	Tasks["unit-test-retry"] = task
+	defer task.Stop()
+	go task.Start()
+	id, err := task.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	job, err := task.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "WAITING", job["status"])
+	assert.Equal(t, "attempt 1 failed", job["response"])
+
+	time.Sleep(300 * time.Millisecond)
+	mutex.Lock()
+	assert.Equal(t, 3, attempts[id])
+	assert.Equal(t, 3, res[id])
+	mutex.Unlock()
+
+	dead, err := task.Dead()
+	assert.Nil(t, err)
+	assert.Len(t, dead, 0)
+}
+
+func TestDeadLetter(t *testing.T) {
+	errs := map[int]error{}
+	var mutex sync.Mutex
+	var fail = true
+	task := New(
+		&Handlers{
+			NextID: nextID(),
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if len(args) > 0 && args[0] == "permanent" {
+					return nil, Permanent(fmt.Errorf("bad request"))
+				}
+
+				if fail {
+					return nil, fmt.Errorf("unavailable")
+				}
+				return "done", nil
+			},
+			Error: func(id int, err error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				errs[id] = err
+			},
+		},
+		Option{
+			Name:         "unit-test-dead",
+			WorkerNums:   2,
+			Timeout:      5,
+			Attempts:     2,
+			AttemptAfter: 20,
+		},
+	)
+
+	Tasks["unit-test-dead"] = task
+	defer task.Stop()
+	go task.Start()
+	id, _ := task.Add()
+	permanent, _ := task.Add("permanent")
+
+	time.Sleep(200 * time.Millisecond)
+	dead, err := task.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, dead, 2)
+	assert.Equal(t, id, dead[0]["id"])
+	assert.Equal(t, "FAILURE", dead[0]["status"])
+	assert.Equal(t, 2, dead[0]["attempts"])
+	assert.Equal(t, permanent, dead[1]["id"])
+	assert.Equal(t, 1, dead[1]["attempts"])
+
+	job, err := task.Get(id)
+	assert.Nil(t, err)
+	assert.Equal(t, "unavailable", job["response"])
+
+	// requeue the dead job
+	mutex.Lock()
+	fail = false
+	mutex.Unlock()
+	requeued, err := task.Requeue(id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, requeued)
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = task.Get(id)
+	assert.NotNil(t, err)
+
+	removed, err := task.Purge()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+
+	dead, _ = task.Dead()
+	assert.Len(t, dead, 0)
+	mutex.Lock()
+	assert.Equal(t, "bad request", errs[permanent].Error())
+	mutex.Unlock()
+}
+
+func TestCancel(t *testing.T) {
+	errs := map[int]error{}
+	stopped := map[int]bool{}
+	var mutex sync.Mutex
+	task := New(
+		&Handlers{
+			NextID: nextID(),
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				select {
+				case <-ctx.Done():
+					mutex.Lock()
+					defer mutex.Unlock()
+					stopped[id] = true
+				case <-time.After(500 * time.Millisecond):
+				}
+				return nil, nil
+			},
+			Error: func(id int, err error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				errs[id] = err
+			},
+		},
+		Option{
+			Name:         "unit-test-cancel",
+			WorkerNums:   1,
+			Timeout:      5,
+			Attempts:     3,
+			AttemptAfter: 20,
+		},
+	)
+
+	Tasks["unit-test-cancel"] = task
+	defer task.Stop()
+	go task.Start()
+	running, _ := task.Add()
+	waiting, _ := task.Add()
+
+	time.Sleep(100 * time.Millisecond)
+	canceled, err := task.Cancel(running)
+	assert.Nil(t, err)
+	assert.True(t, canceled)
+
+	canceled, err = task.Cancel(waiting)
+	assert.Nil(t, err)
+	assert.True(t, canceled)
+
+	time.Sleep(100 * time.Millisecond)
+	canceled, err = task.Cancel(running)
+	assert.Nil(t, err)
+	assert.False(t, canceled)
+
+	// the canceled jobs are not retried
+	mutex.Lock()
+	assert.Equal(t, queue.ErrCanceled, errs[running])
+	assert.Equal(t, queue.ErrCanceled, errs[waiting])
+	assert.True(t, stopped[running]) // the context of the running job is canceled
+	mutex.Unlock()
+
+	dead, _ := task.Dead()
+	assert.Len(t, dead, 0)
+}
+
+func TestQueueRetry(t *testing.T) {
+	attempts := 0
+	var mutex sync.Mutex
+	task := New(
+		&Handlers{
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				attempts++
+				return nil, fmt.Errorf("unavailable")
+			},
+		},
+		Option{
+			Name:         "unit-test-queue-retry",
+			WorkerNums:   1,
+			Timeout:      5,
+			Attempts:     3,
+			AttemptAfter: 20,
+			Queue:        queue.NewMemory("unit-test-queue-retry", queue.Option{}),
+			Poll:         10,
+		},
+	)
+
+	Tasks["unit-test-queue-retry"] = task
+	defer task.Stop()
+	go task.Start()
+	id, err := task.Add()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	job, err := task.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "FAILURE", job["status"])
+	assert.Equal(t, 3, job["attempts"])
+	mutex.Lock()
+	assert.Equal(t, 3, attempts)
+	mutex.Unlock()
+
+	dead, err := task.Dead()
+	assert.Nil(t, err)
+	assert.Len(t, dead, 1)
+
+	removed, err := task.Purge(id)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, err = task.Get(id)
+	assert.NotNil(t, err)
+}
+
+func TestPriority(t *testing.T) {
+	order := []interface{}{}
+	var mutex sync.Mutex
+	task := New(
+		&Handlers{
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				order = append(order, args[0])
+				return nil, nil
+			},
+		},
+		Option{Name: "unit-test-priority", WorkerNums: 1, Poll: 50},
+	)
+
+	Tasks["unit-test-priority"] = task
+	defer task.Stop()
+	start := time.Now()
+	task.Add("low")
+	task.AddWith(AddOption{Priority: 10}, "high")
+	task.AddWith(AddOption{Priority: 100, Delay: 200 * time.Millisecond}, "delayed")
+	task.AddWith(AddOption{Priority: 100}, "urgent")
+	task.AddWith(AddOption{Priority: 10, RunAt: start.Add(-time.Second)}, "due")
+
+	// the duplicated jobs are dropped or merged while the job is waiting
+	id, _ := task.AddWith(AddOption{Unique: "reindex"}, "reindex-1")
+	dup, _ := task.AddWith(AddOption{Unique: "reindex"}, "reindex-2")
+	assert.Equal(t, id, dup)
+	dup, _ = task.AddWith(AddOption{Unique: "reindex", Merge: true, Priority: 5}, "reindex-3")
+	assert.Equal(t, id, dup)
+
+	job, err := task.Get(id)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, job["priority"])
+
+	go task.Start()
+	time.Sleep(100 * time.Millisecond)
+	mutex.Lock()
+	assert.Equal(t, []interface{}{"urgent", "high", "due", "reindex-3", "low"}, order)
+	mutex.Unlock()
+
+	// the uniqueness key is released once the job is running
+	again, _ := task.AddWith(AddOption{Unique: "reindex"}, "reindex-4")
+	assert.NotEqual(t, id, again)
+
+	time.Sleep(300 * time.Millisecond)
+	mutex.Lock()
+	assert.Equal(t, []interface{}{"urgent", "high", "due", "reindex-3", "low", "reindex-4", "delayed"}, order)
+	mutex.Unlock()
+}
+
+func TestAddOptionOf(t *testing.T) {
+	option, err := addOptionOf(map[string]interface{}{"priority": 10, "delay": 1.5, "unique": "reindex", "merge": true})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, option.Priority)
+	assert.Equal(t, 1500*time.Millisecond, option.Delay)
+	assert.Equal(t, "reindex", option.Unique)
+	assert.True(t, option.Merge)
+
+	option, err = addOptionOf(map[string]interface{}{"delay": "5m", "run_at": "2023-08-01T00:00:00Z"})
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, option.Delay)
+	assert.Equal(t, int64(1690848000), option.RunAt.Unix())
+
+	option, err = addOptionOf(map[string]interface{}{"run_at": 1690848000})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1690848000), option.RunAt.Unix())
+
+	_, err = addOptionOf(map[string]interface{}{"run_at": "tomorrow"})
+	assert.NotNil(t, err)
+
+	_, err = addOptionOf("priority")
+	assert.NotNil(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	task := New(&Handlers{}, Option{AttemptAfter: 100, Backoff: "exponential", MaxAttemptAfter: 500})
+	assert.Equal(t, 100*time.Millisecond, task.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, task.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, task.backoff(3))
+	assert.Equal(t, 500*time.Millisecond, task.backoff(4))
+
+	task.Option.Backoff = "fixed"
+	assert.Equal(t, 100*time.Millisecond, task.backoff(4))
+
+	task.Option.Backoff = "jitter"
+	for i := 0; i < 10; i++ {
+		delay := task.backoff(2)
+		assert.True(t, delay >= 100*time.Millisecond && delay < 200*time.Millisecond)
+	}
+}
+
+func TestStats(t *testing.T) {
+	var mutex sync.Mutex
+	flaky := 0
+	block := make(chan struct{})
+	task := New(
+		&Handlers{
+			NextID: nextID(),
+			Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
+				switch args[0] {
+				case "permanent":
+					return nil, Permanent(fmt.Errorf("bad request"))
+				case "flaky":
+					mutex.Lock()
+					defer mutex.Unlock()
+					flaky++
+					if flaky == 1 {
+						return nil, fmt.Errorf("unavailable")
+					}
+				case "block":
+					<-block
+				}
+				return "done", nil
+			},
+		},
+		Option{
+			Name:         "unit-test-stats",
+			WorkerNums:   1,
+			Timeout:      5,
+			Attempts:     2,
+			AttemptAfter: 10,
+		},
+	)
+
+	Tasks["unit-test-stats"] = task
+	defer task.Stop()
+	go task.Start()
+	task.Add("ok")
+	task.Add("ok")
+	permanent, _ := task.Add("permanent")
+	last, _ := task.Add("flaky")
+
+	time.Sleep(200 * time.Millisecond)
+	stats, err := task.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, stats["success"])
+	assert.Equal(t, 1, stats["failure"])
+	assert.Equal(t, 1, stats["retried"])
+	assert.Equal(t, 4, stats["throughput"])
+	assert.Equal(t, 0.25, stats["failure_rate"])
+	assert.Equal(t, 1, stats["dead"])
+	assert.Equal(t, 0, stats["waiting"])
+	assert.Equal(t, 0, stats["busy"])
+
+	// the newest first
+	res, err := task.List(0, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, res["total"])
+	assert.Equal(t, 2, res["pagecnt"])
+	assert.Equal(t, 2, res["next"])
+	assert.Equal(t, -1, res["prev"])
+	data := res["data"].([]map[string]interface{})
+	if assert.Len(t, data, 2) {
+		assert.Equal(t, last, data[0]["id"])
+		assert.Equal(t, "SUCCESS", data[0]["status"])
+		assert.Equal(t, 2, data[0]["attempts"])
+	}
+
+	res, err = task.List(FAILURE, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res["total"])
+	assert.Equal(t, permanent, res["data"].([]map[string]interface{})[0]["id"])
+
+	res, err = task.List(SUCCESS, 1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, res["total"])
+
+	// the running job
+	running, _ := task.Add("block")
+	time.Sleep(50 * time.Millisecond)
+	stats, _ = task.Stats()
+	assert.Equal(t, 1, stats["busy"])
+	assert.Equal(t, 1, stats["running"])
+	assert.Equal(t, 1.0, stats["utilization"])
+
+	res, _ = task.List(RUNNING, 1, 10)
+	assert.Equal(t, running, res["data"].([]map[string]interface{})[0]["id"])
+	close(block)
+
+	status, err := statusOf("failure")
+	assert.Nil(t, err)
+	assert.Equal(t, FAILURE, status)
+	_, err = statusOf("unknown")
+	assert.NotNil(t, err)
+}
+
+func nextID() func() (int, error) {
+	var idseq = 0
+	var mutex sync.Mutex
+	return func() (int, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		idseq++
+		return idseq, nil
+	}
+}
+
 func getHandlers(res map[string]map[int]interface{}) *Handlers {
 	var idseq = 0
 	return &Handlers{
@@ -120,7 +673,7 @@ func getHandlers(res map[string]map[int]interface{}) *Handlers {
 			res["add"][id] = fmt.Sprintln("Add: ", id)
 		},
 
-		Exec: func(id int, args ...interface{}) (interface{}, error) {
+		Exec: func(ctx context.Context, id int, args ...interface{}) (interface{}, error) {
 			if _, has := res["exec"]; !has {
 				res["exec"] = map[int]interface{}{}
 			}
@@ -4,27 +4,29 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"github.com/yaoapp/gou/task/queue"
 )
 
 const (
 	// WAITING the job is waiting
-	WAITING = iota + 1
+	WAITING = queue.WAITING
 
 	// RUNNING the job is running
-	RUNNING
+	RUNNING = queue.RUNNING
 
 	// SUCCESS the job is success
-	SUCCESS
+	SUCCESS = queue.SUCCESS
 
 	// FAILURE the job is failure
-	FAILURE
+	FAILURE = queue.FAILURE
 )
 
 var status = map[int]string{
 	WAITING: "WAITING",
 	RUNNING: "RUNNING",
 	SUCCESS: "SUCCESS",
-	FAILURE: "SUCCESS",
+	FAILURE: "FAILURE",
 }
 
 // Task the task struct
@@ -37,24 +39,49 @@ type Task struct {
 	mutex    sync.Mutex
 	ctx      context.Context
 	cancel   context.CancelFunc
+	queue    queue.Queue     // the durable job queue, the jobs are in the memory if it is nil
+	worker   string          // the worker id of the instance claims the jobs
+	notify   chan struct{}   // claim the jobs immediately when a job was added
+	dead     map[int]*Job    // the dead-letter list of the in-memory jobs
+	waiting  *jobHeap        // the waiting in-memory jobs ordered by the priority
+	delayed  *jobHeap        // the delayed in-memory jobs ordered by the run time
+	unique   map[string]*Job // the waiting in-memory job of the uniqueness key
+	seq      int             // the id of the last in-memory job if the NextID handler is not set
+	enqueued int             // the number of the enqueued in-memory jobs, keep the order of the jobs of the same priority
+	busy     int32           // the number of the busy workers
+	counters counters        // the counters of the jobs completed by the instance
+	finished []*Job          // the recently completed in-memory jobs
 	Option   Option
 }
 
 // Option the task option
 type Option struct {
-	Name           string
-	JobQueueLength int
-	WorkerNums     int
-	AttemptAfter   int
-	Attempts       int
-	Timeout        int
+	Name            string
+	JobQueueLength  int // the maximum waiting in-memory jobs
+	WorkerNums      int
+	AttemptAfter    int    // the delay before the first retry (milliseconds)
+	Attempts        int    // the maximum attempts of a job, the failed jobs are retried and moved to the dead-letter list after all attempts. 0 means no retry
+	Backoff         string // the backoff of the retries: fixed, exponential or jitter (exponential with random jitter). default is fixed
+	MaxAttemptAfter int    // the maximum delay of the retries (milliseconds), 0 means no limit
+	Timeout         int
+	Queue           queue.Queue // the durable job queue
+	Visibility      int         // the visibility timeout of the claimed jobs (seconds), the jobs of the crashed instances are claimed again after the timeout
+	Poll            int         // the interval of polling the queue (milliseconds)
+}
+
+// AddOption the per-job options
+type AddOption struct {
+	Priority int           // the jobs of the higher priority run first, default is 0
+	Delay    time.Duration // the job runs after the delay
+	RunAt    time.Time     // the job runs at the time, it overrides the delay
+	Unique   string        // the uniqueness key, the duplicated job is dropped (or merged) while an equivalent job is waiting
+	Merge    bool          // merge the duplicated job into the waiting one: the args are replaced and the priority is raised
 }
 
 // Pool the worker pool
 type Pool struct {
 	size      int
 	max       int
-	jobque    chan *Job
 	workerque chan *Worker
 }
 
@@ -75,14 +102,52 @@ type Job struct {
 	message  string
 	response interface{}
 	args     []interface{}
+	attempts int
+	canceled bool
+	dead     bool
+	priority int
+	runAt    time.Time
+	unique   string
+	order    int        // the enqueued order
+	index    int        // the index of the heap
+	record   *queue.Job // the claimed job of the queue
+	lock     sync.Mutex
 }
 
 // Handlers the event handlers
 type Handlers struct {
-	Exec     func(int, ...interface{}) (interface{}, error)
+	Exec     func(context.Context, int, ...interface{}) (interface{}, error) // the context is canceled when the job is canceled or timeout
 	Progress func(int, int, int, string)
 	NextID   func() (int, error)
 	Add      func(int)
 	Success  func(int, interface{})
 	Error    func(int, error)
+	Retry    func(int, error) bool // decide whether the failed job should be retried, all the errors are retried if it is nil
+}
+
+// counters the counters of the jobs completed by the instance
+type counters struct {
+	success  int
+	failure  int
+	retried  int
+	canceled int
+	seconds  [60]second // the completed jobs of the last minute
+}
+
+// second the jobs completed in a second
+type second struct {
+	at      int64 // unix seconds
+	success int
+	failure int
+}
+
+// jobHeap the heap of the in-memory jobs
+type jobHeap struct {
+	jobs []*Job
+	less func(a, b *Job) bool
+}
+
+// PermanentError the error is never retried
+type PermanentError struct {
+	Err error
 }